@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/util/health"
+	"github.com/kokoichi206-sandbox/url-shortener/util/lifecycle"
+)
+
+var errShuttingDown = errors.New("shutting down")
+
+// registerHealthChecks registers the checks of the readiness probe.
+// New subsystems (e.g. cache) should register their checks here.
+func registerHealthChecks(checks *health.Registry, db repository.Database, lc *lifecycle.Manager) {
+	checks.Register("shutdown", func(context.Context) error {
+		if !lc.Ready() {
+			return errShuttingDown
+		}
+
+		return nil
+	})
+
+	checks.Register("database", db.Health)
+
+	checks.Register("migrations", func(ctx context.Context) error {
+		version, err := db.SchemaVersion(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get schema version: %w", err)
+		}
+
+		if version != repository.SchemaVersion {
+			return fmt.Errorf("schema version is %d, expected %d", version, repository.SchemaVersion)
+		}
+
+		return nil
+	})
+}
@@ -2,16 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/kokoichi206-sandbox/url-shortener/config"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 	"github.com/kokoichi206-sandbox/url-shortener/handler"
 	"github.com/kokoichi206-sandbox/url-shortener/repository/database"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/memory"
 	"github.com/kokoichi206-sandbox/url-shortener/usecase"
-	"github.com/kokoichi206-sandbox/url-shortener/util"
-	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
-	"github.com/opentracing/opentracing-go"
+	"github.com/kokoichi206-sandbox/url-shortener/util/health"
+	"github.com/kokoichi206-sandbox/url-shortener/util/lifecycle"
+	log "github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
+	"github.com/kokoichi206-sandbox/url-shortener/util/pagemeta"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
 )
 
 const (
@@ -26,55 +40,376 @@ func main() {
 	}()
 
 	// config
-	cfg := config.New()
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		exitCode = printConfig(args[2:])
+
+		return
+	}
+
+	cfg, err := config.Load(args, os.LookupEnv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+
+		log.NewBasicLogger(os.Stderr, "ubuntu", service).
+			WithFields(log.Err(err)).Critical(context.Background(), "invalid config")
+
+		exitCode = 1
+
+		return
+	}
 
 	// logger
-	logger := logger.NewBasicLogger(os.Stdout, "ubuntu", service)
+	sink, closers, err := newLogSink(cfg)
+	if err != nil {
+		log.NewBasicLogger(os.Stderr, "ubuntu", service).
+			WithFields(log.Err(err)).Critical(context.Background(), "failed to create log sink")
+
+		exitCode = 1
+
+		return
+	}
+
+	defer func() {
+		// 後から開いたもの (async writer) から閉じ、残りのログを flush する。
+		for i := len(closers) - 1; i >= 0; i-- {
+			//nolint:errcheck
+			closers[i].Close()
+		}
+	}()
+
+	logger := log.NewBasicLoggerWithSink(sink, "ubuntu", service)
+
+	if err := setLogLevels(logger, cfg); err != nil {
+		logger.WithFields(log.Err(err)).Critical(context.Background(), "invalid log level")
+
+		exitCode = 1
+
+		return
+	}
+
+	log.HandleLevelSignals(context.Background(), logger)
+
+	// lifecycle
+	// 登録と逆順に閉じるため、依存されるもの (tracer, database) から登録する。
+	lc := lifecycle.New(logger)
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		if err := lc.Shutdown(ctx); err != nil {
+			exitCode = 1
+		}
+	}()
 
 	// tracer
-	tracer, traceCloser, err := util.NewJaegerTracer(cfg.AgentHost, cfg.AgentPort, service)
-	defer traceCloser.Close()
+	sampler := tracing.NewRatioSampler(cfg.TraceSamplingRatio)
 
+	tp, err := tracing.NewTracerProvider(context.Background(), tracing.Config{
+		ServiceName: service,
+		Exporter:    cfg.TraceExporter,
+		Host:        cfg.AgentHost,
+		Port:        cfg.AgentPort,
+		Sampler:     sampler,
+	})
 	if err != nil {
-		logger.Errorf(context.Background(), "cannot initialize jaeger tracer: ", err)
+		logger.Errorf(context.Background(), "cannot initialize tracer provider: %v", err)
 	} else {
-		opentracing.SetGlobalTracer(tracer)
+		lc.Register("tracer provider", tp.Shutdown)
+
+		tracing.SetGlobal(tp)
 	}
 
 	// database
-	sqlDB, err := database.Connect(
-		cfg.DBDriver, cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword,
-		cfg.DBName, cfg.DBSSLMode,
+	var (
+		db        repository.Database
+		txManager transaction.TxManager
+		urlRepo   repository.URLRepository
+		tagRepo   repository.TagRepository
+		pageRepo  repository.PageMetadataRepository
+	)
+
+	switch cfg.DBDriver {
+	case config.DBDriverMemory:
+		store := memory.NewStore()
+		store.AddDomain(cfg.DefaultDomain)
+
+		db = memory.New(store, logger)
+		txManager = memory.NewTxManager(store)
+		urlRepo = memory.NewURLRepo(memory.ExtractRWTx)
+		tagRepo = memory.NewTagRepo(memory.ExtractRWTx)
+		pageRepo = memory.NewPageMetadataRepo(memory.ExtractRWTx)
+	default:
+		sqlDB, pool, err := database.Connect(
+			cfg.DBDriver, cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword,
+			cfg.DBName, cfg.DBSSLMode,
+			database.PoolConfig{
+				MaxOpenConns:    cfg.DBMaxOpenConns,
+				MaxIdleConns:    cfg.DBMaxIdleConns,
+				ConnMaxLifetime: cfg.DBConnMaxLifetime,
+				ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+			},
+		)
+		if err != nil {
+			logger.WithFields(log.Err(err)).Critical(context.Background(), "failed to db.Connect")
+
+			exitCode = 1
+
+			return
+		}
+
+		var dbOpts []database.Option
+		if pool != nil {
+			// sqlDB is closed first because the components are closed in reverse order.
+			lc.Register("database pool", func(context.Context) error {
+				pool.Close()
+
+				return nil
+			})
+
+			dbOpts = append(dbOpts, database.WithPgxPool(pool))
+		}
+
+		lc.Register("database", func(context.Context) error {
+			//nolint:wrapcheck
+			return sqlDB.Close()
+		})
+
+		if err := sqlDB.Ping(); err != nil {
+			logger.WithFields(log.Err(err)).Critical(context.Background(), "failed to db.Ping")
+
+			exitCode = 1
+
+			return
+		}
+
+		db = database.New(sqlDB, logger, dbOpts...)
+		txManager = database.NewTxManager(sqlDB)
+		urlRepo = database.NewURLRepo(database.ExtractRWTx)
+		tagRepo = database.NewTagRepo(database.ExtractRWTx)
+		pageRepo = database.NewPageMetadataRepo(database.ExtractRWTx)
+	}
+
+	// metrics
+	metrics.Registry.MustRegister(metrics.NewDBStatsCollector(db.Stats))
+
+	if async, ok := sink.(*log.AsyncSink); ok {
+		metrics.Registry.MustRegister(metrics.NewLogDroppedCollector(async.Dropped))
+	}
+
+	metricsSrv := &http.Server{
+		Addr:              net.JoinHostPort(cfg.MetricsHost, cfg.MetricsPort),
+		Handler:           metrics.Handler(),
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+	}
+	lc.Register("metrics server", metricsSrv.Shutdown)
+
+	go func() {
+		if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf(context.Background(), "failed to serve metrics: %v", err)
+		}
+	}()
+
+	// health checks
+	checks := health.NewRegistry(cfg.HealthCheckTimeout)
+	registerHealthChecks(checks, db, lc)
+
+	// usecase
+	usecaseOpts := []usecase.Option{
+		usecase.WithDedupPolicy(usecase.DedupPolicy(cfg.DedupPolicy)),
+		usecase.WithAccessToken([]byte(cfg.LinkAccessSecret), cfg.LinkAccessTTL),
+	}
+
+	if cfg.MetadataFetchWorkers > 0 {
+		fetcher := pagemeta.NewHTTPFetcher(
+			pagemeta.WithTimeout(cfg.MetadataFetchTimeout),
+			pagemeta.WithMaxBytes(int64(cfg.MetadataFetchMaxBytes)),
+			pagemeta.WithUserAgent(cfg.MetadataFetchUserAgent),
+			pagemeta.WithAllowPrivateNetworks(cfg.MetadataFetchAllowPrivate),
+		)
+		worker := usecase.NewMetadataWorker(
+			txManager, pageRepo, fetcher, logger,
+			cfg.MetadataFetchWorkers, cfg.MetadataFetchQueueSize,
+		)
+		// database より先に閉じるよう、後に登録する。
+		lc.Register("metadata worker", worker.Close)
+
+		usecaseOpts = append(usecaseOpts, usecase.WithMetadataQueue(worker))
+	}
+
+	usecase := usecase.New(db, txManager, urlRepo, tagRepo, pageRepo, logger, usecaseOpts...)
+
+	// handler
+	h := handler.New(
+		logger, usecase,
+		handler.WithRedirectLogSampling(cfg.AccessLogRedirectSamplingRatio),
+		handler.WithAdminToken(cfg.AdminToken),
+		handler.WithRequestIDHeader(cfg.RequestIDHeader),
+		handler.WithDefaultDomain(cfg.DefaultDomain),
+		handler.WithPublicBaseURL(cfg.PublicBaseURL),
+		handler.WithOwnerHeader(cfg.OwnerHeader),
+		handler.WithPasswordThrottle(cfg.PasswordMaxAttempts, cfg.PasswordAttemptWindow),
+		handler.WithTrustedProxies(cfg.TrustedProxyList()),
+		handler.WithReadiness(lc.Ready),
+		handler.WithHealthChecks(checks),
 	)
+
+	srv, err := newServer(cfg, h.Engine, logger)
 	if err != nil {
-		logger.Criticalf(context.Background(), "failed to db.Connect: ", err)
+		logger.WithFields(log.Err(err)).Critical(context.Background(), "failed to create http server")
 
 		exitCode = 1
 
 		return
 	}
 
-	if err := sqlDB.Ping(); err != nil {
-		logger.Criticalf(context.Background(), "failed to db.Ping: ", err)
+	// Shutdown waits for the in-flight requests until the deadline.
+	lc.Register("http server", srv.Shutdown)
+
+	redirectSrv := newRedirectServer(cfg)
+	if redirectSrv != nil {
+		lc.Register("http redirect server", redirectSrv.Shutdown)
+	}
+
+	// config reload
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	lc.Register("config watcher", func(context.Context) error {
+		stopWatch()
+
+		return nil
+	})
+
+	watcher := config.NewWatcher(cfg, args, os.LookupEnv, logger)
+	subscribeReload(watcher, logger, h, sampler)
+	watcher.Watch(watchCtx, cfg.ConfigWatchInterval)
+	watcher.HandleReloadSignals(watchCtx)
+
+	// run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 2)
+
+	go func() {
+		logger.Infof(ctx, "listening on %s (tls: %t)", srv.Addr, srv.TLSConfig != nil)
+
+		if err := serve(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	if redirectSrv != nil {
+		go func() {
+			logger.Infof(ctx, "redirecting http on %s to https", redirectSrv.Addr)
+
+			if err := redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-errCh:
+		logger.WithFields(log.Err(err)).Critical(context.Background(), "failed to serve http")
 
 		exitCode = 1
 
 		return
+	case <-ctx.Done():
 	}
 
-	db := database.New(sqlDB, logger)
-	txManager := database.NewTxManager(sqlDB)
-	urlRepo := database.NewURLRepo(database.ExtractRWTx)
+	// 2 回目のシグナルでは即座に終了できるように、シグナルの捕捉を解除する。
+	stop()
 
-	// usecase
-	usecase := usecase.New(db, txManager, urlRepo, logger)
+	logger.Infof(context.Background(), "shutting down, waiting %s before draining", cfg.ShutdownDelay)
 
-	// handler
-	h := handler.New(logger, usecase)
-	addr := net.JoinHostPort(cfg.ServerHost, cfg.ServerPort)
+	// load balancer が not ready を検知するまで、新しいリクエストも受け付ける。
+	lc.SetNotReady()
+	time.Sleep(cfg.ShutdownDelay)
+}
 
-	// run
-	if err := h.Engine.Run(addr); err != nil {
-		logger.Critical(context.Background(), "failed to serve http")
+// printConfig prints the effective config with the secrets redacted, and returns the exit code.
+func printConfig(args []string) int {
+	cfg, err := config.Load(args, os.LookupEnv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+
+		fmt.Fprintf(os.Stderr, "invalid config:\n%v\n", err)
+
+		return 1
+	}
+
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// newLogSink creates the sinks from the config.
+// The returned closers must be closed in reverse order to flush the records.
+func newLogSink(cfg config.Config) (log.Sink, []io.Closer, error) {
+	stdoutLevel, err := log.ParseLevel(cfg.LogStdoutLevel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid stdout log level: %w", err)
+	}
+
+	sinks := []log.Sink{log.NewWriterSink(os.Stdout, stdoutLevel)}
+
+	var closers []io.Closer
+
+	if cfg.LogFilePath != "" {
+		fileLevel, err := log.ParseLevel(cfg.LogFileLevel)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid file log level: %w", err)
+		}
+
+		file, err := log.NewRotatingFile(log.RotateConfig{
+			Path:       cfg.LogFilePath,
+			MaxSize:    int64(cfg.LogFileMaxSizeMB) * 1024 * 1024,
+			MaxAge:     cfg.LogFileMaxAge,
+			MaxBackups: cfg.LogFileMaxBackups,
+			Compress:   cfg.LogFileCompress,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+
+		sinks = append(sinks, log.NewWriterSink(file, fileLevel))
+		closers = append(closers, file)
 	}
+
+	sink := log.NewTeeSink(sinks...)
+
+	if cfg.LogAsyncBufferSize > 0 {
+		async := log.NewAsyncSink(sink, cfg.LogAsyncBufferSize)
+
+		return async, append(closers, async), nil
+	}
+
+	return sink, closers, nil
+}
+
+func setLogLevels(logger log.Logger, cfg config.Config) error {
+	level, err := log.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+
+	overrides, err := log.ParseOverrides(cfg.LogLevelOverrides)
+	if err != nil {
+		return fmt.Errorf("invalid log level overrides: %w", err)
+	}
+
+	logger.Levels().SetLevel(level)
+	logger.Levels().SetOverrides(overrides)
+
+	return nil
 }
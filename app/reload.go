@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	"github.com/kokoichi206-sandbox/url-shortener/config"
+	log "github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+// subscribeReload applies the reloadable settings to the running components.
+// New reloadable settings must be tagged with reload in config.Config and applied here.
+func subscribeReload(
+	watcher *config.Watcher, logger log.Logger,
+	h interface{ SetRedirectLogSampling(ratio float64) }, sampler *tracing.RatioSampler,
+) {
+	watcher.Subscribe(config.SubscriberFunc(func(ctx context.Context, prev, next config.Config) {
+		// admin API で変更されたレベルを上書きしないよう、変更された場合のみ反映する。
+		if prev.LogLevel == next.LogLevel && prev.LogLevelOverrides == next.LogLevelOverrides {
+			return
+		}
+
+		if err := setLogLevels(logger, next); err != nil {
+			logger.WithFields(log.Err(err)).Error(ctx, "failed to reload log levels")
+		}
+	}))
+
+	watcher.Subscribe(config.SubscriberFunc(func(ctx context.Context, prev, next config.Config) {
+		h.SetRedirectLogSampling(next.AccessLogRedirectSamplingRatio)
+		sampler.SetRatio(next.TraceSamplingRatio)
+	}))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/kokoichi206-sandbox/url-shortener/config"
+	log "github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tlsutil"
+)
+
+// newServer returns the http server, which serves https if the certificate is set,
+// and HTTP/2 without TLS (h2c) if enabled.
+func newServer(cfg config.Config, handler http.Handler, logger log.Logger) (*http.Server, error) {
+	if cfg.ServerH2C {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: cfg.ServerIdleTimeout})
+	}
+
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(cfg.ServerHost, cfg.ServerPort),
+		Handler:           handler,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+	}
+
+	if cfg.TLSCertFile != "" {
+		reloader, err := tlsutil.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cert reloader: %w", err)
+		}
+
+		minVersion, err := tlsutil.ParseVersion(cfg.TLSMinVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls min version: %w", err)
+		}
+
+		// HTTP/2 is enabled by ListenAndServeTLS.
+		srv.TLSConfig = tlsutil.NewServerConfig(reloader, minVersion)
+	}
+
+	return srv, nil
+}
+
+// newRedirectServer returns the server which redirects http to https, or nil if disabled.
+func newRedirectServer(cfg config.Config) *http.Server {
+	if cfg.HTTPRedirectPort == "" {
+		return nil
+	}
+
+	return &http.Server{
+		Addr:              net.JoinHostPort(cfg.ServerHost, cfg.HTTPRedirectPort),
+		Handler:           tlsutil.RedirectHandler(cfg.ServerPort),
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+	}
+}
+
+// serve serves https if the server has the TLS config, otherwise http.
+func serve(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		// 証明書は TLSConfig.GetCertificate から返される。
+		//nolint:wrapcheck
+		return srv.ListenAndServeTLS("", "")
+	}
+
+	//nolint:wrapcheck
+	return srv.ListenAndServe()
+}
@@ -1,92 +1,297 @@
 package config
 
-import "os"
+import (
+	"strings"
+	"time"
+)
 
 const (
-	defaultHost = "localhost"
-	defaultPort = "8080"
+	defaultHost        = "localhost"
+	defaultPort        = "8080"
+	defaultMetricsPort = "9090"
+
+	defaultRequestIDHeader = "X-Request-ID"
+	defaultTLSMinVersion   = "1.2"
+
+	defaultDomain        = "localhost"
+	defaultPublicBaseURL = "http://localhost:8080"
+	defaultDedupPolicy   = "global"
+	defaultOwnerHeader   = "X-Owner-ID"
+
+	defaultMetadataFetchWorkers   = 2
+	defaultMetadataFetchQueueSize = 1000
+	defaultMetadataFetchTimeout   = 5 * time.Second
+	defaultMetadataFetchMaxBytes  = 1 << 20
+	defaultMetadataFetchUserAgent = "url-shortener/1.0"
+
+	defaultLinkAccessTTL         = time.Hour
+	defaultPasswordMaxAttempts   = 5
+	defaultPasswordAttemptWindow = 15 * time.Minute
+
+	defaultTraceExporter      = "none"
+	defaultTraceSamplingRatio = 1.0
+
+	defaultAccessLogRedirectSamplingRatio = 1.0
+
+	defaultLogLevel           = "info"
+	defaultLogSinkLevel       = "debug"
+	defaultLogFileMaxSizeMB   = 100
+	defaultLogFileMaxAge      = 24 * time.Hour
+	defaultLogFileMaxBackups  = 7
+	defaultLogFileCompress    = true
+	defaultLogAsyncBufferSize = 4096
+)
+
+// Default timeouts of the http server.
+const (
+	defaultServerReadTimeout       = 10 * time.Second
+	defaultServerReadHeaderTimeout = 5 * time.Second
+	defaultServerWriteTimeout      = 10 * time.Second
+	defaultServerIdleTimeout       = 2 * time.Minute
+	defaultShutdownDelay           = 5 * time.Second
+	defaultShutdownTimeout         = 30 * time.Second
+	defaultHealthCheckTimeout      = 2 * time.Second
+	defaultConfigWatchInterval     = 5 * time.Second
 )
 
+// Default settings of database.
+const (
+	defaultDBDriver  = "postgres"
+	defaultDBHost    = "localhost"
+	defaultDBPort    = "5432"
+	defaultDBName    = "postgresql"
+	defaultDBSSLMode = "disable"
+
+	defaultDBMaxOpenConns    = 20
+	defaultDBMaxIdleConns    = 10
+	defaultDBConnMaxLifetime = 30 * time.Minute
+	defaultDBConnMaxIdleTime = 5 * time.Minute
+)
+
+// DBDriverMemory makes the server run on the in-memory repository instead of a real database.
+// All data is lost when the server stops, so use it only for tests and demos.
+const DBDriverMemory = "memory"
+
+// Config is the settings of the server.
+// Each field is set from the config file, the env and the flags named after the config tag
+// (e.g. "db_host" is the key in the file, DB_HOST in the env and -db-host in the flags).
+// The fields tagged as secret are also read from the file given by <NAME>_FILE (e.g. DB_PASSWORD_FILE),
+// and are not settable by the flags not to be seen in the process list.
+// The fields tagged as reload are applied to the running server when the config file is reloaded.
 type Config struct {
 	// Settings of this server.
-	ServerHost string
-	ServerPort string
+	ServerHost string `config:"server_host"`
+	ServerPort string `config:"server_port"`
+
+	// TLSCertFile and TLSKeyFile enable https if both are set.
+	// The rotated files are reloaded without restart.
+	TLSCertFile string `config:"tls_cert_file"`
+	TLSKeyFile  string `config:"tls_key_file"`
+	// TLSMinVersion is "1.2" or "1.3".
+	TLSMinVersion string `config:"tls_min_version"`
+	// HTTPRedirectPort enables the listener which redirects http to https if not empty.
+	HTTPRedirectPort string `config:"http_redirect_port"`
+	// ServerH2C enables HTTP/2 without TLS (h2c) for the internal traffic.
+	ServerH2C bool `config:"server_h2c"`
+
+	// Timeouts of the http server.
+	ServerReadTimeout       time.Duration `config:"server_read_timeout"`
+	ServerReadHeaderTimeout time.Duration `config:"server_read_header_timeout"`
+	ServerWriteTimeout      time.Duration `config:"server_write_timeout"`
+	ServerIdleTimeout       time.Duration `config:"server_idle_timeout"`
+
+	// ShutdownDelay is the time between the readiness turning to not-ready and
+	// the server stopping accepting requests, for the load balancer to notice.
+	ShutdownDelay time.Duration `config:"shutdown_delay"`
+	// ShutdownTimeout is the deadline to drain the in-flight requests and close the components.
+	ShutdownTimeout time.Duration `config:"shutdown_timeout"`
+
+	// ConfigWatchInterval is the interval to check whether the config file is changed.
+	// The file is reloaded only by SIGHUP if 0.
+	ConfigWatchInterval time.Duration `config:"config_watch_interval"`
+
+	// HealthCheckTimeout is the timeout of each check of the readiness probe.
+	HealthCheckTimeout time.Duration `config:"health_check_timeout"`
+
+	// RequestIDHeader is the header name of the request id, which is received from the client
+	// and returned in the response.
+	RequestIDHeader string `config:"request_id_header"`
+
+	// DefaultDomain is the domain of the short urls created without the domain,
+	// which must be registered in the domains table.
+	DefaultDomain string `config:"default_domain"`
+	// PublicBaseURL is the url of the default domain seen from the clients (e.g. "https://sho.rt"),
+	// from which the short links in the responses are built.
+	// The links on the other domains use its scheme.
+	PublicBaseURL string `config:"public_base_url"`
+
+	// DedupPolicy is one of "global", "per-owner" and "never", which decides when the existing
+	// short url is returned for the same url instead of creating a new one.
+	DedupPolicy string `config:"dedup_policy"`
+	// OwnerHeader is the header name of the owner of the created short url,
+	// which is expected to be set by the authenticating proxy.
+	OwnerHeader string `config:"owner_header"`
+
+	// Settings of the worker which fetches the title, the description and the Open Graph image
+	// of the destination pages in the background.
+	// MetadataFetchWorkers is the number of the concurrent fetches, and disables the worker if 0.
+	MetadataFetchWorkers   int           `config:"metadata_fetch_workers"`
+	MetadataFetchQueueSize int           `config:"metadata_fetch_queue_size"`
+	MetadataFetchTimeout   time.Duration `config:"metadata_fetch_timeout"`
+	// MetadataFetchMaxBytes is the max size of the page to be read.
+	MetadataFetchMaxBytes int `config:"metadata_fetch_max_bytes"`
+	// MetadataFetchUserAgent is sent to the sites, and its product token selects the group in robots.txt.
+	MetadataFetchUserAgent string `config:"metadata_fetch_user_agent"`
+	// MetadataFetchAllowPrivate allows the pages on the loopback and private networks,
+	// which are refused by default not to expose the internal network.
+	MetadataFetchAllowPrivate bool `config:"metadata_fetch_allow_private"`
+
+	// Settings of the password protected short urls.
+	// LinkAccessSecret is the key to sign the cookies, which let the visitors skip the password for LinkAccessTTL.
+	// A random key is used if empty, which invalidates the cookies on restart and differs among the servers.
+	LinkAccessSecret string        `config:"link_access_secret" secret:"true"`
+	LinkAccessTTL    time.Duration `config:"link_access_ttl"`
+	// PasswordMaxAttempts is the number of the password attempts per short url and client in PasswordAttemptWindow,
+	// after which the client is blocked until the window ends. The throttling is disabled if 0.
+	PasswordMaxAttempts   int           `config:"password_max_attempts"`
+	PasswordAttemptWindow time.Duration `config:"password_attempt_window"`
+	// TrustedProxies is the comma-separated ips or CIDRs of the proxies,
+	// whose X-Forwarded-For is used as the client ip. No proxy is trusted if empty.
+	TrustedProxies string `config:"trusted_proxies"`
+
+	// AdminToken enables the admin endpoints (e.g. changing the log level) if not empty.
+	AdminToken string `config:"admin_token" secret:"true"`
+
+	// Settings of the metrics server, which is separated from the main server
+	// not to expose the metrics to the internet.
+	MetricsHost string `config:"metrics_host"`
+	MetricsPort string `config:"metrics_port"`
+
+	// LogLevel is the default log level, which can be changed at runtime.
+	LogLevel string `config:"log_level" reload:"true"`
+	// LogLevelOverrides is the levels per package like "repository=debug,handler=warn".
+	LogLevelOverrides string `config:"log_level_overrides" reload:"true"`
+
+	// Settings of log sinks.
+	// The minimum levels are the names like "debug" or "info".
+	LogStdoutLevel string `config:"log_stdout_level"`
+	// LogFilePath enables the rotating log file if not empty.
+	LogFilePath       string        `config:"log_file_path"`
+	LogFileLevel      string        `config:"log_file_level"`
+	LogFileMaxSizeMB  int           `config:"log_file_max_size_mb"`
+	LogFileMaxAge     time.Duration `config:"log_file_max_age"`
+	LogFileMaxBackups int           `config:"log_file_max_backups"`
+	LogFileCompress   bool          `config:"log_file_compress"`
+	// LogAsyncBufferSize is the number of the records buffered by the async writer.
+	// The records are written synchronously if 0.
+	LogAsyncBufferSize int `config:"log_async_buffer_size"`
+
+	// AccessLogRedirectSamplingRatio is the ratio of the access logs of the redirects
+	// to be written, from 0 to 1. The password attempts are always written.
+	AccessLogRedirectSamplingRatio float64 `config:"access_log_redirect_sampling_ratio" reload:"true"`
 
 	// Settings of tracer agent (like datadog, jagger, etc).
-	AgentHost string
-	AgentPort string
+	AgentHost string `config:"agent_host"`
+	AgentPort string `config:"agent_port"`
+	// TraceExporter is one of "none", "otlp-grpc", "otlp-http" and "stdout".
+	// The otlp ones require AgentHost and AgentPort.
+	TraceExporter string `config:"trace_exporter"`
+	// TraceSamplingRatio is the ratio of the traces to be sampled, from 0 to 1.
+	TraceSamplingRatio float64 `config:"trace_sampling_ratio" reload:"true"`
 
 	// Settings of database.
-	DBDriver   string
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBSSLMode  string
+	// DBDriver is one of "postgres" (lib/pq), "pgx", "pgxpool" and DBDriverMemory.
+	// In case of DBDriverMemory, the other DB settings are ignored.
+	DBDriver   string `config:"db_driver"`
+	DBHost     string `config:"db_host"`
+	DBPort     string `config:"db_port"`
+	DBUser     string `config:"db_user"`
+	DBPassword string `config:"db_password" secret:"true"`
+	DBName     string `config:"db_name"`
+	DBSSLMode  string `config:"db_ssl_mode"`
+
+	// Settings of database connection pool.
+	DBMaxOpenConns    int           `config:"db_max_open_conns"`
+	DBMaxIdleConns    int           `config:"db_max_idle_conns"`
+	DBConnMaxLifetime time.Duration `config:"db_conn_max_lifetime"`
+	DBConnMaxIdleTime time.Duration `config:"db_conn_max_idle_time"`
 }
 
-// get configuration from environment variables.
-func New() Config {
-	var serverHost string
-	if serverHost = os.Getenv("SERVER_HOST"); serverHost == "" {
-		serverHost = defaultHost
-	}
+// Default returns the config with the default values.
+// DB credentials have no defaults, and must be set explicitly.
+func Default() Config {
+	return Config{
+		ServerHost: defaultHost,
+		ServerPort: defaultPort,
 
-	var serverPort string
-	if serverPort = os.Getenv("SERVER_PORT"); serverPort == "" {
-		serverPort = defaultPort
-	}
+		TLSMinVersion: defaultTLSMinVersion,
 
-	agentHort := os.Getenv("AGENT_HOST")
-	agentPort := os.Getenv("AGENT_PORT")
+		ServerReadTimeout:       defaultServerReadTimeout,
+		ServerReadHeaderTimeout: defaultServerReadHeaderTimeout,
+		ServerWriteTimeout:      defaultServerWriteTimeout,
+		ServerIdleTimeout:       defaultServerIdleTimeout,
+		ShutdownDelay:           defaultShutdownDelay,
+		ShutdownTimeout:         defaultShutdownTimeout,
+		HealthCheckTimeout:      defaultHealthCheckTimeout,
+		ConfigWatchInterval:     defaultConfigWatchInterval,
 
-	var dbDriver string
-	if dbDriver = os.Getenv("DB_DRIVER"); dbDriver == "" {
-		dbDriver = "postgres"
-	}
+		RequestIDHeader: defaultRequestIDHeader,
 
-	var dbHost string
-	if dbHost = os.Getenv("DB_HOST"); dbHost == "" {
-		dbHost = "localhost"
-	}
+		DefaultDomain: defaultDomain,
+		PublicBaseURL: defaultPublicBaseURL,
+		DedupPolicy:   defaultDedupPolicy,
+		OwnerHeader:   defaultOwnerHeader,
 
-	var dbPort string
-	if dbPort = os.Getenv("DB_PORT"); dbPort == "" {
-		dbPort = "5432"
-	}
+		MetadataFetchWorkers:   defaultMetadataFetchWorkers,
+		MetadataFetchQueueSize: defaultMetadataFetchQueueSize,
+		MetadataFetchTimeout:   defaultMetadataFetchTimeout,
+		MetadataFetchMaxBytes:  defaultMetadataFetchMaxBytes,
+		MetadataFetchUserAgent: defaultMetadataFetchUserAgent,
 
-	var dbUser string
-	if dbUser = os.Getenv("DB_USER"); dbUser == "" {
-		dbUser = "root"
-	}
+		LinkAccessTTL:         defaultLinkAccessTTL,
+		PasswordMaxAttempts:   defaultPasswordMaxAttempts,
+		PasswordAttemptWindow: defaultPasswordAttemptWindow,
 
-	var dbPassword string
-	if dbPassword = os.Getenv("DB_PASSWORD"); dbPassword == "" {
-		dbPassword = "root"
-	}
+		MetricsHost: defaultHost,
+		MetricsPort: defaultMetricsPort,
 
-	var dbName string
-	if dbName = os.Getenv("DB_NAME"); dbName == "" {
-		dbName = "postgresql"
-	}
+		LogLevel: defaultLogLevel,
+
+		LogStdoutLevel:     defaultLogSinkLevel,
+		LogFileLevel:       defaultLogSinkLevel,
+		LogFileMaxSizeMB:   defaultLogFileMaxSizeMB,
+		LogFileMaxAge:      defaultLogFileMaxAge,
+		LogFileMaxBackups:  defaultLogFileMaxBackups,
+		LogFileCompress:    defaultLogFileCompress,
+		LogAsyncBufferSize: defaultLogAsyncBufferSize,
+
+		AccessLogRedirectSamplingRatio: defaultAccessLogRedirectSamplingRatio,
 
-	var dbSslMode string
-	if dbSslMode = os.Getenv("DB_SSL_MODE"); dbSslMode == "" {
-		dbSslMode = "disable"
+		TraceExporter:      defaultTraceExporter,
+		TraceSamplingRatio: defaultTraceSamplingRatio,
+
+		DBDriver:  defaultDBDriver,
+		DBHost:    defaultDBHost,
+		DBPort:    defaultDBPort,
+		DBName:    defaultDBName,
+		DBSSLMode: defaultDBSSLMode,
+
+		DBMaxOpenConns:    defaultDBMaxOpenConns,
+		DBMaxIdleConns:    defaultDBMaxIdleConns,
+		DBConnMaxLifetime: defaultDBConnMaxLifetime,
+		DBConnMaxIdleTime: defaultDBConnMaxIdleTime,
 	}
+}
 
-	return Config{
-		ServerHost: serverHost,
-		ServerPort: serverPort,
-		AgentHost:  agentHort,
-		AgentPort:  agentPort,
-		DBDriver:   dbDriver,
-		DBHost:     dbHost,
-		DBPort:     dbPort,
-		DBUser:     dbUser,
-		DBPassword: dbPassword,
-		DBName:     dbName,
-		DBSSLMode:  dbSslMode,
+// TrustedProxyList returns the entries of TrustedProxies.
+func (c Config) TrustedProxyList() []string {
+	var proxies []string
+
+	for _, p := range strings.Split(c.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
 	}
+
+	return proxies
 }
@@ -0,0 +1,220 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/config"
+)
+
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600), "failed to write file")
+
+	return path
+}
+
+func Test_Load(t *testing.T) {
+	t.Parallel()
+
+	withCredentials := func(modify func(c *config.Config)) config.Config {
+		c := config.Default()
+		c.DBUser = "user"
+		c.DBPassword = "password"
+
+		if modify != nil {
+			modify(&c)
+		}
+
+		return c
+	}
+
+	testCases := map[string]struct {
+		args    func(t *testing.T) []string
+		env     func(t *testing.T) map[string]string
+		want    config.Config
+		wantErr string
+	}{
+		"success: defaults": {
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"DB_USER": "user", "DB_PASSWORD": "password"}
+			},
+			want: withCredentials(nil),
+		},
+		"success: flags override env which overrides file": {
+			args: func(t *testing.T) []string {
+				path := writeFile(t, "config.yaml", "server_port: 8081\nlog_level: warn\ndb_user: user\ndb_password: password\ntrace_sampling_ratio: 0.5\n")
+
+				return []string{"-config", path, "-server-port", "8083"}
+			},
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"SERVER_PORT": "8082", "LOG_LEVEL": "error"}
+			},
+			want: withCredentials(func(c *config.Config) {
+				c.ServerPort = "8083"
+				c.LogLevel = "error"
+				c.TraceSamplingRatio = 0.5
+			}),
+		},
+		"success: toml file from env": {
+			env: func(t *testing.T) map[string]string {
+				path := writeFile(t, "config.toml", "db_user = \"user\"\ndb_password = \"password\"\nlog_file_compress = false\nshutdown_delay = \"1s\"\ndb_max_open_conns = 5\n")
+
+				return map[string]string{"CONFIG_FILE": path}
+			},
+			want: withCredentials(func(c *config.Config) {
+				c.LogFileCompress = false
+				c.ShutdownDelay = time.Second
+				c.DBMaxOpenConns = 5
+			}),
+		},
+		"success: secret from file": {
+			args: func(t *testing.T) []string {
+				return []string{"-admin-token-file", writeFile(t, "token", "token\n")}
+			},
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"DB_USER": "user", "DB_PASSWORD_FILE": writeFile(t, "password", "password\n")}
+			},
+			want: withCredentials(func(c *config.Config) {
+				c.AdminToken = "token"
+			}),
+		},
+		"success: memory driver does not need credentials": {
+			args: func(t *testing.T) []string {
+				return []string{"-db-driver", "memory"}
+			},
+			want: func() config.Config {
+				c := config.Default()
+				c.DBDriver = config.DBDriverMemory
+
+				return c
+			}(),
+		},
+		"failure: both secret and its file": {
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"DB_USER": "user", "DB_PASSWORD": "password", "DB_PASSWORD_FILE": "/dev/null"}
+			},
+			wantErr: "both DB_PASSWORD and DB_PASSWORD_FILE are set",
+		},
+		"failure: invalid types": {
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"DB_MAX_OPEN_CONNS": "many", "SHUTDOWN_DELAY": "5", "LOG_FILE_COMPRESS": "yes"}
+			},
+			wantErr: "invalid SHUTDOWN_DELAY \"5\": time: missing unit in duration \"5\"\n" +
+				"invalid LOG_FILE_COMPRESS \"yes\": not a bool\n" +
+				"invalid DB_MAX_OPEN_CONNS \"many\": not an integer",
+		},
+		"failure: validation errors are aggregated": {
+			args: func(t *testing.T) []string {
+				return []string{"-server-port", "0", "-trace-exporter", "zipkin"}
+			},
+			wantErr: "server_port must be a port number: \"0\"\n" +
+				"trace_exporter must be one of none, otlp-grpc, otlp-http and stdout: \"zipkin\"\n" +
+				"db_user is required\n" +
+				"db_password is required",
+		},
+		"failure: otlp exporter without agent": {
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"DB_USER": "user", "DB_PASSWORD": "password", "TRACE_EXPORTER": "otlp-grpc"}
+			},
+			wantErr: "agent_host is required for trace_exporter otlp-grpc\n" +
+				"agent_port must be a port number: \"\"",
+		},
+		"failure: invalid password settings": {
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{
+					"DB_USER": "user", "DB_PASSWORD": "password",
+					"LINK_ACCESS_SECRET": "short", "TRUSTED_PROXIES": "10.0.0.0/8, proxy.local",
+				}
+			},
+			wantErr: "link_access_secret must be at least 32 bytes\n" +
+				"trusted_proxies must be ips or CIDRs: \"proxy.local\"",
+		},
+		"failure: unknown key in file": {
+			args: func(t *testing.T) []string {
+				return []string{"-config", writeFile(t, "config.yml", "server_prot: 8081\n")}
+			},
+			wantErr: "unknown key in config file: server_prot",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			var args []string
+			if tc.args != nil {
+				args = tc.args(t)
+			}
+
+			env := map[string]string{}
+			if tc.env != nil {
+				env = tc.env(t)
+			}
+
+			// Act
+			got, err := config.Load(args, lookupEnv(env))
+
+			// Assert
+			if tc.wantErr != "" {
+				require.Error(t, err, "error should be returned")
+				assert.Equal(t, tc.wantErr, err.Error(), "error message should be equal")
+
+				return
+			}
+
+			require.NoError(t, err, "error should be nil")
+			assert.Equal(t, tc.want, got, "config should be equal")
+		})
+	}
+}
+
+func Test_Config_Print(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	cfg := config.Default()
+	cfg.DBUser = "user"
+	cfg.DBPassword = "password"
+	cfg.TraceSamplingRatio = 0.25
+
+	var b bytes.Buffer
+
+	// Act
+	err := cfg.Print(&b)
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.NotContains(t, b.String(), "password\n", "secret should be redacted")
+	assert.Contains(t, b.String(), "db_password: REDACTED\n", "secret should be redacted")
+	assert.Contains(t, b.String(), "admin_token: \"\"\n", "empty secret should be printed as is")
+
+	// 出力はそのまま設定ファイルとして読み込めること。
+	path := writeFile(t, "config.yaml", b.String())
+
+	got, err := config.Load([]string{"-config", path}, lookupEnv(nil))
+	require.NoError(t, err, "printed config should be loadable")
+
+	cfg.DBPassword = "REDACTED"
+	assert.Equal(t, cfg, got, "printed config should be equal")
+}
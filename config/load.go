@@ -0,0 +1,330 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// envConfigFile is the env of the path to the config file, which is also set by -config flag.
+const envConfigFile = "CONFIG_FILE"
+
+// secretFileSuffix is added to the name of the secret field to read it from the file.
+const secretFileSuffix = "_file"
+
+// field is a settable field of Config.
+type field struct {
+	name       string
+	secret     bool
+	reloadable bool
+	value      reflect.Value
+}
+
+func fieldsOf(cfg *Config) []field {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+
+	fields := make([]field, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup("config")
+		if !ok {
+			continue
+		}
+
+		fields = append(fields, field{
+			name:       name,
+			secret:     t.Field(i).Tag.Get("secret") == "true",
+			reloadable: t.Field(i).Tag.Get("reload") == "true",
+			value:      v.Field(i),
+		})
+	}
+
+	return fields
+}
+
+// layer is a source of the config.
+type layer struct {
+	// key returns the key of the field in this layer (e.g. DB_HOST for db_host in the env).
+	key    func(name string) string
+	lookup func(key string) (string, bool)
+}
+
+// Load returns the config from the layers below, where the latter overrides the former.
+//
+//  1. default values
+//  2. config file (YAML or TOML) given by -config flag or CONFIG_FILE env
+//  3. env
+//  4. command-line flags
+//
+// All errors of parsing and validation are returned together.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+	fields := fieldsOf(&cfg)
+
+	path, flagValues, err := parseFlags(fields, args, lookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var layers []layer
+
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+
+		if err := checkUnknownKeys(fields, values); err != nil {
+			return Config{}, err
+		}
+
+		layers = append(layers, layer{
+			key: func(name string) string { return name },
+			lookup: func(key string) (string, bool) {
+				v, ok := values[key]
+
+				return v, ok
+			},
+		})
+	}
+
+	layers = append(layers,
+		layer{key: envName, lookup: lookupEnv},
+		layer{
+			key: flagName,
+			lookup: func(key string) (string, bool) {
+				v, ok := flagValues[key]
+
+				return v, ok
+			},
+		},
+	)
+
+	var errs []error
+
+	for _, l := range layers {
+		for _, f := range fields {
+			if err := l.apply(f); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// 読み込めなかった値があると検証結果が紛らわしいため、先に返す。
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// parseFlags returns the path to the config file and the values of the flags.
+func parseFlags(
+	fields []field, args []string, lookupEnv func(string) (string, bool),
+) (string, map[string]string, error) {
+	fs := flag.NewFlagSet("url-shortener", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the config file (.yaml, .yml or .toml)")
+
+	flagValues := map[string]string{}
+
+	for _, f := range fields {
+		name := f.name
+		if f.secret {
+			name += secretFileSuffix
+		}
+
+		fs.Func(flagName(name), "same as "+envName(name), func(s string) error {
+			flagValues[flagName(name)] = s
+
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return "", nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	path := *configPath
+	if path == "" {
+		path, _ = lookupEnv(envConfigFile)
+	}
+
+	return path, flagValues, nil
+}
+
+func (l layer) apply(f field) error {
+	key := l.key(f.name)
+	raw, ok := l.lookup(key)
+
+	if f.secret {
+		fileKey := l.key(f.name + secretFileSuffix)
+
+		if path, fileOK := l.lookup(fileKey); fileOK {
+			if ok {
+				return fmt.Errorf("both %s and %s are set", key, fileKey)
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", fileKey, err)
+			}
+
+			// ファイル末尾の改行は値に含めない。
+			raw, ok, key = strings.TrimRight(string(b), "\r\n"), true, fileKey
+		}
+	}
+
+	if !ok {
+		return nil
+	}
+
+	if err := set(f.value, raw); err != nil {
+		if f.secret {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+
+		return fmt.Errorf("invalid %s %q: %w", key, raw, err)
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses the raw value in the type of the field.
+func set(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			//nolint:wrapcheck
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	//nolint:exhaustive
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+
+		v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("not a bool")
+		}
+
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+
+	return nil
+}
+
+// readFile reads the config file into the map from the keys to the raw values.
+func readFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var m map[string]interface{}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &m)
+	case ".toml":
+		err = toml.Unmarshal(b, &m)
+	default:
+		return nil, fmt.Errorf("unsupported config file: %s", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	values := make(map[string]string, len(m))
+
+	for k, v := range m {
+		switch v := v.(type) {
+		case nil:
+			values[k] = ""
+		case string:
+			values[k] = v
+		case int, int64, uint64, float64, bool:
+			values[k] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("invalid %s in config file: must be a scalar", k)
+		}
+	}
+
+	return values, nil
+}
+
+// checkUnknownKeys rejects the typos in the config file, which would be ignored silently otherwise.
+func checkUnknownKeys(fields []field, values map[string]string) error {
+	known := map[string]bool{}
+
+	for _, f := range fields {
+		known[f.name] = true
+		if f.secret {
+			known[f.name+secretFileSuffix] = true
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var errs []error
+
+	for _, k := range keys {
+		if !known[k] {
+			errs = append(errs, fmt.Errorf("unknown key in config file: %s", k))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func envName(name string) string {
+	return strings.ToUpper(name)
+}
+
+func flagName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// Print writes the config in YAML, which can be used as the config file.
+// The secret values are redacted.
+func (c Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, f := range fieldsOf(&c) {
+		value := scalarOf(f.value)
+		if f.secret && f.value.String() != "" {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redacted}
+		}
+
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.name},
+			value,
+		)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(root); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	//nolint:wrapcheck
+	return enc.Close()
+}
+
+func scalarOf(v reflect.Value) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode}
+
+	if v.Type() == durationType {
+		n.Tag, n.Value = "!!str", fmt.Sprint(v.Interface())
+
+		return n
+	}
+
+	//nolint:exhaustive
+	switch v.Kind() {
+	case reflect.Int:
+		n.Tag, n.Value = "!!int", strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		n.Tag, n.Value = "!!float", strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		n.Tag, n.Value = "!!bool", strconv.FormatBool(v.Bool())
+	default:
+		n.Tag, n.Value = "!!str", v.String()
+	}
+
+	return n
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package config
+
+import "context"
+
+// HandleReloadSignals does nothing, since SIGHUP is not available.
+func (w *Watcher) HandleReloadSignals(_ context.Context) {}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleReloadSignals reloads the config by SIGHUP until ctx is done.
+func (w *Watcher) HandleReloadSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				//nolint:errcheck
+				w.Reload(ctx)
+			}
+		}
+	}()
+}
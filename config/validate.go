@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tlsutil"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+// Drivers of database/database.Connect.
+//
+//nolint:gochecknoglobals
+var sqlDrivers = map[string]bool{"postgres": true, "pgx": true, "pgxpool": true}
+
+// Policies of usecase.DedupPolicy.
+//
+//nolint:gochecknoglobals
+var dedupPolicies = map[string]bool{"global": true, "per-owner": true, "never": true}
+
+const minLinkAccessSecretLength = 32
+
+// Validate returns all invalid settings together.
+func (c Config) Validate() error {
+	var errs []error
+
+	check := func(ok bool, format string, a ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, a...))
+		}
+	}
+
+	checkPort := func(name, port string) {
+		p, err := strconv.Atoi(port)
+		check(err == nil && 0 < p && p <= 65535, "%s must be a port number: %q", name, port)
+	}
+
+	checkPositive := func(name string, d time.Duration) {
+		check(d > 0, "%s must be positive: %s", name, d)
+	}
+
+	checkNonNegative := func(name string, i int) {
+		check(i >= 0, "%s must not be negative: %d", name, i)
+	}
+
+	checkRatio := func(name string, r float64) {
+		check(0 <= r && r <= 1, "%s must be from 0 to 1: %v", name, r)
+	}
+
+	checkLevel := func(name, level string) {
+		_, err := logger.ParseLevel(level)
+		check(err == nil, "%s must be a log level: %q", name, level)
+	}
+
+	checkPort("server_port", c.ServerPort)
+	checkPort("metrics_port", c.MetricsPort)
+	check(c.ServerPort != c.MetricsPort || c.ServerHost != c.MetricsHost, "metrics_port must differ from server_port")
+
+	c.validateTLS(check, checkPort)
+
+	checkPositive("server_read_timeout", c.ServerReadTimeout)
+	checkPositive("server_read_header_timeout", c.ServerReadHeaderTimeout)
+	checkPositive("server_write_timeout", c.ServerWriteTimeout)
+	checkPositive("server_idle_timeout", c.ServerIdleTimeout)
+	checkPositive("shutdown_timeout", c.ShutdownTimeout)
+	checkPositive("health_check_timeout", c.HealthCheckTimeout)
+	check(c.ShutdownDelay >= 0, "shutdown_delay must not be negative: %s", c.ShutdownDelay)
+	check(c.ConfigWatchInterval >= 0, "config_watch_interval must not be negative: %s", c.ConfigWatchInterval)
+
+	check(c.RequestIDHeader != "", "request_id_header is required")
+	check(c.DefaultDomain != "", "default_domain is required")
+	check(dedupPolicies[c.DedupPolicy], "dedup_policy must be global, per-owner or never: %q", c.DedupPolicy)
+	check(c.OwnerHeader != "", "owner_header is required")
+	u, err := url.Parse(c.PublicBaseURL)
+	check(
+		err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.RawQuery == "" && u.Fragment == "",
+		"public_base_url must be an absolute http or https url without query: %q", c.PublicBaseURL,
+	)
+
+	checkNonNegative("metadata_fetch_workers", c.MetadataFetchWorkers)
+
+	if c.MetadataFetchWorkers > 0 {
+		checkNonNegative("metadata_fetch_queue_size", c.MetadataFetchQueueSize)
+		checkPositive("metadata_fetch_timeout", c.MetadataFetchTimeout)
+		check(c.MetadataFetchMaxBytes > 0, "metadata_fetch_max_bytes must be positive: %d", c.MetadataFetchMaxBytes)
+		check(c.MetadataFetchUserAgent != "", "metadata_fetch_user_agent is required")
+	}
+
+	// HMAC-SHA256 の鍵として、ハッシュ長以上を要求する。
+	check(c.LinkAccessSecret == "" || len(c.LinkAccessSecret) >= minLinkAccessSecretLength,
+		"link_access_secret must be at least %d bytes", minLinkAccessSecretLength)
+	checkPositive("link_access_ttl", c.LinkAccessTTL)
+	checkNonNegative("password_max_attempts", c.PasswordMaxAttempts)
+
+	if c.PasswordMaxAttempts > 0 {
+		checkPositive("password_attempt_window", c.PasswordAttemptWindow)
+	}
+
+	for _, p := range c.TrustedProxyList() {
+		_, _, err := net.ParseCIDR(p)
+		check(err == nil || net.ParseIP(p) != nil, "trusted_proxies must be ips or CIDRs: %q", p)
+	}
+
+	checkLevel("log_level", c.LogLevel)
+	checkLevel("log_stdout_level", c.LogStdoutLevel)
+	checkLevel("log_file_level", c.LogFileLevel)
+
+	if _, err := logger.ParseOverrides(c.LogLevelOverrides); err != nil {
+		errs = append(errs, fmt.Errorf("invalid log_level_overrides: %w", err))
+	}
+
+	checkNonNegative("log_file_max_size_mb", c.LogFileMaxSizeMB)
+	checkNonNegative("log_file_max_backups", c.LogFileMaxBackups)
+	checkNonNegative("log_async_buffer_size", c.LogAsyncBufferSize)
+	check(c.LogFileMaxAge >= 0, "log_file_max_age must not be negative: %s", c.LogFileMaxAge)
+
+	checkRatio("access_log_redirect_sampling_ratio", c.AccessLogRedirectSamplingRatio)
+	checkRatio("trace_sampling_ratio", c.TraceSamplingRatio)
+
+	switch c.TraceExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+		if c.AgentPort != "" {
+			checkPort("agent_port", c.AgentPort)
+		}
+	case tracing.ExporterOTLPGRPC, tracing.ExporterOTLPHTTP:
+		check(c.AgentHost != "", "agent_host is required for trace_exporter %s", c.TraceExporter)
+		checkPort("agent_port", c.AgentPort)
+	default:
+		errs = append(errs, fmt.Errorf(
+			"trace_exporter must be one of none, otlp-grpc, otlp-http and stdout: %q", c.TraceExporter,
+		))
+	}
+
+	switch {
+	case c.DBDriver == DBDriverMemory:
+	case sqlDrivers[c.DBDriver]:
+		check(c.DBHost != "", "db_host is required")
+		checkPort("db_port", c.DBPort)
+		check(c.DBUser != "", "db_user is required")
+		check(c.DBPassword != "", "db_password is required")
+		check(c.DBName != "", "db_name is required")
+		checkNonNegative("db_max_open_conns", c.DBMaxOpenConns)
+		checkNonNegative("db_max_idle_conns", c.DBMaxIdleConns)
+		check(c.DBConnMaxLifetime >= 0, "db_conn_max_lifetime must not be negative: %s", c.DBConnMaxLifetime)
+		check(c.DBConnMaxIdleTime >= 0, "db_conn_max_idle_time must not be negative: %s", c.DBConnMaxIdleTime)
+	default:
+		errs = append(errs, fmt.Errorf("db_driver must be one of postgres, pgx, pgxpool and memory: %q", c.DBDriver))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Config) validateTLS(check func(bool, string, ...interface{}), checkPort func(string, string)) {
+	_, err := tlsutil.ParseVersion(c.TLSMinVersion)
+	check(err == nil, "tls_min_version must be 1.2 or 1.3: %q", c.TLSMinVersion)
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "tls_cert_file and tls_key_file must be set together")
+
+	tlsEnabled := c.TLSCertFile != ""
+
+	// h2c は平文の通信でのみ使える。
+	check(!(tlsEnabled && c.ServerH2C), "server_h2c cannot be used with tls")
+
+	if c.HTTPRedirectPort != "" {
+		check(tlsEnabled, "http_redirect_port requires tls_cert_file and tls_key_file")
+		checkPort("http_redirect_port", c.HTTPRedirectPort)
+		check(c.HTTPRedirectPort != c.ServerPort, "http_redirect_port must differ from server_port")
+	}
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+// Subscriber applies the reloaded settings to a running component.
+// OnReload is called only when some reloadable setting is changed.
+type Subscriber interface {
+	OnReload(ctx context.Context, prev, next Config)
+}
+
+// SubscriberFunc is an adapter to use a function as a Subscriber.
+type SubscriberFunc func(ctx context.Context, prev, next Config)
+
+func (f SubscriberFunc) OnReload(ctx context.Context, prev, next Config) {
+	f(ctx, prev, next)
+}
+
+// Watcher reloads the config file, and notifies the subscribers of the changed settings.
+// Changes to the settings which are not reloadable (e.g. ports, DB settings) are ignored with a warning,
+// and the invalid config is ignored with an error.
+type Watcher struct {
+	args      []string
+	lookupEnv func(string) (string, bool)
+	logger    logger.Logger
+
+	// mu serializes the reloads.
+	mu          sync.Mutex
+	current     Config
+	subscribers []Subscriber
+}
+
+// NewWatcher returns the watcher of the config loaded by Load with the same args and env.
+func NewWatcher(cfg Config, args []string, lookupEnv func(string) (string, bool), logger logger.Logger) *Watcher {
+	return &Watcher{
+		args:      args,
+		lookupEnv: lookupEnv,
+		logger:    logger,
+		current:   cfg,
+	}
+}
+
+// Current returns the config which the last reload applied.
+func (w *Watcher) Current() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current
+}
+
+func (w *Watcher) Subscribe(s Subscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, s)
+}
+
+// Reload loads the config again, and applies the changed reloadable settings.
+func (w *Watcher) Reload(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next, err := Load(w.args, w.lookupEnv)
+	if err != nil {
+		w.logger.WithFields(logger.Err(err)).Error(ctx, "config is not reloaded since it is invalid")
+
+		return err
+	}
+
+	prev := w.current
+	merged := prev
+
+	var changed []string
+
+	nextFields := fieldsOf(&next)
+
+	for i, f := range fieldsOf(&merged) {
+		if reflect.DeepEqual(f.value.Interface(), nextFields[i].value.Interface()) {
+			continue
+		}
+
+		if !f.reloadable {
+			w.logger.WithFields(logger.String("key", f.name)).
+				Warn(ctx, "config change is ignored since it is not reloadable, restart to apply it")
+
+			continue
+		}
+
+		f.value.Set(nextFields[i].value)
+		changed = append(changed, f.name)
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	w.current = merged
+
+	w.logger.WithFields(logger.Any("keys", changed)).Info(ctx, "config is reloaded")
+
+	for _, s := range w.subscribers {
+		s.OnReload(ctx, prev, merged)
+	}
+
+	return nil
+}
+
+// Watch reloads the config file every time it is modified, until ctx is done.
+// It does nothing if there is no config file or the interval is 0.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) {
+	path, _, err := parseFlags(fieldsOf(&Config{}), w.args, w.lookupEnv)
+	if err != nil || path == "" || interval <= 0 {
+		return
+	}
+
+	last, _ := stat(path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// ConfigMap の更新のように symlink が差し替えられても検知できるよう、stat で比較する。
+				cur, err := stat(path)
+				if err != nil || cur == last {
+					continue
+				}
+
+				last = cur
+
+				//nolint:errcheck
+				w.Reload(ctx)
+			}
+		}
+	}()
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, fmt.Errorf("failed to stat: %w", err)
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
@@ -0,0 +1,150 @@
+package config_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/config"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+const baseConfigFile = "db_driver: memory\nlog_level: info\nserver_port: 8080\n"
+
+func Test_Watcher_Reload(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		next         string
+		wantErr      bool
+		wantNotified bool
+		wantLogLevel string
+		wantPort     string
+		wantLog      string
+	}{
+		"success: reloadable setting": {
+			next:         "db_driver: memory\nlog_level: error\nserver_port: 8080\n",
+			wantNotified: true,
+			wantLogLevel: "error",
+			wantPort:     "8080",
+			wantLog:      "config is reloaded",
+		},
+		"success: not reloadable setting is ignored": {
+			next:         "db_driver: memory\nlog_level: info\nserver_port: 8081\n",
+			wantNotified: false,
+			wantLogLevel: "info",
+			wantPort:     "8080",
+			wantLog:      `"key":"server_port"`,
+		},
+		"success: only reloadable setting is applied": {
+			next:         "db_driver: memory\nlog_level: debug\nserver_port: 8081\n",
+			wantNotified: true,
+			wantLogLevel: "debug",
+			wantPort:     "8080",
+			wantLog:      `"key":"server_port"`,
+		},
+		"failure: invalid config": {
+			next:         "db_driver: memory\nlog_level: verbose\nserver_port: 8080\n",
+			wantErr:      true,
+			wantNotified: false,
+			wantLogLevel: "info",
+			wantPort:     "8080",
+			wantLog:      "config is not reloaded since it is invalid",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			path := writeFile(t, "config.yaml", baseConfigFile)
+			args := []string{"-config", path}
+
+			cfg, err := config.Load(args, lookupEnv(nil))
+			require.NoError(t, err, "initial config should be valid")
+
+			b := bytes.NewBuffer([]byte{})
+			w := config.NewWatcher(cfg, args, lookupEnv(nil), logger.NewBasicLogger(b, "test", "config"))
+
+			var notified *config.Config
+
+			w.Subscribe(config.SubscriberFunc(func(ctx context.Context, prev, next config.Config) {
+				assert.Equal(t, cfg, prev, "previous config should be passed")
+
+				notified = &next
+			}))
+
+			require.NoError(t, os.WriteFile(path, []byte(tc.next), 0o600), "failed to update config file")
+
+			// Act
+			err = w.Reload(context.Background())
+
+			// Assert
+			if tc.wantErr {
+				require.Error(t, err, "error should be returned")
+			} else {
+				require.NoError(t, err, "error should be nil")
+			}
+
+			assert.Equal(t, tc.wantNotified, notified != nil, "subscriber should be notified only if changed")
+			assert.Equal(t, tc.wantLogLevel, w.Current().LogLevel, "log level should be equal")
+			assert.Equal(t, tc.wantPort, w.Current().ServerPort, "server port should not be changed")
+			assert.Contains(t, b.String(), tc.wantLog, "log should contain expected string")
+
+			if notified != nil {
+				assert.Equal(t, w.Current(), *notified, "current config should be passed")
+			}
+		})
+	}
+}
+
+func Test_Watcher_Watch(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	path := writeFile(t, "config.yaml", baseConfigFile)
+	args := []string{"-config", path}
+
+	cfg, err := config.Load(args, lookupEnv(nil))
+	require.NoError(t, err, "initial config should be valid")
+
+	w := config.NewWatcher(cfg, args, lookupEnv(nil), logger.NewBasicLogger(bytes.NewBuffer(nil), "test", "config"))
+
+	var (
+		mu    sync.Mutex
+		ratio float64
+	)
+
+	w.Subscribe(config.SubscriberFunc(func(ctx context.Context, prev, next config.Config) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ratio = next.TraceSamplingRatio
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	w.Watch(ctx, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, []byte(baseConfigFile+"trace_sampling_ratio: 0.1\n"), 0o600), "failed to update config file")
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return ratio == 0.1
+	}, time.Second, 10*time.Millisecond, "config should be reloaded on change")
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "errors"
+
+// Errors returned by the repositories when a unique constraint is violated.
+// Implementations must translate their driver specific errors into these,
+// so that the usecase does not depend on any database driver.
+var (
+	// ErrURLAlreadyExists means the original url has already been shortened with the same dedup key.
+	ErrURLAlreadyExists = errors.New("url already exists")
+	// ErrShortURLAlreadyExists means the short url (slug) is already used by another url.
+	ErrShortURLAlreadyExists = errors.New("short url already exists")
+)
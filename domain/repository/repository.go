@@ -2,10 +2,53 @@ package repository
 
 import (
 	"context"
+	"time"
 )
 
+// SchemaVersion is the version of the schema which this server expects.
+// It must be incremented with init.sql and a new file in migrations/ when the schema is changed.
+const SchemaVersion = 7
+
 type Database interface {
 	Health(ctx context.Context) error
+	Stats() DBStats
+	// SchemaVersion returns the version of the schema applied to the database.
+	SchemaVersion(ctx context.Context) (int, error)
+
+	// SearchURLFromShortURL returns the destination of the short url on the domain (host).
+	SearchURLFromShortURL(ctx context.Context, domain, shortURL string) (Destination, error)
+}
+
+// Destination is what the redirect of the short url needs.
+type Destination struct {
+	// ID is the id of the short url, which is used to count the clicks.
+	ID  int
+	URL string
+	// PasswordHash is the bcrypt hash of the password, which is empty if the short url is not protected.
+	PasswordHash string
+	// MaxClicks is the number of the redirects allowed, which is unlimited if 0.
+	MaxClicks int
+	// Clicks is the number of the redirects counted so far, which is counted only if MaxClicks is set.
+	Clicks int
+}
+
+// Exhausted reports whether the short url has no clicks left.
+func (d Destination) Exhausted() bool {
+	return d.MaxClicks > 0 && d.Clicks >= d.MaxClicks
+}
+
+// Cacheable reports whether the clients may cache the redirect,
+// which is false if every access must be checked by the server (the password or the clicks).
+func (d Destination) Cacheable() bool {
+	return d.PasswordHash == "" && d.MaxClicks == 0
+}
 
-	SearchURLFromShortURL(ctx context.Context, shortURL string) (string, error)
+// DBStats is the statistics of the connection pool.
+type DBStats struct {
+	MaxOpenConnections int
+	OpenConnections    int
+	InUse              int
+	Idle               int
+	WaitCount          int64
+	WaitDuration       time.Duration
 }
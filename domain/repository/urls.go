@@ -2,11 +2,94 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 )
 
+// URLRepository handles the short urls, which are unique per domain (host).
 type URLRepository interface {
-	SelectShortURL(ctx context.Context, tx transaction.RWTx, originalURL string) (string, error)
-	InsertURL(ctx context.Context, tx transaction.RWTx, originalURL string, shortURL string) error
+	// SelectURL returns the short url on the domain, or apperr.ErrShortURLNotFound.
+	SelectURL(ctx context.Context, tx transaction.RWTx, domain, shortURL string) (URL, error)
+	// SelectURLByDedupKey returns the short url with the dedup key on the domain.
+	SelectURLByDedupKey(ctx context.Context, tx transaction.RWTx, domain, dedupKey string) (URL, error)
+	// ListURLs returns the short urls matching the filter, newest first.
+	ListURLs(ctx context.Context, tx transaction.RWTx, filter URLFilter) ([]URL, error)
+	// InsertURL returns the inserted url with the ID and CreatedAt,
+	// or apperr.ErrDomainNotFound if the domain is not registered.
+	InsertURL(ctx context.Context, tx transaction.RWTx, url URL) (URL, error)
+	// UpdateURL updates the title, the notes, the password hash and the dedup key of the url with the ID,
+	// where the empty dedup key stops sharing the url.
+	UpdateURL(ctx context.Context, tx transaction.RWTx, url URL) error
+	// ConsumeClick counts a click of the url with the ID, and returns the clicks left,
+	// or apperr.ErrShortURLExhausted if no click is left.
+	// It must be atomic, so that the concurrent clicks never exceed MaxClicks.
+	ConsumeClick(ctx context.Context, tx transaction.RWTx, id int) (int, error)
+}
+
+// TagRepository handles the tags of the short urls.
+type TagRepository interface {
+	// SetTags replaces the tags of the url, creating the new tags.
+	SetTags(ctx context.Context, tx transaction.RWTx, urlID int, tags []string) error
+	// ListTags returns the sorted tags of each url.
+	ListTags(ctx context.Context, tx transaction.RWTx, urlIDs []int) (map[int][]string, error)
+}
+
+// PageMetadataRepository handles the metadata of the destination pages.
+type PageMetadataRepository interface {
+	// UpsertPageMetadata stores the metadata of the url, replacing the old one.
+	UpsertPageMetadata(ctx context.Context, tx transaction.RWTx, urlID int, page PageMetadata) error
+	// ListPageMetadata returns the metadata of each url, which is missing if not fetched yet.
+	ListPageMetadata(ctx context.Context, tx transaction.RWTx, urlIDs []int) (map[int]PageMetadata, error)
+}
+
+// PageMetadata is the metadata of the destination page of the short url.
+type PageMetadata struct {
+	Title       string
+	Description string
+	// ImageURL is the absolute url of the Open Graph image.
+	ImageURL  string
+	FetchedAt time.Time
+}
+
+// URL is a short url of the original url on the domain.
+type URL struct {
+	ID     int
+	Domain string
+	URL    string
+	Short  string
+	// Owner is the one who created the short url, which is empty if unknown.
+	Owner string
+	// DedupKey is unique per domain, and the short url with the same key is reused
+	// instead of creating a new one. It is empty if the short url is never reused.
+	DedupKey string
+	Title    string
+	Notes    string
+	// PasswordHash is the bcrypt hash of the password, which is empty if the short url is not protected.
+	PasswordHash string
+	// MaxClicks is the number of the redirects allowed, which is unlimited if 0.
+	MaxClicks int
+	// Clicks is the number of the redirects counted so far, which is counted only if MaxClicks is set.
+	Clicks int
+	// Tags are stored by TagRepository, not by URLRepository.
+	Tags []string
+	// Page is stored by PageMetadataRepository, and is nil if not fetched yet.
+	Page      *PageMetadata
+	CreatedAt time.Time
+}
+
+// ClicksLeft returns the number of the redirects left, which is meaningless if MaxClicks is 0.
+func (u URL) ClicksLeft() int {
+	return max(u.MaxClicks-u.Clicks, 0)
+}
+
+// URLFilter is the condition of ListURLs. The empty fields match any url.
+type URLFilter struct {
+	Domain string
+	Owner  string
+	// Tags matches the urls which have all of them.
+	Tags []string
+	// Limit is the max number of the urls, which is unlimited if 0.
+	Limit  int
+	Offset int
 }
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/model/request"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+// adminAuthMW accepts only the requests with "Authorization: Bearer <admin token>".
+func (h *handler) adminAuthMW() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !h.isAdmin(c) {
+			handleError(c, h.logger, apperr.ErrUnauthorized)
+			c.Abort()
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isAdmin reports whether the request has the admin token, which is never true if the token is not set.
+func (h *handler) isAdmin(c *gin.Context) bool {
+	if h.adminToken == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
+func (h *handler) GetLogLevel(c *gin.Context) error {
+	c.JSON(http.StatusOK, logLevelResponse(h.logger.Levels()))
+
+	return nil
+}
+
+func (h *handler) UpdateLogLevel(c *gin.Context) error {
+	var body request.UpdateLogLevel
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+		return apperr.ErrRequestBodyInvalid
+	}
+
+	levels := h.logger.Levels()
+
+	// 全て検証してから反映し、一部だけ変更されることを防ぐ。
+	var (
+		level     logger.Level
+		overrides map[string]logger.Level
+		err       error
+	)
+
+	if body.Level != "" {
+		if level, err = logger.ParseLevel(body.Level); err != nil {
+			return apperr.ErrRequestBodyInvalid.WithDetails(apperr.Detail{Field: "level", Message: err.Error()})
+		}
+	}
+
+	if body.Overrides != nil {
+		overrides = make(map[string]logger.Level, len(body.Overrides))
+
+		for pkg, name := range body.Overrides {
+			if overrides[pkg], err = logger.ParseLevel(name); err != nil {
+				return apperr.ErrRequestBodyInvalid.WithDetails(apperr.Detail{Field: "overrides." + pkg, Message: err.Error()})
+			}
+		}
+	}
+
+	if body.Level != "" {
+		levels.SetLevel(level)
+	}
+
+	if overrides != nil {
+		levels.SetOverrides(overrides)
+	}
+
+	h.logger.Warnf(c.Request.Context(), "log level is changed to %s (overrides: %v)", levels.Level(), levels.Overrides())
+
+	c.JSON(http.StatusOK, logLevelResponse(levels))
+
+	return nil
+}
+
+func logLevelResponse(levels *logger.LevelController) gin.H {
+	overrides := gin.H{}
+	for pkg, lv := range levels.Overrides() {
+		overrides[pkg] = lv.String()
+	}
+
+	return gin.H{
+		"level":     levels.Level().String(),
+		"overrides": overrides,
+	}
+}
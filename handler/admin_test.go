@@ -0,0 +1,95 @@
+package handler_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kokoichi206-sandbox/url-shortener/handler"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_Handler_LogLevel(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method     string
+		token      string
+		body       string
+		wantStatus int
+		want       string
+	}{
+		"success: get": {
+			method:     http.MethodGet,
+			token:      "Bearer secret",
+			wantStatus: http.StatusOK,
+			want:       `{"level":"INFO","overrides":{}}`,
+		},
+		"success: update": {
+			method:     http.MethodPut,
+			token:      "Bearer secret",
+			body:       `{"level":"warn","overrides":{"repository":"debug"}}`,
+			wantStatus: http.StatusOK,
+			want:       `{"level":"WARN","overrides":{"repository":"DEBUG"}}`,
+		},
+		"success: update only level": {
+			method:     http.MethodPut,
+			token:      "Bearer secret",
+			body:       `{"level":"error"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"level":"ERROR","overrides":{}}`,
+		},
+		"failure: invalid level": {
+			method:     http.MethodPut,
+			token:      "Bearer secret",
+			body:       `{"level":"warn","overrides":{"repository":"verbose"}}`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"error":{"code":"request_body_invalid","message":"request body is invalid","request_id":"test-request-id","details":[{"field":"overrides.repository","message":"unknown log level: verbose"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
+		},
+		"failure: no token": {
+			method:     http.MethodGet,
+			wantStatus: http.StatusUnauthorized,
+			want:       `{"error":{"code":"unauthorized","message":"unauthorized","request_id":"test-request-id","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#unauthorized"}}`,
+		},
+		"failure: wrong token": {
+			method:     http.MethodPut,
+			token:      "Bearer wrong",
+			body:       `{"level":"debug"}`,
+			wantStatus: http.StatusUnauthorized,
+			want:       `{"error":{"code":"unauthorized","message":"unauthorized","request_id":"test-request-id","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#unauthorized"}}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "admin")
+
+			h := handler.New(logger, nil, handler.WithAdminToken("secret"))
+			recorder := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(tc.method, "/admin/log-level", strings.NewReader(tc.body))
+			req.Header.Set("X-Request-ID", "test-request-id")
+			if tc.token != "" {
+				req.Header.Set("Authorization", tc.token)
+			}
+
+			// Act
+			h.Engine.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+			assert.Equal(t, tc.want, recorder.Body.String(), "response body should be equal")
+		})
+	}
+}
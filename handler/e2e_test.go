@@ -0,0 +1,490 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/handler"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/memory"
+	"github.com/kokoichi206-sandbox/url-shortener/usecase"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/pagemeta"
+)
+
+// Test_Handler_E2E runs the whole server on the in-memory repository.
+func Test_Handler_E2E(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	b := bytes.NewBuffer([]byte{})
+	logger := logger.NewBasicLogger(b, "test", "e2e")
+
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+	store.AddDomain("short.example.com")
+	u := usecase.New(
+		memory.New(store, logger), memory.NewTxManager(store), memory.NewURLRepo(memory.ExtractRWTx),
+		memory.NewTagRepo(memory.ExtractRWTx), memory.NewPageMetadataRepo(memory.ExtractRWTx), logger,
+	)
+	h := handler.New(logger, u)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/urls", strings.NewReader(body))
+		h.Engine.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	// Act
+	first := post(`{"original_url":"https://example.com"}`)
+	second := post(`{"original_url":"https://example.com"}`)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, first.Code, "status code should be equal")
+	assert.Equal(t, http.StatusOK, second.Code, "existing url should be returned with 200")
+
+	var got, gotSecond struct {
+		Slug    string `json:"slug"`
+		Created bool   `json:"created"`
+	}
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &got), "response should be json")
+	require.NoError(t, json.Unmarshal(second.Body.Bytes(), &gotSecond), "response should be json")
+	// 同じ URL に対しては同じ短縮 URL が返ること。
+	assert.Equal(t, got.Slug, gotSecond.Slug, "same url should be shortened to the same one")
+	assert.True(t, got.Created, "first url should be created")
+	assert.False(t, gotSecond.Created, "second url should be deduplicated")
+
+	// force_new の場合は、同じ URL でも新しい短縮 URL が作成されること。
+	forced := post(`{"original_url":"https://example.com","force_new":true}`)
+	require.Equal(t, http.StatusCreated, forced.Code, "status code should be equal")
+
+	var gotForced struct {
+		Slug string `json:"slug"`
+	}
+	require.NoError(t, json.Unmarshal(forced.Body.Bytes(), &gotForced), "response should be json")
+	assert.NotEqual(t, got.Slug, gotForced.Slug, "force_new should create a new short url")
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/"+got.Slug, nil)
+	h.Engine.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, recorder.Code, "status code should be equal")
+	assert.Equal(t, "https://example.com/", recorder.Header().Get("Location"), "location header should be equal")
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/NUL", nil)
+	h.Engine.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "status code should be equal")
+
+	// 別の domain で作成した短縮 URL は、その Host でのみ解決されること。
+	branded := post(`{"original_url":"https://example.com/branded","domain":"short.example.com"}`)
+	require.Equal(t, http.StatusCreated, branded.Code, "status code should be equal")
+
+	var gotBranded struct {
+		Slug     string `json:"slug"`
+		ShortURL string `json:"short_url"`
+	}
+	require.NoError(t, json.Unmarshal(branded.Body.Bytes(), &gotBranded), "response should be json")
+	assert.Equal(t, "https://short.example.com/"+gotBranded.Slug, gotBranded.ShortURL, "short url should be on the domain")
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/"+gotBranded.Slug, nil)
+	req.Host = "short.example.com"
+	h.Engine.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, recorder.Code, "status code should be equal")
+	assert.Equal(t, "https://example.com/branded", recorder.Header().Get("Location"), "location header should be equal")
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/"+gotBranded.Slug, nil)
+	h.Engine.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "short url should not be found on other domain")
+
+	unknown := post(`{"original_url":"https://example.com","domain":"wtf.example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, unknown.Code, "status code should be equal")
+}
+
+// Test_Handler_E2E_SeededURL shortens the url seeded by init.sql, which must be deduplicated to the seeded one.
+func Test_Handler_E2E_SeededURL(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "e2e")
+
+	initSQL, err := os.ReadFile("../init.sql")
+	require.NoError(t, err)
+
+	seed := regexp.MustCompile(
+		`INSERT INTO shorturl \(domain_id, url, short, dedup_key\) VALUES \(1, '([^']*)', '([^']*)', '([^']*)'\);`,
+	).FindStringSubmatch(string(initSQL))
+	require.Len(t, seed, 4, "init.sql should seed a short url")
+
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+	txManager, urlRepo := memory.NewTxManager(store), memory.NewURLRepo(memory.ExtractRWTx)
+	require.NoError(t, txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		_, err := urlRepo.InsertURL(ctx, tx, repository.URL{Domain: "localhost", URL: seed[1], Short: seed[2], DedupKey: seed[3]})
+
+		return err
+	}))
+
+	u := usecase.New(
+		memory.New(store, logger), txManager, urlRepo,
+		memory.NewTagRepo(memory.ExtractRWTx), memory.NewPageMetadataRepo(memory.ExtractRWTx), logger,
+	)
+	h := handler.New(logger, u)
+
+	for _, originalURL := range []string{"https://www.google.com", "HTTPS://WWW.Google.com:443/"} {
+		// Act
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/urls", strings.NewReader(`{"original_url":"`+originalURL+`"}`))
+		h.Engine.ServeHTTP(recorder, req)
+
+		// Assert
+		require.Equal(t, http.StatusOK, recorder.Code, "seeded url should be returned with 200")
+
+		var got struct {
+			Slug string `json:"slug"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got), "response should be json")
+		assert.Equal(t, "google", got.Slug, "seeded short url should be returned")
+	}
+}
+
+// Test_Handler_E2E_Metadata sets the title, the notes and the tags, and finds the urls by them.
+func Test_Handler_E2E_Metadata(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "e2e")
+
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+	u := usecase.New(
+		memory.New(store, logger), memory.NewTxManager(store), memory.NewURLRepo(memory.ExtractRWTx),
+		memory.NewTagRepo(memory.ExtractRWTx), memory.NewPageMetadataRepo(memory.ExtractRWTx), logger,
+	)
+	h := handler.New(logger, u)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		// 所有者のみが短縮 URL を更新できる。
+		req.Header.Set("X-Owner-ID", "team-a")
+		h.Engine.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	type urlJSON struct {
+		Slug  string   `json:"slug"`
+		Title string   `json:"title"`
+		Notes string   `json:"notes"`
+		Tags  []string `json:"tags"`
+	}
+
+	// Act
+	first := do(http.MethodPost, "/api/v1/urls", `{"original_url":"https://example.com","title":"Example","tags":["Go","news"]}`)
+	second := do(http.MethodPost, "/api/v1/urls", `{"original_url":"https://example.org","tags":["go"]}`)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, first.Code, "status code should be equal")
+	require.Equal(t, http.StatusCreated, second.Code, "status code should be equal")
+
+	var created urlJSON
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &created), "response should be json")
+	assert.Equal(t, urlJSON{Slug: created.Slug, Title: "Example", Tags: []string{"go", "news"}}, created)
+
+	list := func(query string) []urlJSON {
+		recorder := do(http.MethodGet, "/api/v1/urls"+query, "")
+		require.Equal(t, http.StatusOK, recorder.Code, "status code should be equal")
+
+		var res struct {
+			URLs []urlJSON `json:"urls"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res), "response should be json")
+
+		return res.URLs
+	}
+
+	assert.Len(t, list("?tag=go"), 2, "both urls have the tag")
+	assert.Equal(t, []urlJSON{created}, list("?tag=go&tag=NEWS"), "only the first url has both tags")
+
+	updated := do(http.MethodPatch, "/api/v1/urls/"+created.Slug, `{"notes":"memo","tags":[]}`)
+	require.Equal(t, http.StatusOK, updated.Code, "status code should be equal")
+
+	var gotUpdated urlJSON
+	require.NoError(t, json.Unmarshal(updated.Body.Bytes(), &gotUpdated), "response should be json")
+	assert.Equal(t, urlJSON{Slug: created.Slug, Title: "Example", Notes: "memo", Tags: []string{}}, gotUpdated)
+	assert.Empty(t, list("?tag=news"), "tags should be cleared")
+
+	notFound := do(http.MethodPatch, "/api/v1/urls/NUL", `{"notes":"memo"}`)
+	assert.Equal(t, http.StatusNotFound, notFound.Code, "status code should be equal")
+
+	export := do(http.MethodGet, "/api/v1/urls/export", "")
+	require.Equal(t, http.StatusOK, export.Code, "status code should be equal")
+	assert.Equal(t, 3, strings.Count(export.Body.String(), "\n"), "csv should have the header and 2 urls")
+	assert.Contains(t, export.Body.String(), ",Example,memo,,", "csv should have the metadata")
+}
+
+// Test_Handler_E2E_PageMetadata fetches the metadata of the created url in the background.
+func Test_Handler_E2E_PageMetadata(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><title>Example Domain</title><meta name="description" content="An example page."></head>`))
+	}))
+	defer site.Close()
+
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "e2e")
+
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+
+	txManager := memory.NewTxManager(store)
+	pageRepo := memory.NewPageMetadataRepo(memory.ExtractRWTx)
+	worker := usecase.NewMetadataWorker(
+		txManager, pageRepo, pagemeta.NewHTTPFetcher(pagemeta.WithAllowPrivateNetworks(true)), logger, 1, 10,
+	)
+	u := usecase.New(
+		memory.New(store, logger), txManager, memory.NewURLRepo(memory.ExtractRWTx),
+		memory.NewTagRepo(memory.ExtractRWTx), pageRepo, logger,
+		usecase.WithMetadataQueue(worker),
+	)
+	h := handler.New(logger, u)
+
+	// Act
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/urls", strings.NewReader(`{"original_url":"`+site.URL+`/page"}`))
+	h.Engine.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusCreated, recorder.Code, "status code should be equal")
+	assert.Contains(t, recorder.Body.String(), `"page":null`, "metadata should not be fetched on creation")
+
+	// キューに積まれた取得が終わるまで待つ。
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, worker.Close(ctx), "error of Close should be nil")
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/urls", nil)
+	h.Engine.ServeHTTP(recorder, req)
+
+	// Assert
+	var res struct {
+		URLs []struct {
+			Page struct {
+				Title       string `json:"title"`
+				Description string `json:"description"`
+			} `json:"page"`
+		} `json:"urls"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res), "response should be json")
+	require.Len(t, res.URLs, 1, "created url should be listed")
+	assert.Equal(t, "Example Domain", res.URLs[0].Page.Title, "title does not match")
+	assert.Equal(t, "An example page.", res.URLs[0].Page.Description, "description does not match")
+}
+
+// Test_Handler_E2E_Password opens the password protected url through the form and the cookie.
+func Test_Handler_E2E_Password(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "e2e")
+
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+	u := usecase.New(
+		memory.New(store, logger), memory.NewTxManager(store), memory.NewURLRepo(memory.ExtractRWTx),
+		memory.NewTagRepo(memory.ExtractRWTx), memory.NewPageMetadataRepo(memory.ExtractRWTx), logger,
+	)
+	h := handler.New(logger, u, handler.WithPublicBaseURL("http://localhost"))
+
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Host = "localhost"
+
+		recorder := httptest.NewRecorder()
+		h.Engine.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	created := do(httptest.NewRequest(
+		http.MethodPost, "/api/v1/urls", strings.NewReader(`{"original_url":"https://example.com/internal","password":"correct horse"}`),
+	))
+	require.Equal(t, http.StatusCreated, created.Code, "status code should be equal")
+
+	var res struct {
+		Slug      string `json:"slug"`
+		Protected bool   `json:"protected"`
+	}
+	require.NoError(t, json.Unmarshal(created.Body.Bytes(), &res), "response should be json")
+	assert.True(t, res.Protected, "url should be protected")
+
+	// Act
+	form := do(httptest.NewRequest(http.MethodGet, "/"+res.Slug, nil))
+	wrong := do(newPasswordRequest("/"+res.Slug, "wrong password"))
+	unlocked := do(newPasswordRequest("/"+res.Slug, "correct horse"))
+
+	again := httptest.NewRequest(http.MethodGet, "/"+res.Slug, nil)
+	for _, c := range unlocked.Result().Cookies() {
+		again.AddCookie(c)
+	}
+
+	revisited := do(again)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, form.Code, "password form should be served")
+	assert.NotContains(t, form.Body.String(), "example.com/internal", "destination should not be shown")
+	assert.Equal(t, http.StatusForbidden, wrong.Code, "status code should be equal")
+	assert.Equal(t, http.StatusSeeOther, unlocked.Code, "status code should be equal")
+	assert.Equal(t, "https://example.com/internal", unlocked.Header().Get("Location"), "location header should be equal")
+	assert.Equal(t, http.StatusFound, revisited.Code, "cookie should skip the password")
+	assert.Equal(t, "https://example.com/internal", revisited.Header().Get("Location"), "location header should be equal")
+}
+
+// Test_Handler_E2E_PasswordStopsDedup protects the shared url, which must not be returned to the other creators.
+func Test_Handler_E2E_PasswordStopsDedup(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "e2e")
+
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+	u := usecase.New(
+		memory.New(store, logger), memory.NewTxManager(store), memory.NewURLRepo(memory.ExtractRWTx),
+		memory.NewTagRepo(memory.ExtractRWTx), memory.NewPageMetadataRepo(memory.ExtractRWTx), logger,
+	)
+	h := handler.New(logger, u, handler.WithPublicBaseURL("http://localhost"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Host = "localhost"
+		req.Header.Set("X-Owner-ID", "team-a")
+
+		recorder := httptest.NewRecorder()
+		h.Engine.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	var first struct {
+		Slug string `json:"slug"`
+	}
+
+	created := do(http.MethodPost, "/api/v1/urls", `{"original_url":"https://example.com/a"}`)
+	require.Equal(t, http.StatusCreated, created.Code, "status code should be equal")
+	require.NoError(t, json.Unmarshal(created.Body.Bytes(), &first), "response should be json")
+
+	// Act
+	updated := do(http.MethodPatch, "/api/v1/urls/"+first.Slug, `{"password":"correct horse"}`)
+	again := do(http.MethodPost, "/api/v1/urls", `{"original_url":"https://example.com/a"}`)
+
+	// Assert
+	require.Equal(t, http.StatusOK, updated.Code, "status code should be equal")
+	assert.Equal(t, http.StatusCreated, again.Code, "new short url should be created")
+	assert.Contains(t, again.Body.String(), `"protected":false`, "new short url should not be protected")
+	assert.NotContains(t, again.Body.String(), first.Slug, "protected short url should not be returned")
+}
+
+// Test_Handler_E2E_ClickLimit opens the one-time url concurrently, which must redirect only once.
+func Test_Handler_E2E_ClickLimit(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "e2e")
+
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+	u := usecase.New(
+		memory.New(store, logger), memory.NewTxManager(store), memory.NewURLRepo(memory.ExtractRWTx),
+		memory.NewTagRepo(memory.ExtractRWTx), memory.NewPageMetadataRepo(memory.ExtractRWTx), logger,
+	)
+	h := handler.New(logger, u, handler.WithPublicBaseURL("http://localhost"))
+
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Host = "localhost"
+
+		recorder := httptest.NewRecorder()
+		h.Engine.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	created := do(httptest.NewRequest(
+		http.MethodPost, "/api/v1/urls", strings.NewReader(`{"original_url":"https://example.com/secret","max_clicks":1}`),
+	))
+	require.Equal(t, http.StatusCreated, created.Code, "status code should be equal")
+
+	var res struct {
+		Slug       string `json:"slug"`
+		MaxClicks  int    `json:"max_clicks"`
+		ClicksLeft int    `json:"clicks_left"`
+	}
+	require.NoError(t, json.Unmarshal(created.Body.Bytes(), &res), "response should be json")
+	assert.Equal(t, 1, res.ClicksLeft, "clicks left does not match")
+
+	// Act
+	// プレビューではクリック数を消費しないこと。
+	preview := do(httptest.NewRequest(http.MethodGet, "/"+res.Slug+"+", nil))
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			recorder := do(httptest.NewRequest(http.MethodGet, "/"+res.Slug, nil))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			codes[recorder.Code]++
+		}()
+	}
+
+	wg.Wait()
+
+	got := do(httptest.NewRequest(http.MethodGet, "/api/v1/urls/"+res.Slug, nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, preview.Code, "status code should be equal")
+	assert.Equal(t, map[int]int{http.StatusFound: 1, http.StatusGone: 9}, codes, "only one click should redirect")
+
+	require.Equal(t, http.StatusOK, got.Code, "status code should be equal")
+	require.NoError(t, json.Unmarshal(got.Body.Bytes(), &res), "response should be json")
+	assert.Equal(t, 1, res.MaxClicks, "max clicks does not match")
+	assert.Equal(t, 0, res.ClicksLeft, "clicks left does not match")
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+const problemJSON = "application/problem+json"
+
+type errorBody struct {
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	RequestID string          `json:"request_id,omitempty"`
+	Details   []apperr.Detail `json:"details,omitempty"`
+	DocsURL   string          `json:"docs_url"`
+}
+
+// problem is the error format defined in RFC 7807.
+type problem struct {
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Status    int             `json:"status"`
+	Instance  string          `json:"instance"`
+	Code      string          `json:"code"`
+	RequestID string          `json:"request_id,omitempty"`
+	Details   []apperr.Detail `json:"details,omitempty"`
+}
+
+// handleError is a helper function to handle error.
+// This function writes status code and error envelope to response body.
+func handleError(c *gin.Context, logger logger.Logger, err error) {
+	var e apperr.AppError
+	if ok := errors.As(err, &e); !ok {
+		e = apperr.ErrServerError
+		e.Log = err.Error()
+	}
+
+	if e.Log != "" {
+		logger.Error(context.WithoutCancel(c.Request.Context()), e.Log)
+	}
+
+	// 内部エラーは recoveryMW から error reporter に送られる。
+	if e.StatusCode >= http.StatusInternalServerError {
+		_ = c.Error(err)
+	}
+
+	reqID := util.GetRequestID(c.Request.Context())
+
+	// problem+json は Accept で明示された場合のみ返す。
+	if acceptsProblemJSON(c.GetHeader("Accept")) {
+		// gin は Content-Type が設定済みの場合は上書きしない。
+		c.Header("Content-Type", problemJSON)
+		c.JSON(e.StatusCode, problem{
+			Type:      e.DocsURL(),
+			Title:     e.Message,
+			Status:    e.StatusCode,
+			Instance:  c.Request.URL.Path,
+			Code:      e.Code,
+			RequestID: reqID,
+			Details:   e.Details,
+		})
+
+		return
+	}
+
+	c.JSON(e.StatusCode, gin.H{
+		"error": errorBody{
+			Code:      e.Code,
+			Message:   e.Message,
+			RequestID: reqID,
+			Details:   e.Details,
+			DocsURL:   e.DocsURL(),
+		},
+	})
+}
+
+func acceptsProblemJSON(accept string) bool {
+	for _, v := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(v, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), problemJSON) {
+			return true
+		}
+	}
+
+	return false
+}
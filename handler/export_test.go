@@ -1,3 +1,29 @@
 package handler
 
+import "github.com/gin-gonic/gin"
+
 var HandleWrapper = handlerWrapper
+
+func (h *handler) MetricsMW() gin.HandlerFunc {
+	return h.metricsMW()
+}
+
+func (h *handler) TracingMW() gin.HandlerFunc {
+	return h.tracingMW()
+}
+
+func (h *handler) SetRandom(random func() float64) {
+	h.random = random
+}
+
+func (h *handler) AccessLogMW() gin.HandlerFunc {
+	return h.accessLogMW()
+}
+
+func (h *handler) RequestIDMW() gin.HandlerFunc {
+	return h.requestIDMW()
+}
+
+func (h *handler) RecoveryMW() gin.HandlerFunc {
+	return h.recoveryMW()
+}
@@ -2,47 +2,128 @@ package handler
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
 	"github.com/kokoichi206-sandbox/url-shortener/usecase"
+	"github.com/kokoichi206-sandbox/url-shortener/util/health"
 	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/reporter"
+	"github.com/kokoichi206-sandbox/url-shortener/util/throttle"
+)
+
+const (
+	defaultRequestIDHeader = "X-Request-ID"
+	defaultCheckTimeout    = 2 * time.Second
+	defaultDomain          = "localhost"
+	defaultOwnerHeader     = "X-Owner-ID"
 )
 
 type handler struct {
 	logger  logger.Logger
 	usecase usecase.Usecase
 
+	// redirectLogSampling is the bits of float64, since it is changed on config reload.
+	redirectLogSampling atomic.Uint64
+	adminToken          string
+	requestIDHeader     string
+	reporter            reporter.Reporter
+	ready               func() bool
+	checks              *health.Registry
+	defaultDomain       string
+	// publicBaseURL is the url of the default domain, from which the short links are built.
+	publicBaseURL *url.URL
+	ownerHeader   string
+	// attempts throttles the password attempts per short url and client.
+	attempts *throttle.Limiter
+	// trustedProxies are the proxies whose X-Forwarded-For is trusted to get the client ip.
+	trustedProxies []string
+	// random returns a number in [0, 1) to sample the access logs.
+	random func() float64
+
 	Engine *gin.Engine
 }
 
 //nolint:revive
-func New(logger logger.Logger, usecase usecase.Usecase) *handler {
-	r := gin.Default()
+func New(logger logger.Logger, usecase usecase.Usecase, opts ...Option) *handler {
+	// gin.Default is not used, since the access logs are written by accessLogMW
+	// and the panics are recovered by recoveryMW.
+	r := gin.New()
 
 	h := &handler{
-		logger:  logger,
-		usecase: usecase,
-		Engine:  r,
+		logger:          logger,
+		usecase:         usecase,
+		requestIDHeader: defaultRequestIDHeader,
+		reporter:        reporter.Nop(),
+		ready:           func() bool { return true },
+		checks:          health.NewRegistry(defaultCheckTimeout),
+		defaultDomain:   defaultDomain,
+		publicBaseURL:   &url.URL{Scheme: "https", Host: defaultDomain},
+		ownerHeader:     defaultOwnerHeader,
+		attempts:        throttle.New(defaultPasswordMaxAttempts, defaultPasswordAttemptWindow),
+		//nolint:gosec
+		random: rand.Float64,
+		Engine: r,
+	}
+
+	h.SetRedirectLogSampling(1)
+
+	for _, opt := range opts {
+		opt(h)
 	}
+
+	// gin trusts every proxy by default, with which the clients can spoof their ip.
+	if err := r.SetTrustedProxies(h.trustedProxies); err != nil {
+		logger.Warnf(context.Background(), "failed to set trusted proxies, and no proxy is trusted: %v", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+
 	h.setupRoutes()
 
 	return h
 }
 
+// SetRedirectLogSampling changes the ratio of the access logs of the redirect route at runtime.
+func (h *handler) SetRedirectLogSampling(ratio float64) {
+	h.redirectLogSampling.Store(math.Float64bits(ratio))
+}
+
 func (h *handler) setupRoutes() {
+	// recoveryMW is the innermost, so that the outer ones record the panics as 500.
+	h.Engine.Use(h.metricsMW(), h.tracingMW(), h.requestIDMW(), h.accessLogMW(), h.recoveryMW())
+
 	base := h.Engine.Group("")
 	base.Handle(http.MethodGet, "/:shortURL", handlerWrapper(h.GetOriginalURL, h.logger))
+	base.Handle(http.MethodPost, "/:shortURL", handlerWrapper(h.UnlockURL, h.logger))
+	base.Handle(http.MethodGet, "/:shortURL/preview", handlerWrapper(h.PreviewURL, h.logger))
+	base.StaticFileFS("/assets/preview.css", "preview.css", http.FS(assets))
+	base.Handle(http.MethodGet, "/livez", handlerWrapper(h.Livez, h.logger))
+	base.Handle(http.MethodGet, "/readyz", handlerWrapper(h.Readyz, h.logger))
 
 	api := base.Group("/api/v1")
-	api.Use(h.requestIDMW())
 
 	api.Handle(http.MethodGet, "/health", handlerWrapper(h.Health, h.logger))
 	api.Handle(http.MethodPost, "/urls", handlerWrapper(h.GenerateURL, h.logger))
+	api.Handle(http.MethodGet, "/urls", handlerWrapper(h.ListURLs, h.logger))
+	api.Handle(http.MethodGet, "/urls/export", handlerWrapper(h.ExportURLs, h.logger))
+	api.Handle(http.MethodGet, "/urls/:shortURL", handlerWrapper(h.GetURL, h.logger))
+	api.Handle(http.MethodPatch, "/urls/:shortURL", handlerWrapper(h.UpdateURL, h.logger))
+
+	if h.adminToken != "" {
+		admin := base.Group("/admin")
+		admin.Use(h.adminAuthMW())
+
+		admin.Handle(http.MethodGet, "/log-level", handlerWrapper(h.GetLogLevel, h.logger))
+		admin.Handle(http.MethodPut, "/log-level", handlerWrapper(h.UpdateLogLevel, h.logger))
+	}
 }
 
 func handlerWrapper(fun func(c *gin.Context) error, logger logger.Logger) gin.HandlerFunc {
@@ -53,34 +134,27 @@ func handlerWrapper(fun func(c *gin.Context) error, logger logger.Logger) gin.Ha
 	}
 }
 
-// handleError is a helper function to handle error.
-// This function writes status code and error message to response body.
-func handleError(c *gin.Context, logger logger.Logger, err error) {
-	var e apperr.AppError
-	if ok := errors.As(err, &e); !ok {
-		e = apperr.AppError{
-			StatusCode: http.StatusInternalServerError,
-			Message:    "internal server error",
-			Log:        err.Error(),
-		}
-	}
-
-	if e.Log != "" {
-		logger.Error(context.WithoutCancel(c.Request.Context()), e.Log)
+func (h *handler) Health(c *gin.Context) error {
+	if !h.ready() {
+		return apperr.ErrServiceUnavailable
 	}
 
-	c.JSON(e.StatusCode, gin.H{
-		"error": e.Message,
-	})
-}
-
-func (h *handler) Health(c *gin.Context) error {
 	if err := h.usecase.Health(c.Request.Context()); err != nil {
 		return fmt.Errorf("failed to health check: %w", err)
 	}
 
+	stats := h.usecase.DBStats()
+
 	c.JSON(http.StatusOK, gin.H{
 		"health": "ok",
+		"db": gin.H{
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+		},
 	})
 
 	return nil
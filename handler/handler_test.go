@@ -7,9 +7,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	gomock "github.com/golang/mock/gomock"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/handler"
 	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
 	"github.com/stretchr/testify/assert"
@@ -19,6 +21,7 @@ func Test_Handler_Health(t *testing.T) {
 	t.Parallel()
 
 	testCases := map[string]struct {
+		notReady        bool
 		makeMockUsecase func(m *MockUsecase)
 		wantStatus      int
 		want            string
@@ -30,9 +33,20 @@ func Test_Handler_Health(t *testing.T) {
 					EXPECT().
 					Health(gomock.Any()).
 					Return(nil)
+				m.
+					EXPECT().
+					DBStats().
+					Return(repository.DBStats{
+						MaxOpenConnections: 20,
+						OpenConnections:    3,
+						InUse:              1,
+						Idle:               2,
+						WaitCount:          4,
+						WaitDuration:       1500 * time.Millisecond,
+					})
 			},
 			wantStatus: http.StatusOK,
-			want:       `{"health":"ok"}`,
+			want:       `{"db":{"idle":2,"in_use":1,"max_open_connections":20,"open_connections":3,"wait_count":4,"wait_duration_ms":1500},"health":"ok"}`,
 		},
 		"failure: ng": {
 			makeMockUsecase: func(m *MockUsecase) {
@@ -42,9 +56,15 @@ func Test_Handler_Health(t *testing.T) {
 					Return(errors.New("usecase error"))
 			},
 			wantStatus: http.StatusInternalServerError,
-			want:       `{"error":"internal server error"}`,
+			want:       `{"error":{"code":"internal_server_error","message":"internal server error","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#internal_server_error"}}`,
 			wantLog:    "failed to health check: usecase error",
 		},
+		"failure: shutting down": {
+			notReady:        true,
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusServiceUnavailable,
+			want:            `{"error":{"code":"service_unavailable","message":"service unavailable","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#service_unavailable"}}`,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -64,7 +84,7 @@ func Test_Handler_Health(t *testing.T) {
 			b := bytes.NewBuffer([]byte{})
 			logger := logger.NewBasicLogger(b, "test", "health")
 
-			h := handler.New(logger, u)
+			h := handler.New(logger, u, handler.WithReadiness(func() bool { return !tc.notReady }))
 			recorder := httptest.NewRecorder()
 			_, r := gin.CreateTestContext(recorder)
 
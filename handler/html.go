@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// htmlCSP only allows the stylesheet of this server and the forms posted to itself,
+// so the pages never load anything from the destinations.
+const htmlCSP = "default-src 'none'; style-src 'self'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'"
+
+//go:embed templates/*.html assets/*
+var embedded embed.FS
+
+var templates = template.Must(template.ParseFS(embedded, "templates/*.html"))
+
+// assets are served under /assets/.
+var assets = func() fs.FS {
+	sub, err := fs.Sub(embedded, "assets")
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}()
+
+// renderHTML writes the template, which is rendered before writing so that the error results in 500.
+func renderHTML(c *gin.Context, status int, name string, data any) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	c.Header("Content-Security-Policy", htmlCSP)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Referrer-Policy", "no-referrer")
+	// 短縮 URL は更新され得るため、キャッシュする場合も毎回検証させる。
+	c.Header("Cache-Control", "no-cache")
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+
+	return nil
+}
@@ -1,16 +1,182 @@
 package handler
 
 import (
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kokoichi206-sandbox/url-shortener/util"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
 )
 
+const maxRequestIDLength = 128
+
+// requestIDMW sets the request id to the context, the response header and the trace span.
+// The request id of the client is used if it is valid, so that the client can correlate.
 func (h *handler) requestIDMW() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx := util.WithRequestID(c.Request.Context())
+		ctx := c.Request.Context()
+
+		if reqID := c.GetHeader(h.requestIDHeader); isValidRequestID(reqID) {
+			ctx = util.ContextWithRequestID(ctx, reqID)
+		} else {
+			ctx = util.WithRequestID(ctx)
+		}
+
+		reqID := util.GetRequestID(ctx)
+
+		c.Header(h.requestIDHeader, reqID)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", reqID))
+
 		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
 	}
 }
+
+// isValidRequestID accepts the ids which consist of [a-zA-Z0-9._:-] (e.g. uuid),
+// not to write arbitrary strings to the logs and the response header.
+func isValidRequestID(reqID string) bool {
+	if reqID == "" || len(reqID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range reqID {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case r == '.', r == '_', r == ':', r == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// metricsMW records the count and the latency of requests per route and status.
+func (h *handler) metricsMW() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := routeOf(c)
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// tracingMW starts the server span of the request.
+// The trace is continued if the request has W3C traceparent header.
+func (h *handler) tracingMW() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := routeOf(c)
+
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Start(
+			ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+const redirectRoute = "/:shortURL"
+
+// isRedirect reports whether the request is the redirect, whose access logs are sampled.
+// The password attempts posted to the same route are always logged to find the brute-force attacks.
+func isRedirect(method, route string) bool {
+	return route == redirectRoute && (method == http.MethodGet || method == http.MethodHead)
+}
+
+// accessLogMW writes an access log for each request through the logger.
+func (h *handler) accessLogMW() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := routeOf(c)
+		status := c.Writer.Status()
+
+		if isRedirect(c.Request.Method, route) && status < http.StatusInternalServerError &&
+			h.random() >= math.Float64frombits(h.redirectLogSampling.Load()) {
+			return
+		}
+
+		fields := []logger.Field{
+			logger.String("method", c.Request.Method),
+			logger.String("route", route),
+			logger.String("path", redactQuery(c.Request.URL)),
+			logger.Int("status_code", status),
+			logger.Any("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			logger.Int("bytes", max(c.Writer.Size(), 0)),
+			logger.String("client_ip", c.ClientIP()),
+			logger.String("user_agent", c.Request.UserAgent()),
+		}
+
+		if shortURL := c.Param("shortURL"); shortURL != "" {
+			fields = append(fields, logger.String("short_url", shortURL))
+		}
+
+		// request id is added by the logger from the context.
+		h.logger.WithFields(fields...).Info(c.Request.Context(), "access")
+	}
+}
+
+// redactQuery returns the path with the query values replaced,
+// since they may contain secrets like tokens.
+func redactQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+
+	query := u.Query()
+	for key := range query {
+		query[key] = []string{"REDACTED"}
+	}
+
+	// url.Values.Encode escapes the values, so unescape for readability.
+	redacted, err := url.QueryUnescape(query.Encode())
+	if err != nil {
+		redacted = query.Encode()
+	}
+
+	return u.Path + "?" + redacted
+}
+
+// routeOf returns the route template (e.g. /:shortURL) of the request,
+// which is used instead of the path not to explode the cardinality.
+func routeOf(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+
+	return "unmatched"
+}
@@ -0,0 +1,335 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/kokoichi206-sandbox/url-shortener/handler"
+	"github.com/kokoichi206-sandbox/url-shortener/util"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+func Test_Handler_MetricsMW(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	b := bytes.NewBuffer([]byte{})
+	logger := logger.NewBasicLogger(b, "test", "metrics")
+
+	h := handler.New(logger, nil)
+	recorder := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(recorder)
+
+	// 他のテストと label が被らないように、テスト専用の route を使う。
+	r.Use(h.MetricsMW())
+	r.GET("/metrics-mw-test/:id", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	counter := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/metrics-mw-test/:id", "418")
+	before := testutil.ToFloat64(counter)
+
+	// Act
+	for _, path := range []string{"/metrics-mw-test/a", "/metrics-mw-test/b"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Assert
+	// path が違っても route template 単位で集計されること。
+	got := testutil.ToFloat64(counter) - before
+	assert.InDelta(t, 2, got, 0, "request count should be equal")
+}
+
+// global の tracer provider を差し替えるため、他のテストと並列に実行しない。
+//
+//nolint:paralleltest
+func Test_Handler_TracingMW(t *testing.T) {
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+
+	testCases := map[string]struct {
+		traceparent string
+		status      int
+		wantTraceID string
+		wantParent  string
+		wantError   bool
+	}{
+		"success: continue the incoming trace": {
+			traceparent: "00-" + traceID + "-" + parentID + "-01",
+			status:      http.StatusOK,
+			wantTraceID: traceID,
+			wantParent:  parentID,
+		},
+		"success: server error marks the span": {
+			traceparent: "00-" + traceID + "-" + parentID + "-01",
+			status:      http.StatusInternalServerError,
+			wantTraceID: traceID,
+			wantParent:  parentID,
+			wantError:   true,
+		},
+	}
+
+	tp, exporter := tracing.NewInMemoryTracerProvider()
+	tracing.SetGlobal(tp)
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		//nolint:paralleltest
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			exporter.Reset()
+
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "tracing")
+
+			h := handler.New(logger, nil)
+			_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+			r.Use(h.TracingMW())
+			r.GET("/tracing-mw-test/:id", func(c *gin.Context) {
+				c.Status(tc.status)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/tracing-mw-test/a", nil)
+			req.Header.Set("traceparent", tc.traceparent)
+
+			// Act
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Assert
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1, "server span should be exported")
+
+			s := spans[0]
+			assert.Equal(t, "GET /tracing-mw-test/:id", s.Name, "span name should be the route")
+			assert.Equal(t, tc.wantTraceID, s.SpanContext.TraceID().String(), "trace id should be propagated")
+			assert.Equal(t, tc.wantParent, s.Parent.SpanID().String(), "parent span id should be propagated")
+			assert.Equal(t, tc.wantError, s.Status.Code == codes.Error, "span status does not match")
+		})
+	}
+}
+
+func Test_Handler_AccessLogMW(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method   string
+		path     string
+		sampling float64
+		random   float64
+		status   int
+		want     map[string]interface{}
+	}{
+		"success": {
+			path:     "/api/v1/urls?token=secret&debug=1",
+			sampling: 1,
+			status:   http.StatusOK,
+			want: map[string]interface{}{
+				"message":     "access",
+				"method":      "GET",
+				"route":       "/api/v1/urls",
+				"path":        "/api/v1/urls?debug=REDACTED&token=REDACTED",
+				"status_code": float64(http.StatusOK),
+				"bytes":       float64(2),
+				"user_agent":  "test-agent",
+			},
+		},
+		"success: redirect route with short url": {
+			path:     "/R0D",
+			sampling: 0.5,
+			random:   0.4,
+			status:   http.StatusMovedPermanently,
+			want: map[string]interface{}{
+				"message":     "access",
+				"method":      "GET",
+				"route":       "/:shortURL",
+				"path":        "/R0D",
+				"status_code": float64(http.StatusMovedPermanently),
+				"bytes":       float64(2),
+				"user_agent":  "test-agent",
+				"short_url":   "R0D",
+			},
+		},
+		"success: redirect route is sampled out": {
+			path:     "/R0D",
+			sampling: 0.5,
+			random:   0.6,
+			status:   http.StatusMovedPermanently,
+		},
+		"success: password attempt is never sampled out": {
+			method:   http.MethodPost,
+			path:     "/R0D",
+			sampling: 0,
+			random:   0.6,
+			status:   http.StatusForbidden,
+			want: map[string]interface{}{
+				"message":     "access",
+				"method":      "POST",
+				"route":       "/:shortURL",
+				"path":        "/R0D",
+				"status_code": float64(http.StatusForbidden),
+				"bytes":       float64(2),
+				"user_agent":  "test-agent",
+				"short_url":   "R0D",
+			},
+		},
+		"success: server error is never sampled out": {
+			path:     "/R0D",
+			sampling: 0,
+			random:   0.6,
+			status:   http.StatusInternalServerError,
+			want: map[string]interface{}{
+				"message":     "access",
+				"method":      "GET",
+				"route":       "/:shortURL",
+				"path":        "/R0D",
+				"status_code": float64(http.StatusInternalServerError),
+				"bytes":       float64(2),
+				"user_agent":  "test-agent",
+				"short_url":   "R0D",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "access")
+
+			h := handler.New(logger, nil, handler.WithRedirectLogSampling(tc.sampling))
+			h.SetRandom(func() float64 { return tc.random })
+
+			_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+			r.Use(h.AccessLogMW())
+			handle := func(c *gin.Context) {
+				c.String(tc.status, "ok")
+			}
+			r.GET("/:shortURL", handle)
+			r.POST("/:shortURL", handle)
+			r.GET("/api/v1/urls", handle)
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req, _ := http.NewRequest(method, tc.path, nil)
+			req.Header.Set("User-Agent", "test-agent")
+
+			// Act
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Assert
+			if tc.want == nil {
+				assert.Empty(t, b.String(), "access log should be sampled out")
+
+				return
+			}
+
+			var got map[string]interface{}
+			require.NoError(t, json.Unmarshal(b.Bytes(), &got), "access log should be json")
+
+			for key, want := range tc.want {
+				assert.Equal(t, want, got[key], "%s does not match", key)
+			}
+
+			assert.Contains(t, got, "latency_ms", "latency should be logged")
+			assert.Contains(t, got, "client_ip", "client ip should be logged")
+		})
+	}
+}
+
+func Test_Handler_RequestIDMW(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		header    string
+		reqID     string
+		wantReqID string
+	}{
+		"success: use the given request id": {
+			header:    "X-Request-ID",
+			reqID:     "0f8fad5b-d9cb-469f-a165-70867728950e",
+			wantReqID: "0f8fad5b-d9cb-469f-a165-70867728950e",
+		},
+		"success: custom header": {
+			header:    "X-Correlation-ID",
+			reqID:     "abc.123:xyz_-",
+			wantReqID: "abc.123:xyz_-",
+		},
+		"success: generate when not given": {
+			header:    "X-Request-ID",
+			wantReqID: `^[0-9a-f-]{36}$`,
+		},
+		"success: generate when invalid charset": {
+			header:    "X-Request-ID",
+			reqID:     `"><script>`,
+			wantReqID: `^[0-9a-f-]{36}$`,
+		},
+		"success: generate when too long": {
+			header:    "X-Request-ID",
+			reqID:     strings.Repeat("a", 129),
+			wantReqID: `^[0-9a-f-]{36}$`,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "requestID")
+
+			h := handler.New(logger, nil, handler.WithRequestIDHeader(tc.header))
+			recorder := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(recorder)
+
+			var got string
+
+			r.Use(h.RequestIDMW())
+			r.GET("/request-id-mw-test", func(c *gin.Context) {
+				got = util.GetRequestID(c.Request.Context())
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/request-id-mw-test", nil)
+			if tc.reqID != "" {
+				req.Header.Set(tc.header, tc.reqID)
+			}
+
+			// Act
+			r.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Regexp(t, tc.wantReqID, got, "request id in context does not match")
+			assert.Equal(t, got, recorder.Header().Get(tc.header), "request id should be returned in the header")
+		})
+	}
+}
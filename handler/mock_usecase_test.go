@@ -9,6 +9,8 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	repository "github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	usecase "github.com/kokoichi206-sandbox/url-shortener/usecase"
 )
 
 // MockUsecase is a mock of Usecase interface.
@@ -34,19 +36,49 @@ func (m *MockUsecase) EXPECT() *MockUsecaseMockRecorder {
 	return m.recorder
 }
 
+// DBStats mocks base method.
+func (m *MockUsecase) DBStats() repository.DBStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DBStats")
+	ret0, _ := ret[0].(repository.DBStats)
+	return ret0
+}
+
+// DBStats indicates an expected call of DBStats.
+func (mr *MockUsecaseMockRecorder) DBStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DBStats", reflect.TypeOf((*MockUsecase)(nil).DBStats))
+}
+
 // GenerateURL mocks base method.
-func (m *MockUsecase) GenerateURL(ctx context.Context, originalURL string) (string, error) {
+func (m *MockUsecase) GenerateURL(ctx context.Context, params usecase.GenerateURLParams) (repository.URL, bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateURL", ctx, originalURL)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GenerateURL", ctx, params)
+	ret0, _ := ret[0].(repository.URL)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GenerateURL indicates an expected call of GenerateURL.
+func (mr *MockUsecaseMockRecorder) GenerateURL(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateURL", reflect.TypeOf((*MockUsecase)(nil).GenerateURL), ctx, params)
+}
+
+// GetURL mocks base method.
+func (m *MockUsecase) GetURL(ctx context.Context, domain, shortURL string) (repository.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURL", ctx, domain, shortURL)
+	ret0, _ := ret[0].(repository.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GenerateURL indicates an expected call of GenerateURL.
-func (mr *MockUsecaseMockRecorder) GenerateURL(ctx, originalURL interface{}) *gomock.Call {
+// GetURL indicates an expected call of GetURL.
+func (mr *MockUsecaseMockRecorder) GetURL(ctx, domain, shortURL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateURL", reflect.TypeOf((*MockUsecase)(nil).GenerateURL), ctx, originalURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockUsecase)(nil).GetURL), ctx, domain, shortURL)
 }
 
 // Health mocks base method.
@@ -63,17 +95,63 @@ func (mr *MockUsecaseMockRecorder) Health(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockUsecase)(nil).Health), ctx)
 }
 
+// ListURLs mocks base method.
+func (m *MockUsecase) ListURLs(ctx context.Context, filter repository.URLFilter) ([]repository.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", ctx, filter)
+	ret0, _ := ret[0].([]repository.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockUsecaseMockRecorder) ListURLs(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockUsecase)(nil).ListURLs), ctx, filter)
+}
+
 // SearchOriginalURL mocks base method.
-func (m *MockUsecase) SearchOriginalURL(ctx context.Context, shortURL string) (string, error) {
+func (m *MockUsecase) SearchOriginalURL(ctx context.Context, domain, shortURL, accessToken string) (repository.Destination, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SearchOriginalURL", ctx, shortURL)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "SearchOriginalURL", ctx, domain, shortURL, accessToken)
+	ret0, _ := ret[0].(repository.Destination)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SearchOriginalURL indicates an expected call of SearchOriginalURL.
-func (mr *MockUsecaseMockRecorder) SearchOriginalURL(ctx, shortURL interface{}) *gomock.Call {
+func (mr *MockUsecaseMockRecorder) SearchOriginalURL(ctx, domain, shortURL, accessToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchOriginalURL", reflect.TypeOf((*MockUsecase)(nil).SearchOriginalURL), ctx, domain, shortURL, accessToken)
+}
+
+// UnlockURL mocks base method.
+func (m *MockUsecase) UnlockURL(ctx context.Context, domain, shortURL, password string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockURL", ctx, domain, shortURL, password)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UnlockURL indicates an expected call of UnlockURL.
+func (mr *MockUsecaseMockRecorder) UnlockURL(ctx, domain, shortURL, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockURL", reflect.TypeOf((*MockUsecase)(nil).UnlockURL), ctx, domain, shortURL, password)
+}
+
+// UpdateURL mocks base method.
+func (m *MockUsecase) UpdateURL(ctx context.Context, params usecase.UpdateURLParams) (repository.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateURL", ctx, params)
+	ret0, _ := ret[0].(repository.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateURL indicates an expected call of UpdateURL.
+func (mr *MockUsecaseMockRecorder) UpdateURL(ctx, params interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchOriginalURL", reflect.TypeOf((*MockUsecase)(nil).SearchOriginalURL), ctx, shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockUsecase)(nil).UpdateURL), ctx, params)
 }
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/health"
+	"github.com/kokoichi206-sandbox/url-shortener/util/reporter"
+	"github.com/kokoichi206-sandbox/url-shortener/util/throttle"
+)
+
+// Option configures the handler.
+type Option func(h *handler)
+
+// WithRedirectLogSampling sets the ratio (from 0 to 1) of the access logs of
+// the redirects (GET and HEAD) to be written, since the route receives most of the traffic.
+// Server errors and the password attempts are always written.
+func WithRedirectLogSampling(ratio float64) Option {
+	return func(h *handler) {
+		h.SetRedirectLogSampling(ratio)
+	}
+}
+
+// WithAdminToken enables the admin endpoints, which require the token as a bearer token.
+// The admin endpoints are disabled if the token is empty.
+func WithAdminToken(token string) Option {
+	return func(h *handler) {
+		h.adminToken = token
+	}
+}
+
+// WithRequestIDHeader sets the header name of the request id (default: X-Request-ID).
+func WithRequestIDHeader(name string) Option {
+	return func(h *handler) {
+		if name != "" {
+			h.requestIDHeader = name
+		}
+	}
+}
+
+// WithErrorReporter sets the reporter to which the panics and the internal errors are forwarded.
+func WithErrorReporter(r reporter.Reporter) Option {
+	return func(h *handler) {
+		if r != nil {
+			h.reporter = r
+		}
+	}
+}
+
+// WithReadiness sets the function which reports whether the server can receive the traffic.
+// The health check fails if it returns false (e.g. during shutdown).
+func WithReadiness(ready func() bool) Option {
+	return func(h *handler) {
+		if ready != nil {
+			h.ready = ready
+		}
+	}
+}
+
+// WithHealthChecks sets the checks of the readiness probe (/readyz).
+func WithHealthChecks(checks *health.Registry) Option {
+	return func(h *handler) {
+		if checks != nil {
+			h.checks = checks
+		}
+	}
+}
+
+// WithDefaultDomain sets the domain of the short urls created without the domain (default: localhost).
+func WithDefaultDomain(domain string) Option {
+	return func(h *handler) {
+		if domain != "" {
+			h.defaultDomain = strings.ToLower(domain)
+		}
+	}
+}
+
+// WithPublicBaseURL sets the url of the default domain seen from the clients (default: https://localhost),
+// which may have the port and the path prefix. The links on the other domains use its scheme.
+// The invalid url is ignored.
+func WithPublicBaseURL(rawURL string) Option {
+	return func(h *handler) {
+		if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && u.Host != "" {
+			h.publicBaseURL = u
+		}
+	}
+}
+
+// WithOwnerHeader sets the header name of the owner of the created short url (default: X-Owner-ID),
+// which is used by the dedup policy "per-owner".
+func WithOwnerHeader(name string) Option {
+	return func(h *handler) {
+		if name != "" {
+			h.ownerHeader = name
+		}
+	}
+}
+
+// WithPasswordThrottle blocks the client for the window after max password attempts of a short url
+// without the correct one (default: 5 in 15 minutes). The throttling is disabled if max is 0.
+func WithPasswordThrottle(max int, window time.Duration) Option {
+	return func(h *handler) {
+		h.attempts = throttle.New(max, window)
+	}
+}
+
+// WithTrustedProxies sets the ips or the CIDRs of the proxies, whose X-Forwarded-For is used as the client ip
+// (e.g. for the password throttling). No proxy is trusted by default.
+func WithTrustedProxies(proxies []string) Option {
+	return func(h *handler) {
+		h.trustedProxies = proxies
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+// accessCookie holds the access token of the password protected short url,
+// whose path is the short url so that each short url has its own.
+const accessCookie = "link_access"
+
+// Defaults of the throttling of the password attempts per short url and client.
+const (
+	defaultPasswordMaxAttempts   = 5
+	defaultPasswordAttemptWindow = 15 * time.Minute
+)
+
+type passwordPage struct {
+	ShortURL string
+	Error    string
+}
+
+// UnlockURL checks the password posted from the form, and redirects to the original url if correct.
+func (h *handler) UnlockURL(c *gin.Context) error {
+	ctx := c.Request.Context()
+
+	ctx, span := tracing.Start(ctx, "h.UnlockURL")
+	defer span.End()
+
+	domain, shortURL := h.domainOf(c.Request), c.Param("shortURL")
+	// 同じクライアントからの試行のみを制限し、他の訪問者は締め出さない。
+	// 並行した試行も数えるよう、パスワードの確認前に数え、正しければ戻す。
+	key := domain + "/" + shortURL + " " + c.ClientIP()
+
+	if ok, retryAfter := h.attempts.Allow(key); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+		return h.renderPassword(c, domain, shortURL, apperr.ErrTooManyAttempts, "Too many attempts. Try again later.")
+	}
+
+	originalURL, token, err := h.usecase.UnlockURL(ctx, domain, shortURL, c.PostForm("password"))
+	if err != nil {
+		if errors.Is(err, apperr.ErrPasswordIncorrect) {
+			return h.renderPassword(c, domain, shortURL, apperr.ErrPasswordIncorrect, "The password is incorrect.")
+		}
+
+		return fmt.Errorf("failed to exec usecase.UnlockURL: %w", err)
+	}
+
+	h.attempts.Reset(key)
+
+	if token != "" {
+		h.setAccessCookie(c, domain, shortURL, token)
+	}
+
+	// POST の後は GET で遷移させる。
+	c.Redirect(http.StatusSeeOther, originalURL)
+
+	return nil
+}
+
+// renderPassword serves the password form with the status of the error.
+func (h *handler) renderPassword(c *gin.Context, domain, shortURL string, e apperr.AppError, message string) error {
+	return renderHTML(c, e.StatusCode, "password.html", passwordPage{
+		ShortURL: h.linkOf(domain, shortURL),
+		Error:    message,
+	})
+}
+
+// setAccessCookie sets the token as the session cookie, which is expired by the token itself.
+func (h *handler) setAccessCookie(c *gin.Context, domain, shortURL, token string) {
+	path := "/" + shortURL
+	if u, err := url.Parse(h.linkOf(domain, shortURL)); err == nil {
+		// 公開 URL がパスの接頭辞を持つ場合も、ブラウザから見たパスに限定する。
+		path = u.EscapedPath()
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     accessCookie,
+		Value:    token,
+		Path:     path,
+		Secure:   h.publicBaseURL.Scheme == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
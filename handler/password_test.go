@@ -0,0 +1,231 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kokoichi206-sandbox/url-shortener/handler"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_Handler_UnlockURL(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		path            string
+		opts            []handler.Option
+		makeMockUsecase func(m *MockUsecase)
+		wantStatus      int
+		wantLocation    string
+		wantCookie      string
+		wantBody        string
+	}{
+		"success": {
+			path: "/R0D",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UnlockURL(gomock.Any(), "localhost", "R0D", "correct horse").
+					Return("https://example.com/internal", "token", nil)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "https://example.com/internal",
+			wantCookie:   "link_access=token; Path=/R0D; HttpOnly; Secure; SameSite=Lax",
+		},
+		"success: path prefix of public base url": {
+			path: "/R0D",
+			opts: []handler.Option{handler.WithPublicBaseURL("http://localhost:8080/s")},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UnlockURL(gomock.Any(), "localhost", "R0D", "correct horse").
+					Return("https://example.com/internal", "token", nil)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "https://example.com/internal",
+			// ブラウザから見たパスに限定され、http では Secure が付かないこと。
+			wantCookie: "link_access=token; Path=/s/R0D; HttpOnly; SameSite=Lax",
+		},
+		"success: not protected": {
+			path: "/R0D",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UnlockURL(gomock.Any(), "localhost", "R0D", "correct horse").
+					Return("https://example.com", "", nil)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "https://example.com",
+		},
+		"failure: incorrect password": {
+			path: "/R0D",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UnlockURL(gomock.Any(), "localhost", "R0D", "correct horse").
+					Return("", "", apperr.ErrPasswordIncorrect)
+			},
+			wantStatus: http.StatusForbidden,
+			wantBody:   `<p class="error" role="alert">The password is incorrect.</p>`,
+		},
+		"failure: not found": {
+			path: "/RXX",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UnlockURL(gomock.Any(), "localhost", "RXX", "correct horse").
+					Return("", "", apperr.ErrShortURLNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody:   `"code":"short_url_not_found"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			u := NewMockUsecase(ctrl)
+			tc.makeMockUsecase(u)
+
+			logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "unlockURL")
+
+			h := handler.New(logger, u, tc.opts...)
+			recorder := httptest.NewRecorder()
+
+			req := newPasswordRequest(tc.path, "correct horse")
+
+			// Act
+			h.Engine.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"), "location header should be equal")
+			assert.Equal(t, tc.wantCookie, recorder.Header().Get("Set-Cookie"), "cookie does not match")
+			assert.Contains(t, recorder.Body.String(), tc.wantBody, "body should contain expected string")
+		})
+	}
+}
+
+func Test_Handler_UnlockURL_Throttle(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := NewMockUsecase(ctrl)
+	u.
+		EXPECT().
+		UnlockURL(gomock.Any(), "localhost", "R0D", "wrong").
+		// 制限に達した後は、パスワードを検証しないこと。
+		Times(3).
+		Return("", "", apperr.ErrPasswordIncorrect)
+
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "unlockURL")
+	h := handler.New(logger, u, handler.WithPasswordThrottle(2, time.Minute))
+
+	post := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+
+		req := newPasswordRequest("/R0D", "wrong")
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+
+		h.Engine.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	// Act
+	first := post("192.0.2.1:1234", "")
+	second := post("192.0.2.1:1234", "")
+	// 信頼しないプロキシの X-Forwarded-For では制限を回避できないこと。
+	blocked := post("192.0.2.1:1234", "198.51.100.1")
+	other := post("192.0.2.2:1234", "")
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, first.Code, "status code should be equal")
+	assert.Equal(t, http.StatusForbidden, second.Code, "status code should be equal")
+	assert.Equal(t, http.StatusTooManyRequests, blocked.Code, "status code should be equal")
+	assert.Equal(t, "60", blocked.Header().Get("Retry-After"), "retry after does not match")
+	assert.Contains(t, blocked.Body.String(), "Too many attempts.", "body should contain expected string")
+	assert.Equal(t, http.StatusForbidden, other.Code, "other clients should not be blocked")
+}
+
+func Test_Handler_UnlockURL_ThrottleConcurrent(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := NewMockUsecase(ctrl)
+	u.
+		EXPECT().
+		UnlockURL(gomock.Any(), "localhost", "R0D", "wrong").
+		// 検証中の試行も数えられ、制限を超えて検証されないこと。
+		Times(2).
+		DoAndReturn(func(context.Context, string, string, string) (string, string, error) {
+			time.Sleep(50 * time.Millisecond)
+
+			return "", "", apperr.ErrPasswordIncorrect
+		})
+
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "unlockURL")
+	h := handler.New(logger, u, handler.WithPasswordThrottle(2, time.Minute))
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+
+	// Act
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			recorder := httptest.NewRecorder()
+			h.Engine.ServeHTTP(recorder, newPasswordRequest("/R0D", "wrong"))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			codes[recorder.Code]++
+		}()
+	}
+
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, map[int]int{http.StatusForbidden: 2, http.StatusTooManyRequests: 8}, codes, "status codes do not match")
+}
+
+func newPasswordRequest(path, password string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(url.Values{"password": {password}}.Encode()))
+	req.Host = "localhost"
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
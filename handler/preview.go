@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+// previewSuffix is appended to the short url to show the preview instead of redirecting (e.g. /mRJ+).
+const previewSuffix = "+"
+
+type previewPage struct {
+	// AssetsPath is the relative path to /assets/, which works behind the proxy adding a path prefix.
+	AssetsPath  string
+	ShortURL    string
+	OriginalURL string
+	Host        string
+	Title       string
+	Description string
+	CreatedAt   string
+	// Protected hides the destination, which is shown only to the visitors knowing the password.
+	Protected bool
+	// Limited hides the destination as well, not to let the visitors open it without counting the click.
+	Limited    bool
+	ClicksLeft int
+}
+
+// PreviewURL renders the page of the destination with the continue link, instead of redirecting.
+func (h *handler) PreviewURL(c *gin.Context) error {
+	// /:shortURL/preview の場合、assets は 1 つ上の階層になる。
+	return h.preview(c, c.Param("shortURL"), "../assets/")
+}
+
+func (h *handler) preview(c *gin.Context, shortURL, assetsPath string) error {
+	ctx := c.Request.Context()
+
+	ctx, span := tracing.Start(ctx, "h.PreviewURL")
+	defer span.End()
+
+	record, err := h.usecase.GetURL(ctx, h.domainOf(c.Request), shortURL)
+	if err != nil {
+		return fmt.Errorf("failed to exec usecase.GetURL: %w", err)
+	}
+
+	if record.MaxClicks > 0 && record.ClicksLeft() == 0 {
+		return apperr.ErrShortURLExhausted
+	}
+
+	return renderHTML(c, http.StatusOK, "preview.html", h.previewPageOf(record, assetsPath))
+}
+
+func (h *handler) previewPageOf(record repository.URL, assetsPath string) previewPage {
+	page := previewPage{
+		AssetsPath: assetsPath,
+		ShortURL:   h.linkOf(record.Domain, record.Short),
+		CreatedAt:  record.CreatedAt.UTC().Format("2006-01-02"),
+	}
+
+	page.Protected = record.PasswordHash != ""
+	if record.MaxClicks > 0 {
+		page.Limited, page.ClicksLeft = true, record.ClicksLeft()
+	}
+
+	if page.Protected || page.Limited {
+		return page
+	}
+
+	page.OriginalURL = record.URL
+	page.Title = record.Title
+
+	if u, err := url.Parse(record.URL); err == nil {
+		page.Host = u.Hostname()
+	}
+
+	// The title given by the owner is preferred to the one of the page.
+	if record.Page != nil {
+		if page.Title == "" {
+			page.Title = record.Page.Title
+		}
+
+		page.Description = record.Page.Description
+	}
+
+	return page
+}
+
+// isPreview reports whether the short url asks for the preview, and returns the short url without the suffix.
+func isPreview(shortURL string) (string, bool) {
+	if s, ok := strings.CutSuffix(shortURL, previewSuffix); ok && s != "" {
+		return s, true
+	}
+
+	return shortURL, false
+}
@@ -0,0 +1,238 @@
+package handler_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/handler"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_Handler_PreviewURL(t *testing.T) {
+	t.Parallel()
+
+	record := repository.URL{
+		ID: 1, Domain: "localhost", Short: "R0D", URL: "https://example.com/path?q=1",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	testCases := map[string]struct {
+		path            string
+		makeMockUsecase func(m *MockUsecase)
+		wantStatus      int
+		wantContains    []string
+		wantNotContains []string
+		wantLog         string
+	}{
+		"success: plus suffix": {
+			path: "/R0D+",
+			makeMockUsecase: func(m *MockUsecase) {
+				r := record
+				r.Title = "Owner title"
+				r.Page = &repository.PageMetadata{Title: "Page title", Description: "About the page."}
+
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(r, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`<h1 class="host">example.com</h1>`,
+				// タイトルは作成者の指定を優先すること。
+				`<p class="title">Owner title</p>`,
+				`<p class="description">About the page.</p>`,
+				`<code class="url">https://example.com/path?q=1</code>`,
+				`<time datetime="2024-01-02">2024-01-02</time>`,
+				`href="https://example.com/path?q=1"`,
+				`href="assets/preview.css"`,
+				"https://localhost/R0D leads to",
+			},
+			wantNotContains: []string{"Page title"},
+		},
+		"success: preview path": {
+			path: "/R0D/preview",
+			makeMockUsecase: func(m *MockUsecase) {
+				r := record
+				r.Page = &repository.PageMetadata{Title: "Page title"}
+
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(r, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`<p class="title">Page title</p>`,
+				`href="../assets/preview.css"`,
+			},
+			wantNotContains: []string{`class="description"`},
+		},
+		"success: escaped title": {
+			path: "/R0D+",
+			makeMockUsecase: func(m *MockUsecase) {
+				r := record
+				r.Title = `<script>alert(1)</script>`
+
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(r, nil)
+			},
+			wantStatus:      http.StatusOK,
+			wantContains:    []string{"&lt;script&gt;alert(1)&lt;/script&gt;"},
+			wantNotContains: []string{"<script>"},
+		},
+		"success: protected": {
+			path: "/R0D+",
+			makeMockUsecase: func(m *MockUsecase) {
+				r := record
+				r.Title = "Internal"
+				r.PasswordHash = "hash"
+
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(r, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`<h1 class="host">Protected link</h1>`,
+				`href="https://localhost/R0D"`,
+			},
+			// パスワードを知らない訪問者には行き先を見せないこと。
+			wantNotContains: []string{"example.com", "Internal"},
+		},
+		"success: click-limited": {
+			path: "/R0D+",
+			makeMockUsecase: func(m *MockUsecase) {
+				r := record
+				r.MaxClicks, r.Clicks = 3, 1
+
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(r, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`<h1 class="host">Limited link</h1>`,
+				"<dd>2</dd>",
+				`href="https://localhost/R0D"`,
+			},
+			// 行き先へ直接遷移されると、クリック数が数えられない。
+			wantNotContains: []string{"example.com"},
+		},
+		"failure: exhausted": {
+			path: "/R0D+",
+			makeMockUsecase: func(m *MockUsecase) {
+				r := record
+				r.MaxClicks, r.Clicks = 1, 1
+
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(r, nil)
+			},
+			wantStatus:      http.StatusGone,
+			wantContains:    []string{`"code":"short_url_exhausted"`},
+			wantNotContains: []string{"example.com"},
+		},
+		"failure: not found": {
+			path: "/RXX+",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "RXX").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+			},
+			wantStatus:   http.StatusNotFound,
+			wantContains: []string{`"code":"short_url_not_found"`},
+		},
+		"failure: server error": {
+			path: "/RXX/preview",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "RXX").
+					Return(repository.URL{}, errors.New("usecase error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantLog:    "failed to exec usecase.GetURL: usecase error",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			u := NewMockUsecase(ctrl)
+			tc.makeMockUsecase(u)
+
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "previewURL")
+
+			h := handler.New(logger, u)
+			recorder := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+
+			// Act
+			h.Engine.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+			assert.Empty(t, recorder.Header().Get("Location"), "preview should not redirect")
+			for _, want := range tc.wantContains {
+				assert.Contains(t, recorder.Body.String(), want, "body should contain expected string")
+			}
+			for _, notWant := range tc.wantNotContains {
+				assert.NotContains(t, recorder.Body.String(), notWant, "body should not contain the string")
+			}
+			if tc.wantStatus == http.StatusOK {
+				assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"), "content type does not match")
+				assert.Contains(t, recorder.Header().Get("Content-Security-Policy"), "default-src 'none'", "csp does not match")
+			}
+			assert.True(t, strings.Contains(b.String(), tc.wantLog), "log should contain expected string")
+		})
+	}
+}
+
+func Test_Handler_PreviewAssets(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "previewAssets")
+	h := handler.New(logger, NewMockUsecase(ctrl))
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/assets/preview.css", nil)
+
+	// Act
+	h.Engine.ServeHTTP(recorder, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, recorder.Code, "status code should be equal")
+	assert.Equal(t, "text/css; charset=utf-8", recorder.Header().Get("Content-Type"), "content type does not match")
+	assert.Contains(t, recorder.Body.String(), ".button", "body should be the stylesheet")
+}
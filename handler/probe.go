@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/health"
+)
+
+// Livez reports that the process is up, without checking the dependencies,
+// so that the process is not restarted because of the failure of the dependencies.
+func (h *handler) Livez(c *gin.Context) error {
+	c.JSON(http.StatusOK, gin.H{
+		"status": health.StatusOK,
+	})
+
+	return nil
+}
+
+// Readyz reports whether the server can receive the traffic with the result of each check.
+func (h *handler) Readyz(c *gin.Context) error {
+	report := h.checks.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+
+	return nil
+}
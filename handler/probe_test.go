@@ -0,0 +1,107 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/handler"
+	"github.com/kokoichi206-sandbox/url-shortener/util/health"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_Handler_Probes(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		path       string
+		checks     map[string]health.CheckFunc
+		wantStatus int
+		want       health.Report
+	}{
+		"success: livez ignores checks": {
+			path: "/livez",
+			checks: map[string]health.CheckFunc{
+				"database": func(ctx context.Context) error { return errors.New("connection refused") },
+			},
+			wantStatus: http.StatusOK,
+			want:       health.Report{Status: health.StatusOK},
+		},
+		"success: readyz": {
+			path: "/readyz",
+			checks: map[string]health.CheckFunc{
+				"database": func(ctx context.Context) error { return nil },
+			},
+			wantStatus: http.StatusOK,
+			want: health.Report{
+				Status: health.StatusOK,
+				Checks: map[string]health.Result{
+					"database": {Status: health.StatusOK},
+				},
+			},
+		},
+		"failure: readyz": {
+			path: "/readyz",
+			checks: map[string]health.CheckFunc{
+				"database":   func(ctx context.Context) error { return nil },
+				"migrations": func(ctx context.Context) error { return errors.New("schema version is 0, expected 1") },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			want: health.Report{
+				Status: health.StatusFail,
+				Checks: map[string]health.Result{
+					"database":   {Status: health.StatusOK},
+					"migrations": {Status: health.StatusFail, Error: "schema version is 0, expected 1"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "probes")
+
+			checks := health.NewRegistry(time.Second)
+			for name, fn := range tc.checks {
+				checks.Register(name, fn)
+			}
+
+			h := handler.New(logger, nil, handler.WithHealthChecks(checks))
+			recorder := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+
+			// Act
+			h.Engine.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+
+			var got health.Report
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got), "body should be json")
+
+			// latency は実行ごとに変わるため比較しない。
+			for name, res := range got.Checks {
+				res.LatencyMs = 0
+				got.Checks[name] = res
+			}
+
+			assert.Equal(t, tc.want, got, "response body should be equal")
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/reporter"
+)
+
+// recoveryMW recovers from the panics of the handlers and returns the standard error response.
+// The panics and the internal errors are forwarded to the error reporter.
+func (h *handler) recoveryMW() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			// http.ErrAbortHandler is used to abort the response intentionally.
+			//nolint:errorlint,goerr113
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			h.handlePanic(c, &reporter.PanicError{Value: v, Stack: debug.Stack()})
+		}()
+
+		c.Next()
+
+		for _, e := range c.Errors {
+			h.reporter.Report(c.Request.Context(), e.Err)
+		}
+	}
+}
+
+func (h *handler) handlePanic(c *gin.Context, err *reporter.PanicError) {
+	ctx := c.Request.Context()
+
+	// recover 中に呼ばれるため、stack には panic した箇所が含まれる。
+	h.logger.WithFields(logger.Err(err)).Critical(ctx, "recovered from panic")
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	h.reporter.Report(ctx, err)
+
+	// response が書き込み済みの場合は、status code を変更できない。
+	if c.Writer.Written() {
+		c.Abort()
+
+		return
+	}
+
+	// panic は上で記録済みのため、handleError ではログを書かない。
+	e := apperr.ErrServerError
+	e.Log = ""
+
+	handleError(c, h.logger, e)
+	c.Abort()
+}
@@ -0,0 +1,139 @@
+package handler_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/handler"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/reporter"
+)
+
+func Test_Handler_RecoveryMW(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		fun          func(c *gin.Context) error
+		wantStatus   int
+		want         string
+		wantLog      string
+		wantReported []string
+	}{
+		"success: no error": {
+			fun: func(c *gin.Context) error {
+				c.Status(http.StatusNoContent)
+
+				return nil
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		"success: client error is not reported": {
+			fun: func(c *gin.Context) error {
+				return apperr.ErrShortURLNotFound
+			},
+			wantStatus: http.StatusNotFound,
+			want:       `{"error":{"code":"short_url_not_found","message":"short url not found","request_id":"test-request-id","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#short_url_not_found"}}`,
+		},
+		"failure: internal error": {
+			fun: func(c *gin.Context) error {
+				return errors.New("db error")
+			},
+			wantStatus:   http.StatusInternalServerError,
+			want:         `{"error":{"code":"internal_server_error","message":"internal server error","request_id":"test-request-id","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#internal_server_error"}}`,
+			wantLog:      "db error",
+			wantReported: []string{"db error"},
+		},
+		"failure: panic": {
+			fun: func(c *gin.Context) error {
+				var m map[string]int
+				m["panic"] = 1
+
+				return nil
+			},
+			wantStatus:   http.StatusInternalServerError,
+			want:         `{"error":{"code":"internal_server_error","message":"internal server error","request_id":"test-request-id","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#internal_server_error"}}`,
+			wantLog:      "recovered from panic",
+			wantReported: []string{"panic: assignment to entry in nil map"},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "recovery")
+			r := reporter.NewMemory()
+
+			h := handler.New(logger, nil, handler.WithErrorReporter(r))
+			recorder := httptest.NewRecorder()
+
+			h.Engine.GET("/recovery-mw-test", handler.HandleWrapper(tc.fun, logger))
+
+			req, _ := http.NewRequest(http.MethodGet, "/recovery-mw-test", nil)
+			req.Header.Set("X-Request-ID", "test-request-id")
+
+			// Act
+			h.Engine.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+			assert.Equal(t, tc.want, recorder.Body.String(), "response body should be equal")
+			assert.True(t, strings.Contains(b.String(), tc.wantLog), "log should contain expected string")
+
+			errs := r.Errors()
+			require.Len(t, errs, len(tc.wantReported), "reported errors should be equal")
+
+			for i, want := range tc.wantReported {
+				assert.Equal(t, want, errs[i].Error(), "reported error should be equal")
+			}
+		})
+	}
+}
+
+func Test_Handler_RecoveryMW_PanicLog(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	b := bytes.NewBuffer([]byte{})
+	logger := logger.NewBasicLogger(b, "test", "recovery")
+	r := reporter.NewMemory()
+
+	h := handler.New(logger, nil, handler.WithErrorReporter(r))
+
+	h.Engine.GET("/recovery-mw-test", func(c *gin.Context) {
+		panic("unexpected")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/recovery-mw-test", nil)
+	req.Header.Set("X-Request-ID", "test-request-id")
+
+	// Act
+	h.Engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	// panic した箇所の stack と request id がログに含まれること。
+	assert.Contains(t, b.String(), `"request_id":"test-request-id"`, "log should contain request id")
+	assert.Contains(t, b.String(), "Test_Handler_RecoveryMW_PanicLog", "log should contain the stack of the panic")
+
+	errs := r.Errors()
+	require.Len(t, errs, 1, "panic should be reported")
+
+	var panicErr *reporter.PanicError
+	require.ErrorAs(t, errs[0], &panicErr, "reported error should be PanicError")
+	assert.Equal(t, "unexpected", panicErr.Value, "panic value should be equal")
+	assert.NotEmpty(t, panicErr.Stack, "stack should be recorded")
+}
@@ -1,31 +1,59 @@
 package handler
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	tracer "github.com/opentracing/opentracing-go"
 
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
 	"github.com/kokoichi206-sandbox/url-shortener/model/request"
+	"github.com/kokoichi206-sandbox/url-shortener/usecase"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
 )
 
 func (h *handler) GetOriginalURL(c *gin.Context) error {
 	ctx := c.Request.Context()
 
-	span, ctx := tracer.StartSpanFromContext(ctx, "h.GetOriginalURL")
-	defer span.Finish()
+	ctx, span := tracing.Start(ctx, "h.GetOriginalURL")
+	defer span.End()
 
-	shortURL := c.Param("shortURL")
+	shortURL, ok := isPreview(c.Param("shortURL"))
+	if ok {
+		return h.preview(c, shortURL, "assets/")
+	}
+
+	domain := h.domainOf(c.Request)
+	// Cookie が無い場合は空となり、パスワードが無い短縮 URL のみ解決される。
+	token, _ := c.Cookie(accessCookie)
 
-	url, err := h.usecase.SearchOriginalURL(ctx, shortURL)
+	dest, err := h.usecase.SearchOriginalURL(ctx, domain, shortURL, token)
 	if err != nil {
+		if errors.Is(err, apperr.ErrPasswordRequired) {
+			return h.renderPassword(c, domain, shortURL, apperr.ErrPasswordRequired, "")
+		}
+
 		return fmt.Errorf("failed to exec usecase.SearchOriginalURL: %w", err)
 	}
 
-	c.Redirect(http.StatusMovedPermanently, url)
+	if !dest.Cacheable() {
+		// 301 はブラウザにキャッシュされ、パスワードやクリック数の確認を経ずに遷移してしまう。
+		c.Header("Cache-Control", "no-store")
+		c.Redirect(http.StatusFound, dest.URL)
+
+		return nil
+	}
+
+	c.Redirect(http.StatusMovedPermanently, dest.URL)
 
 	return nil
 }
@@ -33,8 +61,8 @@ func (h *handler) GetOriginalURL(c *gin.Context) error {
 func (h *handler) GenerateURL(c *gin.Context) error {
 	ctx := c.Request.Context()
 
-	span, ctx := tracer.StartSpanFromContext(ctx, "h.GenerateURL")
-	defer span.Finish()
+	ctx, span := tracing.Start(ctx, "h.GenerateURL")
+	defer span.End()
 
 	var body request.CreateURL
 	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
@@ -42,14 +70,323 @@ func (h *handler) GenerateURL(c *gin.Context) error {
 		return apperr.ErrRequestBodyInvalid
 	}
 
-	url, err := h.usecase.GenerateURL(ctx, body.OriginalURL)
+	if err := body.Validate(); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	domain := h.defaultDomain
+	if body.Domain != "" {
+		domain = strings.ToLower(body.Domain)
+	}
+
+	record, created, err := h.usecase.GenerateURL(ctx, usecase.GenerateURLParams{
+		Domain:      domain,
+		OriginalURL: body.OriginalURL,
+		Owner:       c.GetHeader(h.ownerHeader),
+		ForceNew:    body.ForceNew,
+		Title:       body.Title,
+		Notes:       body.Notes,
+		Tags:        body.Tags,
+		Password:    body.Password,
+		MaxClicks:   body.MaxClicks,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to exec usecase.SearchOriginalURL: %w", err)
+		return fmt.Errorf("failed to exec usecase.GenerateURL: %w", err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"short_url": url,
+	// 既存の短縮 URL を返す場合は、新規作成ではないため 200 とする。
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	res := h.urlJSON(record)
+	res["created"] = created
+	// 作成者には、隠す短縮 URL でも正規化された URL を返す。
+	res["original_url"] = record.URL
+
+	c.Header("Location", h.linkOf(record.Domain, record.Short))
+	c.JSON(status, res)
+
+	return nil
+}
+
+// GetURL returns the short url with the metadata, including the clicks left of the click-limited one.
+func (h *handler) GetURL(c *gin.Context) error {
+	ctx := c.Request.Context()
+
+	ctx, span := tracing.Start(ctx, "h.GetURL")
+	defer span.End()
+
+	domain := h.defaultDomain
+	if d := c.Query("domain"); d != "" {
+		domain = strings.ToLower(d)
+	}
+
+	record, err := h.usecase.GetURL(ctx, domain, c.Param("shortURL"))
+	if err != nil {
+		return fmt.Errorf("failed to exec usecase.GetURL: %w", err)
+	}
+
+	c.JSON(http.StatusOK, h.urlJSON(record))
+
+	return nil
+}
+
+func (h *handler) UpdateURL(c *gin.Context) error {
+	ctx := c.Request.Context()
+
+	ctx, span := tracing.Start(ctx, "h.UpdateURL")
+	defer span.End()
+
+	var body request.UpdateURL
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+		return apperr.ErrRequestBodyInvalid
+	}
+
+	if err := body.Validate(); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	domain := h.defaultDomain
+	if d := c.Query("domain"); d != "" {
+		domain = strings.ToLower(d)
+	}
+
+	record, err := h.usecase.UpdateURL(ctx, usecase.UpdateURLParams{
+		Domain:   domain,
+		ShortURL: c.Param("shortURL"),
+		Owner:    c.GetHeader(h.ownerHeader),
+		Admin:    h.isAdmin(c),
+		Title:    body.Title,
+		Notes:    body.Notes,
+		Tags:     body.Tags,
+		Password: body.Password,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to exec usecase.UpdateURL: %w", err)
+	}
+
+	c.JSON(http.StatusOK, h.urlJSON(record))
+
+	return nil
+}
+
+// Limits of the number of the short urls in a page.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+func (h *handler) ListURLs(c *gin.Context) error {
+	ctx := c.Request.Context()
+
+	ctx, span := tracing.Start(ctx, "h.ListURLs")
+	defer span.End()
+
+	filter, err := h.urlFilterOf(c, defaultListLimit)
+	if err != nil {
+		return err
+	}
+
+	urls, err := h.usecase.ListURLs(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to exec usecase.ListURLs: %w", err)
+	}
+
+	res := make([]gin.H, 0, len(urls))
+	for _, record := range urls {
+		res = append(res, h.urlJSON(record))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"urls": res})
 
 	return nil
 }
+
+// exportHeader is the columns of the exported csv.
+//
+//nolint:gochecknoglobals
+var exportHeader = []string{"domain", "slug", "short_url", "original_url", "title", "notes", "tags", "created_at"}
+
+// ExportURLs writes every short url matching the query as csv, where the tags are joined by ";".
+func (h *handler) ExportURLs(c *gin.Context) error {
+	ctx := c.Request.Context()
+
+	ctx, span := tracing.Start(ctx, "h.ExportURLs")
+	defer span.End()
+
+	// エクスポートはページングせず、既定では全件を返す。
+	filter, err := h.urlFilterOf(c, 0)
+	if err != nil {
+		return err
+	}
+
+	urls, err := h.usecase.ListURLs(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to exec usecase.ListURLs: %w", err)
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="urls.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+
+	records := make([][]string, 0, len(urls)+1)
+	records = append(records, exportHeader)
+
+	for _, record := range urls {
+		originalURL := record.URL
+		if hidesDestination(record) {
+			originalURL = ""
+		}
+
+		records = append(records, []string{
+			record.Domain,
+			record.Short,
+			h.linkOf(record.Domain, record.Short),
+			originalURL,
+			record.Title,
+			record.Notes,
+			strings.Join(record.Tags, ";"),
+			record.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	if err := w.WriteAll(records); err != nil {
+		// ヘッダーは送信済みのため、エラーレスポンスは返せない。
+		h.logger.Warnf(ctx, "failed to write csv: %v", err)
+	}
+
+	return nil
+}
+
+// urlFilterOf returns the filter from the query: domain, owner, tag (repeatable), limit and offset.
+// The limit is defaultLimit if not given, and unlimited if defaultLimit is 0.
+func (h *handler) urlFilterOf(c *gin.Context, defaultLimit int) (repository.URLFilter, error) {
+	filter := repository.URLFilter{
+		Domain: strings.ToLower(c.Query("domain")),
+		Owner:  c.Query("owner"),
+		Tags:   c.QueryArray("tag"),
+		Limit:  defaultLimit,
+	}
+
+	var details []apperr.Detail
+
+	for i, tag := range filter.Tags {
+		if !request.IsTag(tag) {
+			details = append(details, apperr.Detail{Field: fmt.Sprintf("tag[%d]", i), Message: "is not a valid tag"})
+		}
+	}
+
+	if v, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			details = append(details, apperr.Detail{
+				Field: "limit", Message: fmt.Sprintf("must be an integer from 1 to %d", maxListLimit),
+			})
+		}
+
+		filter.Limit = limit
+	}
+
+	if v, ok := c.GetQuery("offset"); ok {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			details = append(details, apperr.Detail{Field: "offset", Message: "must be a non-negative integer"})
+		}
+
+		filter.Offset = offset
+	}
+
+	if len(details) > 0 {
+		return repository.URLFilter{}, apperr.ErrQueryInvalid.WithDetails(details...)
+	}
+
+	return filter, nil
+}
+
+// urlJSON returns the json of the short url in the responses.
+func (h *handler) urlJSON(record repository.URL) gin.H {
+	tags := record.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	// ページのメタデータは非同期に取得されるため、作成直後は null になる。
+	var page gin.H
+	if record.Page != nil {
+		page = gin.H{
+			"title":       record.Page.Title,
+			"description": record.Page.Description,
+			"image_url":   record.Page.ImageURL,
+			"fetched_at":  record.Page.FetchedAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	// 行き先を隠す場合は、行き先のページのメタデータも返さない。
+	var originalURL any = record.URL
+	if hidesDestination(record) {
+		originalURL, page = nil, nil
+	}
+
+	// 回数制限が無い場合は、どちらも null とする。
+	var maxClicks, clicksLeft *int
+	if record.MaxClicks > 0 {
+		left := record.ClicksLeft()
+		maxClicks, clicksLeft = &record.MaxClicks, &left
+	}
+
+	return gin.H{
+		"short_url":    h.linkOf(record.Domain, record.Short),
+		"slug":         record.Short,
+		"domain":       record.Domain,
+		"original_url": originalURL,
+		"page":         page,
+		"title":        record.Title,
+		"notes":        record.Notes,
+		"tags":         tags,
+		// パスワードのハッシュは返さない。
+		"protected":   record.PasswordHash != "",
+		"max_clicks":  maxClicks,
+		"clicks_left": clicksLeft,
+		"created_at":  record.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// hidesDestination reports whether the metadata api hides the destination of the short url,
+// which is shown only to the creator and the visitors knowing the password or spending a click.
+func hidesDestination(record repository.URL) bool {
+	return record.PasswordHash != "" || record.MaxClicks > 0
+}
+
+// linkOf returns the absolute short link of the short url on the domain.
+// The links on the default domain are built from the public base url, which may have the port and the path prefix.
+func (h *handler) linkOf(domain, shortURL string) string {
+	if domain == h.defaultDomain {
+		return h.publicBaseURL.JoinPath(shortURL).String()
+	}
+
+	u := url.URL{Scheme: h.publicBaseURL.Scheme, Host: domain}
+
+	return u.JoinPath(shortURL).String()
+}
+
+// domainOf returns the domain of the short url from the Host of the request.
+// The default domain is used if the Host is empty (e.g. HTTP/1.0).
+func (h *handler) domainOf(r *http.Request) string {
+	host := r.Host
+	if host == "" {
+		return h.defaultDomain
+	}
+
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	// ホスト名は大文字小文字を区別しない。
+	return strings.ToLower(host)
+}
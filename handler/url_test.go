@@ -8,14 +8,17 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/handler"
 	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
 	"github.com/kokoichi206-sandbox/url-shortener/model/request"
+	"github.com/kokoichi206-sandbox/url-shortener/usecase"
 	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
 )
 
@@ -24,9 +27,13 @@ func Test_Handler_GetOriginalURL(t *testing.T) {
 
 	testCases := map[string]struct {
 		path            string
+		host            string
+		cookie          *http.Cookie
 		makeMockUsecase func(m *MockUsecase)
 		wantStatus      int
 		wantLocation    string
+		wantCache       string
+		wantBody        string
 		wantLog         string
 	}{
 		"success": {
@@ -34,19 +41,82 @@ func Test_Handler_GetOriginalURL(t *testing.T) {
 			makeMockUsecase: func(m *MockUsecase) {
 				m.
 					EXPECT().
-					SearchOriginalURL(gomock.Any(), "R0D").
-					Return("https://example.com", nil)
+					SearchOriginalURL(gomock.Any(), "localhost", "R0D", "").
+					Return(repository.Destination{URL: "https://example.com"}, nil)
 			},
 			wantStatus:   http.StatusMovedPermanently,
 			wantLocation: "https://example.com",
 		},
+		"success: domain from host": {
+			path: "/R0D",
+			// ポート番号は除かれ、小文字で検索されること。
+			host: "Short.Example.com:8080",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					SearchOriginalURL(gomock.Any(), "short.example.com", "R0D", "").
+					Return(repository.Destination{URL: "https://example.com/branded"}, nil)
+			},
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://example.com/branded",
+		},
+		"success: access token in cookie": {
+			path:   "/R0D",
+			cookie: &http.Cookie{Name: "link_access", Value: "token"},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					SearchOriginalURL(gomock.Any(), "localhost", "R0D", "token").
+					Return(repository.Destination{URL: "https://example.com/internal", PasswordHash: "hash"}, nil)
+			},
+			// キャッシュされると、Cookie の期限後もパスワード無しで遷移できてしまう。
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://example.com/internal",
+			wantCache:    "no-store",
+		},
+		"success: click-limited": {
+			path: "/R0D",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					SearchOriginalURL(gomock.Any(), "localhost", "R0D", "").
+					Return(repository.Destination{URL: "https://example.com/once", MaxClicks: 1, Clicks: 1}, nil)
+			},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://example.com/once",
+			wantCache:    "no-store",
+		},
+		"failure: password required": {
+			path: "/R0D",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					SearchOriginalURL(gomock.Any(), "localhost", "R0D", "").
+					Return(repository.Destination{}, apperr.ErrPasswordRequired)
+			},
+			// リダイレクトの代わりにパスワードの入力画面を返すこと。
+			wantStatus: http.StatusUnauthorized,
+			wantCache:  "no-cache",
+			wantBody:   `<input id="password" name="password" type="password"`,
+		},
+		"failure: exhausted": {
+			path: "/R0D",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					SearchOriginalURL(gomock.Any(), "localhost", "R0D", "").
+					Return(repository.Destination{}, apperr.ErrShortURLExhausted)
+			},
+			wantStatus: http.StatusGone,
+			wantBody:   `"code":"short_url_exhausted"`,
+		},
 		"failure: not found": {
 			path: "/RXX",
 			makeMockUsecase: func(m *MockUsecase) {
 				m.
 					EXPECT().
-					SearchOriginalURL(gomock.Any(), "RXX").
-					Return("", apperr.ErrShortURLNotFound)
+					SearchOriginalURL(gomock.Any(), "localhost", "RXX", "").
+					Return(repository.Destination{}, apperr.ErrShortURLNotFound)
 			},
 			wantStatus: http.StatusNotFound,
 		},
@@ -55,8 +125,8 @@ func Test_Handler_GetOriginalURL(t *testing.T) {
 			makeMockUsecase: func(m *MockUsecase) {
 				m.
 					EXPECT().
-					SearchOriginalURL(gomock.Any(), "RXX").
-					Return("", errors.New("usecase error"))
+					SearchOriginalURL(gomock.Any(), "localhost", "RXX", "").
+					Return(repository.Destination{}, errors.New("usecase error"))
 			},
 			wantStatus: http.StatusInternalServerError,
 			wantLog:    "failed to exec usecase.SearchOriginalURL: usecase error",
@@ -90,6 +160,10 @@ func Test_Handler_GetOriginalURL(t *testing.T) {
 			)
 
 			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			if tc.cookie != nil {
+				req.AddCookie(tc.cookie)
+			}
 
 			// Act
 			r.ServeHTTP(recorder, req)
@@ -97,6 +171,8 @@ func Test_Handler_GetOriginalURL(t *testing.T) {
 			// Assert
 			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
 			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"), "location header should be equal")
+			assert.Equal(t, tc.wantCache, recorder.Header().Get("Cache-Control"), "cache control header should be equal")
+			assert.Contains(t, recorder.Body.String(), tc.wantBody, "body should contain expected string")
 			assert.True(t, strings.Contains(b.String(), tc.wantLog), "log should contain expected string")
 		})
 	}
@@ -105,10 +181,17 @@ func Test_Handler_GetOriginalURL(t *testing.T) {
 func Test_Handler_GenerateURL(t *testing.T) {
 	t.Parallel()
 
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
 	testCases := map[string]struct {
 		body            *request.CreateURL
+		accept          string
+		owner           string
+		opts            []handler.Option
 		makeMockUsecase func(m *MockUsecase)
 		wantStatus      int
+		wantLocation    string
+		wantContentType string
 		want            string
 		wantLog         string
 	}{
@@ -119,16 +202,248 @@ func Test_Handler_GenerateURL(t *testing.T) {
 			makeMockUsecase: func(m *MockUsecase) {
 				m.
 					EXPECT().
-					GenerateURL(gomock.Any(), "https://example.com").
-					Return("R0D", nil)
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "localhost",
+						OriginalURL: "https://example.com",
+					}).
+					Return(repository.URL{
+						Domain:    "localhost",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						CreatedAt: createdAt,
+					}, true, nil)
 			},
-			wantStatus: http.StatusOK,
-			want:       `{"short_url":"R0D"}`,
+			wantStatus:   http.StatusCreated,
+			wantLocation: "https://localhost/R0D",
+			want:         `{"clicks_left":null,"created":true,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"https://localhost/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"success: existing url": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+			},
+			opts: []handler.Option{handler.WithPublicBaseURL("http://localhost:8080/s")},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "localhost",
+						OriginalURL: "https://example.com",
+					}).
+					Return(repository.URL{
+						Domain:    "localhost",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						CreatedAt: createdAt,
+					}, false, nil)
+			},
+			// 既存の短縮 URL は 200 で返され、公開 URL から link が作られること。
+			wantStatus:   http.StatusOK,
+			wantLocation: "http://localhost:8080/s/R0D",
+			want:         `{"clicks_left":null,"created":false,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"http://localhost:8080/s/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"success: force new with owner": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				ForceNew:    true,
+			},
+			owner: "team-a",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "localhost",
+						OriginalURL: "https://example.com",
+						Owner:       "team-a",
+						ForceNew:    true,
+					}).
+					Return(repository.URL{
+						Domain:    "localhost",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						CreatedAt: createdAt,
+					}, true, nil)
+			},
+			wantStatus:   http.StatusCreated,
+			wantLocation: "https://localhost/R0D",
+			want:         `{"clicks_left":null,"created":true,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"https://localhost/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"success: with domain": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				Domain:      "Short.Example.com",
+			},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "short.example.com",
+						OriginalURL: "https://example.com",
+					}).
+					Return(repository.URL{
+						Domain:    "short.example.com",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						CreatedAt: createdAt,
+					}, true, nil)
+			},
+			// 他の domain の link は公開 URL の scheme で作られること。
+			opts:         []handler.Option{handler.WithPublicBaseURL("http://localhost:8080")},
+			wantStatus:   http.StatusCreated,
+			wantLocation: "http://short.example.com/R0D",
+			want:         `{"clicks_left":null,"created":true,"created_at":"2024-01-02T03:04:05Z","domain":"short.example.com","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"http://short.example.com/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"success: with title and tags": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				Title:       "Example",
+				Tags:        []string{"Go", "news"},
+			},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "localhost",
+						OriginalURL: "https://example.com",
+						Title:       "Example",
+						Tags:        []string{"Go", "news"},
+					}).
+					Return(repository.URL{
+						Domain:    "localhost",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						Title:     "Example",
+						Tags:      []string{"go", "news"},
+						CreatedAt: createdAt,
+					}, true, nil)
+			},
+			wantStatus:   http.StatusCreated,
+			wantLocation: "https://localhost/R0D",
+			want:         `{"clicks_left":null,"created":true,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"https://localhost/R0D","slug":"R0D","tags":["go","news"],"title":"Example"}`,
+		},
+		"success: with password": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				Password:    "correct horse",
+			},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "localhost",
+						OriginalURL: "https://example.com",
+						Password:    "correct horse",
+					}).
+					Return(repository.URL{
+						Domain:       "localhost",
+						URL:          "https://example.com/",
+						Short:        "R0D",
+						PasswordHash: "hash",
+						CreatedAt:    createdAt,
+					}, true, nil)
+			},
+			wantStatus:   http.StatusCreated,
+			wantLocation: "https://localhost/R0D",
+			// パスワードのハッシュは返さず、作成者には正規化された URL を返すこと。
+			want: `{"clicks_left":null,"created":true,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":true,"short_url":"https://localhost/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"failure: short password": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				Password:    "short",
+			},
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			want:            `{"error":{"code":"request_body_invalid","message":"request body is invalid","details":[{"field":"password","message":"must be at least 8 characters and at most 72 bytes"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
+		},
+		"success: with max clicks": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				MaxClicks:   1,
+			},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "localhost",
+						OriginalURL: "https://example.com",
+						MaxClicks:   1,
+					}).
+					Return(repository.URL{
+						Domain:    "localhost",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						MaxClicks: 1,
+						CreatedAt: createdAt,
+					}, true, nil)
+			},
+			wantStatus:   http.StatusCreated,
+			wantLocation: "https://localhost/R0D",
+			want:         `{"clicks_left":1,"created":true,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":1,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"https://localhost/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"failure: negative max clicks": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				MaxClicks:   -1,
+			},
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			want:            `{"error":{"code":"request_body_invalid","message":"request body is invalid","details":[{"field":"max_clicks","message":"must be an integer from 0 to 1000000"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
+		},
+		"failure: invalid tag": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				Tags:        []string{"go", "a;b"},
+			},
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			want:            `{"error":{"code":"request_body_invalid","message":"request body is invalid","details":[{"field":"tags[1]","message":"must be 1 to 50 letters, digits, '-', '_' or '.'"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
+		},
+		"failure: invalid domain": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				Domain:      "https://short.example.com",
+			},
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			want:            `{"error":{"code":"request_body_invalid","message":"request body is invalid","details":[{"field":"domain","message":"must be a host name without scheme and port"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
+		},
+		"failure: domain not found": {
+			body: &request.CreateURL{
+				OriginalURL: "https://example.com",
+				Domain:      "wtf.example.com",
+			},
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "wtf.example.com",
+						OriginalURL: "https://example.com",
+					}).
+					Return(repository.URL{}, false, apperr.ErrDomainNotFound)
+			},
+			wantStatus: http.StatusBadRequest,
+			want:       `{"error":{"code":"domain_not_found","message":"domain not found","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#domain_not_found"}}`,
+		},
+		"failure: not http url": {
+			body: &request.CreateURL{
+				OriginalURL: "javascript:alert(1)",
+			},
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			want:            `{"error":{"code":"request_body_invalid","message":"request body is invalid","details":[{"field":"original_url","message":"must be an absolute http or https url"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
+		},
+		"failure: problem json": {
+			body:            &request.CreateURL{},
+			accept:          "application/problem+json, application/json;q=0.9",
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/problem+json",
+			want:            `{"type":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid","title":"request body is invalid","status":400,"instance":"/api/v1/urls","code":"request_body_invalid","details":[{"field":"original_url","message":"is required"}]}`,
 		},
 		"failure: body empty": {
 			makeMockUsecase: func(m *MockUsecase) {},
 			wantStatus:      http.StatusBadRequest,
-			want:            `{"error":"request body is invalid"}`,
+			want:            `{"error":{"code":"request_body_invalid","message":"request body is invalid","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
 		},
 		"failure: usecase error": {
 			body: &request.CreateURL{
@@ -137,11 +452,14 @@ func Test_Handler_GenerateURL(t *testing.T) {
 			makeMockUsecase: func(m *MockUsecase) {
 				m.
 					EXPECT().
-					GenerateURL(gomock.Any(), "https://example.com").
-					Return("", errors.New("usecase error"))
+					GenerateURL(gomock.Any(), usecase.GenerateURLParams{
+						Domain:      "localhost",
+						OriginalURL: "https://example.com",
+					}).
+					Return(repository.URL{}, false, errors.New("usecase error"))
 			},
 			wantStatus: http.StatusInternalServerError,
-			want:       `{"error":"internal server error"}`,
+			want:       `{"error":{"code":"internal_server_error","message":"internal server error","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#internal_server_error"}}`,
 		},
 	}
 
@@ -162,7 +480,7 @@ func Test_Handler_GenerateURL(t *testing.T) {
 			b := bytes.NewBuffer([]byte{})
 			logger := logger.NewBasicLogger(b, "test", "generateURL")
 
-			h := handler.New(logger, u)
+			h := handler.New(logger, u, tc.opts...)
 			recorder := httptest.NewRecorder()
 			_, r := gin.CreateTestContext(recorder)
 
@@ -177,6 +495,8 @@ func Test_Handler_GenerateURL(t *testing.T) {
 			}
 
 			req, _ := http.NewRequest(http.MethodPost, "/api/v1/urls", &buf)
+			req.Header.Set("Accept", tc.accept)
+			req.Header.Set("X-Owner-ID", tc.owner)
 
 			// Act
 			r.ServeHTTP(recorder, req)
@@ -184,7 +504,445 @@ func Test_Handler_GenerateURL(t *testing.T) {
 			// Assert
 			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
 			assert.Equal(t, tc.want, recorder.Body.String(), "response body should be equal")
+			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"), "location header should be equal")
 			assert.True(t, strings.Contains(b.String(), tc.wantLog), "log should contain expected string")
+
+			if tc.wantContentType != "" {
+				assert.Equal(t, tc.wantContentType, recorder.Header().Get("Content-Type"), "content type should be equal")
+			}
+		})
+	}
+}
+
+func Test_Handler_GetURL(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := map[string]struct {
+		query           string
+		makeMockUsecase func(m *MockUsecase)
+		wantStatus      int
+		want            string
+	}{
+		"success": {
+			query: "?domain=Short.Example.com",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "short.example.com", "R0D").
+					Return(repository.URL{
+						Domain:    "short.example.com",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						CreatedAt: createdAt,
+					}, nil)
+			},
+			wantStatus: http.StatusOK,
+			want:       `{"clicks_left":null,"created_at":"2024-01-02T03:04:05Z","domain":"short.example.com","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"https://short.example.com/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"success: click-limited": {
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{
+						Domain:    "localhost",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						MaxClicks: 3,
+						Clicks:    1,
+						// 行き先のページのメタデータも返さないこと。
+						Page: &repository.PageMetadata{
+							Title:     "Example Domain",
+							FetchedAt: createdAt,
+						},
+						CreatedAt: createdAt,
+					}, nil)
+			},
+			wantStatus: http.StatusOK,
+			want:       `{"clicks_left":2,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":3,"notes":"","original_url":null,"page":null,"protected":false,"short_url":"https://localhost/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"success: protected": {
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{
+						Domain:       "localhost",
+						URL:          "https://example.com/secret",
+						Short:        "R0D",
+						PasswordHash: "hash",
+						Page:         &repository.PageMetadata{Title: "Secret", Description: "Internal page."},
+						CreatedAt:    createdAt,
+					}, nil)
+			},
+			wantStatus: http.StatusOK,
+			// パスワードを知らない利用者にも返るため、行き先とそのメタデータは返さないこと。
+			want: `{"clicks_left":null,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":null,"page":null,"protected":true,"short_url":"https://localhost/R0D","slug":"R0D","tags":[],"title":""}`,
+		},
+		"failure: not found": {
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					GetURL(gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			want:       `{"error":{"code":"short_url_not_found","message":"short url not found","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#short_url_not_found"}}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			u := NewMockUsecase(ctrl)
+			tc.makeMockUsecase(u)
+
+			logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "getURL")
+
+			h := handler.New(logger, u)
+			recorder := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(recorder)
+
+			r.GET(
+				"/api/v1/urls/:shortURL",
+				handler.HandleWrapper(h.GetURL, logger),
+			)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/urls/R0D"+tc.query, nil)
+
+			// Act
+			r.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+			assert.Equal(t, tc.want, recorder.Body.String(), "response body should be equal")
 		})
 	}
 }
+
+func Test_Handler_UpdateURL(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	title := "Example"
+
+	testCases := map[string]struct {
+		query           string
+		owner           string
+		auth            string
+		body            string
+		makeMockUsecase func(m *MockUsecase)
+		wantStatus      int
+		want            string
+	}{
+		"success": {
+			query: "?domain=Short.Example.com",
+			owner: "team-a",
+			body:  `{"title":"Example","tags":["go"]}`,
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UpdateURL(gomock.Any(), usecase.UpdateURLParams{
+						Domain:   "short.example.com",
+						ShortURL: "R0D",
+						Owner:    "team-a",
+						Title:    &title,
+						Tags:     []string{"go"},
+					}).
+					Return(repository.URL{
+						Domain:    "short.example.com",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						Title:     "Example",
+						Tags:      []string{"go"},
+						CreatedAt: createdAt,
+					}, nil)
+			},
+			wantStatus: http.StatusOK,
+			want:       `{"clicks_left":null,"created_at":"2024-01-02T03:04:05Z","domain":"short.example.com","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"https://short.example.com/R0D","slug":"R0D","tags":["go"],"title":"Example"}`,
+		},
+		"success: admin": {
+			auth: "Bearer secret",
+			body: `{"title":"Example"}`,
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UpdateURL(gomock.Any(), usecase.UpdateURLParams{
+						Domain:   "localhost",
+						ShortURL: "R0D",
+						Admin:    true,
+						Title:    &title,
+					}).
+					Return(repository.URL{
+						Domain:    "localhost",
+						URL:       "https://example.com/",
+						Short:     "R0D",
+						Title:     "Example",
+						CreatedAt: createdAt,
+					}, nil)
+			},
+			wantStatus: http.StatusOK,
+			want:       `{"clicks_left":null,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":"https://example.com/","page":null,"protected":false,"short_url":"https://localhost/R0D","slug":"R0D","tags":[],"title":"Example"}`,
+		},
+		"failure: wrong admin token": {
+			auth: "Bearer wrong",
+			body: `{"title":"Example"}`,
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UpdateURL(gomock.Any(), usecase.UpdateURLParams{
+						Domain:   "localhost",
+						ShortURL: "R0D",
+						Title:    &title,
+					}).
+					Return(repository.URL{}, apperr.ErrNotOwner)
+			},
+			wantStatus: http.StatusForbidden,
+			want:       `{"error":{"code":"not_owner","message":"short url can be updated only by its owner","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#not_owner"}}`,
+		},
+		"failure: nothing to update": {
+			body:            `{}`,
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			want:            `{"error":{"code":"request_body_invalid","message":"request body is invalid","details":[{"field":"","message":"nothing to update"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#request_body_invalid"}}`,
+		},
+		"failure: not found": {
+			body: `{"title":"Example"}`,
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UpdateURL(gomock.Any(), usecase.UpdateURLParams{
+						Domain:   "localhost",
+						ShortURL: "R0D",
+						Title:    &title,
+					}).
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			want:       `{"error":{"code":"short_url_not_found","message":"short url not found","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#short_url_not_found"}}`,
+		},
+		"failure: not owner": {
+			owner: "team-b",
+			body:  `{"title":"Example"}`,
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					UpdateURL(gomock.Any(), usecase.UpdateURLParams{
+						Domain:   "localhost",
+						ShortURL: "R0D",
+						Owner:    "team-b",
+						Title:    &title,
+					}).
+					Return(repository.URL{}, apperr.ErrNotOwner)
+			},
+			wantStatus: http.StatusForbidden,
+			want:       `{"error":{"code":"not_owner","message":"short url can be updated only by its owner","docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#not_owner"}}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			u := NewMockUsecase(ctrl)
+			tc.makeMockUsecase(u)
+
+			logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "updateURL")
+
+			h := handler.New(logger, u, handler.WithAdminToken("secret"))
+			recorder := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(recorder)
+
+			r.PATCH(
+				"/api/v1/urls/:shortURL",
+				handler.HandleWrapper(h.UpdateURL, logger),
+			)
+
+			req, _ := http.NewRequest(http.MethodPatch, "/api/v1/urls/R0D"+tc.query, strings.NewReader(tc.body))
+			if tc.owner != "" {
+				req.Header.Set("X-Owner-ID", tc.owner)
+			}
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+
+			// Act
+			r.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+			assert.Equal(t, tc.want, recorder.Body.String(), "response body should be equal")
+		})
+	}
+}
+
+func Test_Handler_ListURLs(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := map[string]struct {
+		query           string
+		makeMockUsecase func(m *MockUsecase)
+		wantStatus      int
+		want            string
+	}{
+		"success": {
+			query: "?owner=team-a&tag=go&tag=news&limit=10&offset=20",
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					ListURLs(gomock.Any(), repository.URLFilter{
+						Owner: "team-a", Tags: []string{"go", "news"}, Limit: 10, Offset: 20,
+					}).
+					Return([]repository.URL{{
+						Domain: "localhost",
+						URL:    "https://example.com/",
+						Short:  "R0D",
+						Tags:   []string{"go", "news"},
+						Page: &repository.PageMetadata{
+							Title:       "Example Domain",
+							Description: "An example page.",
+							ImageURL:    "https://example.com/og.png",
+							FetchedAt:   createdAt,
+						},
+						CreatedAt: createdAt,
+					}}, nil)
+			},
+			wantStatus: http.StatusOK,
+			want:       `{"urls":[{"clicks_left":null,"created_at":"2024-01-02T03:04:05Z","domain":"localhost","max_clicks":null,"notes":"","original_url":"https://example.com/","page":{"description":"An example page.","fetched_at":"2024-01-02T03:04:05Z","image_url":"https://example.com/og.png","title":"Example Domain"},"protected":false,"short_url":"https://localhost/R0D","slug":"R0D","tags":["go","news"],"title":""}]}`,
+		},
+		"success: default limit": {
+			makeMockUsecase: func(m *MockUsecase) {
+				m.
+					EXPECT().
+					ListURLs(gomock.Any(), repository.URLFilter{Limit: 100}).
+					Return([]repository.URL{}, nil)
+			},
+			wantStatus: http.StatusOK,
+			want:       `{"urls":[]}`,
+		},
+		"failure: invalid query": {
+			query:           "?limit=1001&offset=-1",
+			makeMockUsecase: func(m *MockUsecase) {},
+			wantStatus:      http.StatusBadRequest,
+			want:            `{"error":{"code":"query_invalid","message":"query parameter is invalid","details":[{"field":"limit","message":"must be an integer from 1 to 1000"},{"field":"offset","message":"must be a non-negative integer"}],"docs_url":"https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md#query_invalid"}}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			u := NewMockUsecase(ctrl)
+			tc.makeMockUsecase(u)
+
+			logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "listURLs")
+
+			h := handler.New(logger, u)
+			recorder := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(recorder)
+
+			r.GET(
+				"/api/v1/urls",
+				handler.HandleWrapper(h.ListURLs, logger),
+			)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/urls"+tc.query, nil)
+
+			// Act
+			r.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, recorder.Code, "status code should be equal")
+			assert.Equal(t, tc.want, recorder.Body.String(), "response body should be equal")
+		})
+	}
+}
+
+func Test_Handler_ExportURLs(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := NewMockUsecase(ctrl)
+	u.
+		EXPECT().
+		// エクスポートは件数の制限なしで検索されること。
+		ListURLs(gomock.Any(), repository.URLFilter{Tags: []string{"go"}}).
+		Return([]repository.URL{{
+			Domain:    "localhost",
+			URL:       "https://example.com/",
+			Short:     "R0D",
+			Title:     "Example, Inc.",
+			Notes:     "line1\nline2",
+			Tags:      []string{"go", "news"},
+			CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}, {
+			Domain:       "localhost",
+			URL:          "https://example.com/secret",
+			Short:        "R0E",
+			PasswordHash: "hash",
+			CreatedAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}, {
+			Domain:    "localhost",
+			URL:       "https://example.com/once",
+			Short:     "R0F",
+			MaxClicks: 1,
+			CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}}, nil)
+
+	logger := logger.NewBasicLogger(bytes.NewBuffer([]byte{}), "test", "exportURLs")
+
+	h := handler.New(logger, u)
+	recorder := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(recorder)
+
+	r.GET(
+		"/api/v1/urls/export",
+		handler.HandleWrapper(h.ExportURLs, logger),
+	)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/urls/export?tag=go", nil)
+
+	// Act
+	r.ServeHTTP(recorder, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, recorder.Code, "status code should be equal")
+	assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"), "content type should be equal")
+	assert.Equal(t, `attachment; filename="urls.csv"`, recorder.Header().Get("Content-Disposition"))
+	assert.Equal(t,
+		"domain,slug,short_url,original_url,title,notes,tags,created_at\n"+
+			"localhost,R0D,https://localhost/R0D,https://example.com/,\"Example, Inc.\",\"line1\nline2\",go;news,2024-01-02T03:04:05Z\n"+
+			// パスワード付き、回数制限付きの短縮 URL の行き先は出力しないこと。
+			"localhost,R0E,https://localhost/R0E,,,,,2024-01-02T03:04:05Z\n"+
+			"localhost,R0F,https://localhost/R0F,,,,,2024-01-02T03:04:05Z\n",
+		recorder.Body.String(), "csv should be equal")
+}
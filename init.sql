@@ -1,8 +1,66 @@
-CREATE TABLE shorturl (
+-- schema_migrations has the version of this schema,
+-- which must be equal to repository.SchemaVersion.
+-- The existing databases are upgraded by the files in migrations/.
+CREATE TABLE schema_migrations (
+    version INTEGER NOT NULL PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES (1), (2), (3), (4), (5), (6), (7);
+
+-- domains are the hosts which serve the short urls (e.g. branded short domains).
+CREATE TABLE domains (
     id SERIAL PRIMARY KEY,
-    url TEXT NOT NULL UNIQUE,
-    short TEXT NOT NULL UNIQUE,
+    host TEXT NOT NULL UNIQUE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
-INSERT INTO shorturl (url, short) VALUES ('https://www.google.com', 'google');
+INSERT INTO domains (host) VALUES ('localhost');
+
+-- The same short url can be used on different domains.
+-- dedup_key decides which urls share the short url (e.g. the url itself, or the owner and the url),
+-- and NULL means the short url is never shared.
+-- password_hash is the bcrypt hash of the password, and the empty one means the short url is not protected.
+-- max_clicks limits the redirects (0 means unlimited), and clicks counts them only if max_clicks is set.
+CREATE TABLE shorturl (
+    id SERIAL PRIMARY KEY,
+    domain_id INTEGER NOT NULL REFERENCES domains (id),
+    url TEXT NOT NULL,
+    short TEXT NOT NULL,
+    owner TEXT NOT NULL DEFAULT '',
+    dedup_key TEXT,
+    title TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    max_clicks INTEGER NOT NULL DEFAULT 0 CHECK (max_clicks >= 0),
+    clicks INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT shorturl_domain_dedup_key UNIQUE (domain_id, dedup_key),
+    CONSTRAINT shorturl_domain_short_key UNIQUE (domain_id, short)
+);
+
+-- dedup_key is normalized in the same way as usecase.normalizeURL.
+INSERT INTO shorturl (domain_id, url, short, dedup_key) VALUES (1, 'https://www.google.com/', 'google', 'https://www.google.com/');
+
+-- tags organize the short urls (many-to-many).
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE shorturl_tags (
+    shorturl_id INTEGER NOT NULL REFERENCES shorturl (id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags (id),
+    PRIMARY KEY (shorturl_id, tag_id)
+);
+
+CREATE INDEX shorturl_tags_tag_id_idx ON shorturl_tags (tag_id);
+
+-- page_metadata is the metadata of the destination pages, fetched asynchronously after the short urls are created.
+CREATE TABLE page_metadata (
+    shorturl_id INTEGER PRIMARY KEY REFERENCES shorturl (id) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
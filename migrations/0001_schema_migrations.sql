@@ -0,0 +1,12 @@
+-- Adds schema_migrations to the databases created before it, whose schema is the version 1.
+-- It can be applied to the databases which already have the table, as well.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER NOT NULL PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES (1) ON CONFLICT (version) DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Adds domains, and makes the short urls unique per domain.
+-- The existing short urls belong to the domain 'localhost', which should be renamed to the actual host.
+BEGIN;
+
+CREATE TABLE domains (
+    id SERIAL PRIMARY KEY,
+    host TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO domains (host) VALUES ('localhost');
+
+ALTER TABLE shorturl ADD COLUMN domain_id INTEGER REFERENCES domains (id);
+UPDATE shorturl SET domain_id = (SELECT id FROM domains WHERE host = 'localhost');
+ALTER TABLE shorturl ALTER COLUMN domain_id SET NOT NULL;
+
+ALTER TABLE shorturl DROP CONSTRAINT shorturl_url_key;
+ALTER TABLE shorturl DROP CONSTRAINT shorturl_short_key;
+ALTER TABLE shorturl ADD CONSTRAINT shorturl_domain_url_key UNIQUE (domain_id, url);
+ALTER TABLE shorturl ADD CONSTRAINT shorturl_domain_short_key UNIQUE (domain_id, short);
+
+INSERT INTO schema_migrations (version) VALUES (2);
+
+COMMIT;
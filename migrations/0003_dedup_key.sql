@@ -0,0 +1,45 @@
+-- Replaces the uniqueness of the urls with the dedup key, so that the same url can be shortened
+-- more than once depending on the dedup policy (e.g. per owner).
+-- The existing short urls keep being reused as the dedup policy "global".
+BEGIN;
+
+ALTER TABLE shorturl ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE shorturl ADD COLUMN dedup_key TEXT;
+
+-- The keys are normalized in the same way as the new ones (usecase.normalizeURL):
+-- the scheme and the host in lower case, no default port and "/" for the empty path.
+-- The urls which cannot be parsed keep themselves as the key.
+UPDATE shorturl AS s
+SET dedup_key = COALESCE(n.key, s.url)
+FROM (
+    SELECT
+        id,
+        lower(m[1]) || '://' || COALESCE(m[2], '') || lower(m[3])
+            || CASE
+                WHEN (lower(m[1]) = 'http' AND m[4] = ':80') OR (lower(m[1]) = 'https' AND m[4] = ':443') THEN ''
+                ELSE COALESCE(m[4], '')
+            END
+            || CASE WHEN m[5] = '' THEN '/' ELSE m[5] END
+            || m[6] AS key
+    FROM (
+        SELECT
+            id,
+            regexp_match(url, '^([A-Za-z][A-Za-z0-9+.-]*)://([^/?#@]*@)?(\[[^]/?#]*\]|[^/?#:]*)(:[0-9]*)?([^?#]*)(.*)$') AS m
+        FROM shorturl
+    ) AS parsed
+) AS n
+WHERE s.id = n.id;
+
+-- The urls which differed only before the normalization (e.g. with and without the trailing "/")
+-- have the same key, and only the oldest one is shared.
+UPDATE shorturl AS s
+SET dedup_key = NULL
+FROM shorturl AS o
+WHERE o.domain_id = s.domain_id AND o.dedup_key = s.dedup_key AND o.id < s.id;
+
+ALTER TABLE shorturl DROP CONSTRAINT shorturl_domain_url_key;
+ALTER TABLE shorturl ADD CONSTRAINT shorturl_domain_dedup_key UNIQUE (domain_id, dedup_key);
+
+INSERT INTO schema_migrations (version) VALUES (3);
+
+COMMIT;
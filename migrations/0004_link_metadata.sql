@@ -0,0 +1,22 @@
+-- Adds the title, the notes and the tags of the short urls.
+BEGIN;
+
+ALTER TABLE shorturl ADD COLUMN title TEXT NOT NULL DEFAULT '';
+ALTER TABLE shorturl ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE shorturl_tags (
+    shorturl_id INTEGER NOT NULL REFERENCES shorturl (id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags (id),
+    PRIMARY KEY (shorturl_id, tag_id)
+);
+
+CREATE INDEX shorturl_tags_tag_id_idx ON shorturl_tags (tag_id);
+
+INSERT INTO schema_migrations (version) VALUES (4);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Adds the metadata of the destination pages, which are fetched asynchronously.
+BEGIN;
+
+CREATE TABLE page_metadata (
+    shorturl_id INTEGER PRIMARY KEY REFERENCES shorturl (id) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+INSERT INTO schema_migrations (version) VALUES (5);
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Adds the password of the short urls, which is stored as a bcrypt hash.
+BEGIN;
+
+ALTER TABLE shorturl ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+
+INSERT INTO schema_migrations (version) VALUES (6);
+
+COMMIT;
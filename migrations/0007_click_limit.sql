@@ -0,0 +1,9 @@
+-- Adds the limit of the clicks of the short urls (e.g. one-time links).
+BEGIN;
+
+ALTER TABLE shorturl ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0 CHECK (max_clicks >= 0);
+ALTER TABLE shorturl ADD COLUMN clicks INTEGER NOT NULL DEFAULT 0;
+
+INSERT INTO schema_migrations (version) VALUES (7);
+
+COMMIT;
@@ -2,10 +2,23 @@ package apperr
 
 import "net/http"
 
+// DocsURL is the document which describes every error code.
+const DocsURL = "https://github.com/kokoichi206-sandbox/url-shortener/blob/main/docs/errors.md"
+
 type AppError struct {
 	StatusCode int
-	Message    string
-	Log        string
+	// Code is the machine-readable code, which must not be changed once released.
+	Code    string
+	Message string
+	Log     string
+	// Details is the additional information like field-level validation errors.
+	Details []Detail
+}
+
+// Detail describes what is wrong with the field of the request.
+type Detail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Error returns error message.
@@ -14,8 +27,105 @@ func (e AppError) Error() string {
 	return e.Message
 }
 
+// Is reports whether the target has the same code,
+// so that errors.Is works even if the details are added.
+func (e AppError) Is(target error) bool {
+	t, ok := target.(AppError)
+
+	return ok && t.Code == e.Code
+}
+
+// WithDetails returns a copy of the error with the details.
+func (e AppError) WithDetails(details ...Detail) AppError {
+	e.Details = append(append([]Detail{}, e.Details...), details...)
+
+	return e
+}
+
+// DocsURL returns the link to the description of the error.
+func (e AppError) DocsURL() string {
+	return DocsURL + "#" + e.Code
+}
+
 var (
-	ErrServerError        = AppError{http.StatusInternalServerError, "internal server error", "internal server error"}
-	ErrRequestBodyInvalid = AppError{http.StatusBadRequest, "request body is invalid", ""}
-	ErrShortURLNotFound   = AppError{http.StatusNotFound, "short url not found", ""}
+	ErrServerError = AppError{
+		StatusCode: http.StatusInternalServerError,
+		Code:       "internal_server_error",
+		Message:    "internal server error",
+		Log:        "internal server error",
+	}
+	ErrRequestBodyInvalid = AppError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "request_body_invalid",
+		Message:    "request body is invalid",
+	}
+	ErrQueryInvalid = AppError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "query_invalid",
+		Message:    "query parameter is invalid",
+	}
+	ErrShortURLNotFound = AppError{
+		StatusCode: http.StatusNotFound,
+		Code:       "short_url_not_found",
+		Message:    "short url not found",
+	}
+	ErrShortURLExhausted = AppError{
+		StatusCode: http.StatusGone,
+		Code:       "short_url_exhausted",
+		Message:    "short url has no clicks left",
+	}
+	ErrDomainNotFound = AppError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "domain_not_found",
+		Message:    "domain not found",
+	}
+	ErrUnauthorized = AppError{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "unauthorized",
+		Message:    "unauthorized",
+	}
+	ErrNotOwner = AppError{
+		StatusCode: http.StatusForbidden,
+		Code:       "not_owner",
+		Message:    "short url can be updated only by its owner",
+	}
+	ErrPasswordRequired = AppError{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "password_required",
+		Message:    "password required",
+	}
+	ErrPasswordIncorrect = AppError{
+		StatusCode: http.StatusForbidden,
+		Code:       "password_incorrect",
+		Message:    "password is incorrect",
+	}
+	ErrTooManyAttempts = AppError{
+		StatusCode: http.StatusTooManyRequests,
+		Code:       "too_many_attempts",
+		Message:    "too many attempts",
+	}
+	ErrServiceUnavailable = AppError{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "service_unavailable",
+		Message:    "service unavailable",
+	}
 )
+
+// Catalog is the list of every error returned to the clients.
+// A new error must be added here and to the document.
+//
+//nolint:gochecknoglobals
+var Catalog = []AppError{
+	ErrServerError,
+	ErrRequestBodyInvalid,
+	ErrQueryInvalid,
+	ErrShortURLNotFound,
+	ErrShortURLExhausted,
+	ErrDomainNotFound,
+	ErrUnauthorized,
+	ErrNotOwner,
+	ErrPasswordRequired,
+	ErrPasswordIncorrect,
+	ErrTooManyAttempts,
+	ErrServiceUnavailable,
+}
@@ -0,0 +1,73 @@
+package apperr_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+)
+
+func Test_AppError_Catalog(t *testing.T) {
+	t.Parallel()
+
+	docs, err := os.ReadFile("../../docs/errors.md")
+	require.NoError(t, err, "error document should exist")
+
+	codes := map[string]bool{}
+
+	for _, e := range apperr.Catalog {
+		// code は一意であること。
+		assert.False(t, codes[e.Code], "code %s is duplicated", e.Code)
+		codes[e.Code] = true
+
+		// 全ての code がドキュメントに記載されていること。
+		assert.True(t, strings.Contains(string(docs), "## "+e.Code+"\n"), "code %s is not documented", e.Code)
+	}
+}
+
+func Test_AppError_Is(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		err    error
+		target error
+		want   bool
+	}{
+		"success: same error": {
+			err:    apperr.ErrShortURLNotFound,
+			target: apperr.ErrShortURLNotFound,
+			want:   true,
+		},
+		"success: with details": {
+			err:    fmt.Errorf("wrapped: %w", apperr.ErrRequestBodyInvalid.WithDetails(apperr.Detail{Field: "f", Message: "m"})),
+			target: apperr.ErrRequestBodyInvalid,
+			want:   true,
+		},
+		"failure: different error": {
+			err:    apperr.ErrShortURLNotFound,
+			target: apperr.ErrRequestBodyInvalid,
+			want:   false,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got := errors.Is(tc.err, tc.target)
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+		})
+	}
+}
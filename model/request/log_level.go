@@ -0,0 +1,9 @@
+package request
+
+type UpdateLogLevel struct {
+	// Level is the default level like "debug" or "info". Unchanged if empty.
+	Level string `json:"level"`
+	// Overrides replaces the levels per package (e.g. {"repository": "debug"}).
+	// Unchanged if null, and cleared if empty.
+	Overrides map[string]string `json:"overrides"`
+}
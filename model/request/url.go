@@ -1,5 +1,186 @@
 package request
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+)
+
 type CreateURL struct {
 	OriginalURL string `json:"original_url"`
+	// Domain is the host of the short url, which is the default domain if empty.
+	Domain string `json:"domain"`
+	// ForceNew creates a new short url even if the url has already been shortened.
+	ForceNew bool `json:"force_new"`
+	// Title, Notes and Tags are ignored if the existing short url is returned.
+	Title string   `json:"title"`
+	Notes string   `json:"notes"`
+	Tags  []string `json:"tags"`
+	// Password protects the short url if not empty, and always creates a new short url.
+	Password string `json:"password"`
+	// MaxClicks limits the redirects if not 0, and always creates a new short url.
+	MaxClicks int `json:"max_clicks"`
+}
+
+// Validate returns apperr.ErrRequestBodyInvalid with the details of the invalid fields.
+func (r CreateURL) Validate() error {
+	var details []apperr.Detail
+
+	if r.OriginalURL == "" {
+		details = append(details, apperr.Detail{Field: "original_url", Message: "is required"})
+	} else if u, err := url.Parse(r.OriginalURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details = append(details, apperr.Detail{Field: "original_url", Message: "must be an absolute http or https url"})
+	}
+
+	if r.Domain != "" && !isHost(r.Domain) {
+		details = append(details, apperr.Detail{Field: "domain", Message: "must be a host name without scheme and port"})
+	}
+
+	details = append(details, validateMetadata(&r.Title, &r.Notes, r.Tags)...)
+
+	if r.Password != "" {
+		details = append(details, validatePassword(r.Password)...)
+	}
+
+	if r.MaxClicks < 0 || r.MaxClicks > MaxClicksLimit {
+		details = append(details, apperr.Detail{
+			Field: "max_clicks", Message: fmt.Sprintf("must be an integer from 0 to %d", MaxClicksLimit),
+		})
+	}
+
+	if len(details) > 0 {
+		return apperr.ErrRequestBodyInvalid.WithDetails(details...)
+	}
+
+	return nil
+}
+
+// MaxClicksLimit is the upper limit of the max clicks of the short url.
+const MaxClicksLimit = 1000000
+
+// UpdateURL is the request to update the short url.
+type UpdateURL struct {
+	// Title and Notes are unchanged if null.
+	Title *string `json:"title"`
+	Notes *string `json:"notes"`
+	// Tags replaces the tags. Unchanged if null, and cleared if empty.
+	Tags []string `json:"tags"`
+	// Password replaces the password. Unchanged if null, and removed if empty.
+	Password *string `json:"password"`
+}
+
+// Validate returns apperr.ErrRequestBodyInvalid with the details of the invalid fields.
+func (r UpdateURL) Validate() error {
+	if r.Title == nil && r.Notes == nil && r.Tags == nil && r.Password == nil {
+		return apperr.ErrRequestBodyInvalid.WithDetails(apperr.Detail{Message: "nothing to update"})
+	}
+
+	details := validateMetadata(r.Title, r.Notes, r.Tags)
+
+	if r.Password != nil && *r.Password != "" {
+		details = append(details, validatePassword(*r.Password)...)
+	}
+
+	if len(details) > 0 {
+		return apperr.ErrRequestBodyInvalid.WithDetails(details...)
+	}
+
+	return nil
+}
+
+// Limits of the metadata of the short url.
+const (
+	MaxTitleLength = 200
+	MaxNotesLength = 2000
+	MaxTags        = 20
+	MaxTagLength   = 50
+)
+
+// validateMetadata validates the title, the notes and the tags, where nil means not given.
+func validateMetadata(title, notes *string, tags []string) []apperr.Detail {
+	var details []apperr.Detail
+
+	if title != nil && utf8.RuneCountInString(*title) > MaxTitleLength {
+		details = append(details, apperr.Detail{
+			Field: "title", Message: fmt.Sprintf("must be at most %d characters", MaxTitleLength),
+		})
+	}
+
+	if notes != nil && utf8.RuneCountInString(*notes) > MaxNotesLength {
+		details = append(details, apperr.Detail{
+			Field: "notes", Message: fmt.Sprintf("must be at most %d characters", MaxNotesLength),
+		})
+	}
+
+	if len(tags) > MaxTags {
+		details = append(details, apperr.Detail{
+			Field: "tags", Message: fmt.Sprintf("must be at most %d tags", MaxTags),
+		})
+	}
+
+	for i, tag := range tags {
+		if !IsTag(tag) {
+			details = append(details, apperr.Detail{
+				Field:   fmt.Sprintf("tags[%d]", i),
+				Message: fmt.Sprintf("must be 1 to %d letters, digits, '-', '_' or '.'", MaxTagLength),
+			})
+		}
+	}
+
+	return details
+}
+
+// Limits of the password of the short url.
+// The max is in bytes, since bcrypt uses only the first 72 bytes.
+const (
+	MinPasswordLength = 8
+	MaxPasswordBytes  = 72
+)
+
+func validatePassword(password string) []apperr.Detail {
+	if utf8.RuneCountInString(password) < MinPasswordLength || len(password) > MaxPasswordBytes {
+		return []apperr.Detail{{
+			Field: "password",
+			Message: fmt.Sprintf(
+				"must be at least %d characters and at most %d bytes", MinPasswordLength, MaxPasswordBytes,
+			),
+		}}
+	}
+
+	return nil
+}
+
+// IsTag reports whether s is a valid tag, ignoring the surrounding spaces.
+// The tags are compared case-insensitively.
+func IsTag(s string) bool {
+	s = strings.TrimSpace(s)
+
+	if s == "" || utf8.RuneCountInString(s) > MaxTagLength {
+		return false
+	}
+
+	for _, r := range s {
+		// エクスポート時の区切り文字と衝突しないよう、記号は一部のみ許可する。
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isHost reports whether s is a bare host name like "example.com".
+func isHost(s string) bool {
+	// ポート番号や IPv6 アドレスは受け付けない。
+	if strings.ContainsAny(s, ":[]") {
+		return false
+	}
+
+	u, err := url.Parse("//" + s)
+
+	return err == nil && u.Host == s && u.User == nil
 }
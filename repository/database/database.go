@@ -4,39 +4,122 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/lib/pq"
 
 	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
-	_ "github.com/lib/pq"
 )
 
+// Drivers which can be passed to Connect.
+const (
+	// DriverPQ uses github.com/lib/pq.
+	DriverPQ = "postgres"
+	// DriverPgx uses the database/sql driver of github.com/jackc/pgx.
+	DriverPgx = "pgx"
+	// DriverPgxPool uses the native connection pool of github.com/jackc/pgx (pgxpool).
+	DriverPgxPool = "pgxpool"
+)
+
+// PoolConfig is the settings of the connection pool.
+// Zero values mean the default of each driver.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
 type database struct {
 	db     *sql.DB
+	pool   *pgxpool.Pool
 	logger logger.Logger
 }
 
-func Connect(driver, host, port, user, password, dbname, sslmode string) (*sql.DB, error) {
+// Option is the optional setting of the database.
+type Option func(*database)
+
+// WithPgxPool makes Stats read the statistics from pgxpool,
+// because *sql.DB opened on top of it does not know the connections in the pool.
+func WithPgxPool(pool *pgxpool.Pool) Option {
+	return func(d *database) {
+		d.pool = pool
+	}
+}
+
+// Connect opens *sql.DB with the driver.
+// The pool is returned only for DriverPgxPool and must be closed after *sql.DB.
+func Connect(
+	driver, host, port, user, password, dbname, sslmode string, pool PoolConfig,
+) (*sql.DB, *pgxpool.Pool, error) {
 	source := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		host, port, user, password, dbname, sslmode,
 	)
 
+	if driver == DriverPgxPool {
+		return connectPgxPool(source, pool)
+	}
+
 	sqlDB, err := sql.Open(driver, source)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open sql: %w", err)
+		return nil, nil, fmt.Errorf("failed to open sql: %w", err)
+	}
+
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	// database/sql では 0 がアイドル接続なしを意味するため、デフォルト (2) を使う場合は設定しない。
+	if pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
 	}
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
-	return sqlDB, nil
+	return sqlDB, nil, nil
+}
+
+// connectPgxPool opens *sql.DB on top of pgxpool.
+// The connections are managed by pgxpool, so MaxIdleConns is not used.
+func connectPgxPool(source string, pool PoolConfig) (*sql.DB, *pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse pgxpool config: %w", err)
+	}
+
+	if pool.MaxOpenConns > 0 {
+		cfg.MaxConns = int32(pool.MaxOpenConns)
+	}
+
+	if pool.ConnMaxLifetime > 0 {
+		cfg.MaxConnLifetime = pool.ConnMaxLifetime
+	}
+
+	if pool.ConnMaxIdleTime > 0 {
+		cfg.MaxConnIdleTime = pool.ConnMaxIdleTime
+	}
+
+	p, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create pgxpool: %w", err)
+	}
+
+	return stdlib.OpenDBFromPool(p), p, nil
 }
 
 func New(
-	sqlDB *sql.DB, logger logger.Logger,
+	sqlDB *sql.DB, logger logger.Logger, opts ...Option,
 ) repository.Database {
 	db := &database{
 		db:     sqlDB,
 		logger: logger,
 	}
 
+	for _, opt := range opts {
+		opt(db)
+	}
+
 	return db
 }
 
@@ -44,3 +127,46 @@ func (d *database) Health(ctx context.Context) error {
 	//nolint: wrapcheck
 	return d.db.PingContext(ctx)
 }
+
+const selectSchemaVersionStmt = `
+SELECT
+	MAX(version)
+FROM schema_migrations;
+`
+
+func (d *database) SchemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	if err := d.db.QueryRowContext(ctx, selectSchemaVersionStmt).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to scan: %w", err)
+	}
+
+	return int(version.Int64), nil
+}
+
+func (d *database) Stats() repository.DBStats {
+	if d.pool != nil {
+		s := d.pool.Stat()
+
+		// pgxpool does not record the time spent waiting for a connection,
+		// so the total time of acquiring connections is used instead.
+		return repository.DBStats{
+			MaxOpenConnections: int(s.MaxConns()),
+			OpenConnections:    int(s.TotalConns()),
+			InUse:              int(s.AcquiredConns()),
+			Idle:               int(s.IdleConns()),
+			WaitCount:          s.EmptyAcquireCount(),
+			WaitDuration:       s.AcquireDuration(),
+		}
+	}
+
+	s := d.db.Stats()
+
+	return repository.DBStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+	}
+}
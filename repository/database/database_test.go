@@ -0,0 +1,156 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/database"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_Database_SchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		makeMock func(m sqlmock.Sqlmock)
+		want     int
+		wantErr  string
+	}{
+		"success": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.SelectSchemaVersionStmt)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"max"}).
+							AddRow(1),
+					)
+			},
+			want: 1,
+		},
+		"success: no migration applied": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.SelectSchemaVersionStmt)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"max"}).
+							AddRow(nil),
+					)
+			},
+			want: 0,
+		},
+		"failure: no table": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.SelectSchemaVersionStmt)).
+					WillReturnError(errors.New("relation does not exist"))
+			},
+			wantErr: "failed to scan: relation does not exist",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			tc.makeMock(mock)
+
+			logger := logger.NewBasicLogger(nil, "test", "database")
+
+			database := database.New(db, logger)
+
+			// Act
+			got, err := database.SchemaVersion(context.Background())
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			if tc.wantErr == "" {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.Equal(t, tc.wantErr, err.Error(), "result does not match")
+			}
+		})
+	}
+}
+
+func Test_Database_Stats(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		pool bool
+		want repository.DBStats
+	}{
+		"success: sql.DB": {
+			// sqlmock opens a connection when it is created.
+			want: repository.DBStats{
+				MaxOpenConnections: 3,
+				OpenConnections:    1,
+				Idle:               1,
+			},
+		},
+		"success: pgxpool": {
+			pool: true,
+			want: repository.DBStats{
+				MaxOpenConnections: 5,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			db, _, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			db.SetMaxOpenConns(3)
+
+			var opts []database.Option
+			if tc.pool {
+				// pgxpool does not connect until a connection is acquired.
+				cfg, err := pgxpool.ParseConfig("host=localhost port=5432 user=test dbname=test pool_max_conns=5")
+				require.NoError(t, err, "error should be nil")
+
+				pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+				require.NoError(t, err, "error should be nil")
+				defer pool.Close()
+
+				opts = append(opts, database.WithPgxPool(pool))
+			}
+
+			logger := logger.NewBasicLogger(nil, "test", "database")
+
+			database := database.New(db, logger, opts...)
+
+			// Act
+			got := database.Stats()
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+)
+
+const (
+	// Error code 23505 means 'unique_violation' error in PostgreSQL.
+	uniqueViolationCode = "23505"
+
+	// Constraint names of the UNIQUE columns in init.sql.
+	urlUniqueConstraint   = "shorturl_domain_dedup_key"
+	shortUniqueConstraint = "shorturl_domain_short_key"
+)
+
+// translateError converts the driver specific errors into the domain errors.
+// The original error is kept in the chain for logging.
+func translateError(err error) error {
+	code, constraint := pgErrorDetail(err)
+	if code != uniqueViolationCode {
+		return err
+	}
+
+	switch constraint {
+	case urlUniqueConstraint:
+		return fmt.Errorf("%w: %w", repository.ErrURLAlreadyExists, err)
+	case shortUniqueConstraint:
+		return fmt.Errorf("%w: %w", repository.ErrShortURLAlreadyExists, err)
+	default:
+		return err
+	}
+}
+
+// pgErrorDetail returns the error code and the constraint name of PostgreSQL
+// from the error of either lib/pq or pgx.
+func pgErrorDetail(err error) (string, string) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code), pqErr.Constraint
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code, pgErr.ConstraintName
+	}
+
+	return "", ""
+}
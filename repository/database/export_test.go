@@ -2,6 +2,17 @@ package database
 
 var (
 	SearchURLFromShortURLStmt = searchURLFromShortURLStmt
-	SelectShortURLStmt        = selectShortURLStmt
+	SelectURLStmt             = selectURLStmt
+	SelectURLByDedupKeyStmt   = selectURLByDedupKeyStmt
+	ListURLsStmt              = listURLsStmt
+	UpdateURLStmt             = updateURLStmt
+	ConsumeClickStmt          = consumeClickStmt
+	DeleteURLTagsStmt         = deleteURLTagsStmt
+	InsertTagsStmt            = insertTagsStmt
+	InsertURLTagsStmt         = insertURLTagsStmt
+	ListTagsStmt              = listTagsStmt
+	UpsertPageMetadataStmt    = upsertPageMetadataStmt
+	ListPageMetadataStmt      = listPageMetadataStmt
 	InsertURLStmt             = insertURLStmt
+	SelectSchemaVersionStmt   = selectSchemaVersionStmt
 )
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+type pageMetadataRepo struct {
+	extractRWTx func(transaction.RWTx) (*RwTx, error)
+}
+
+func NewPageMetadataRepo(
+	extractRWTx func(transaction.RWTx) (*RwTx, error),
+) repository.PageMetadataRepository {
+	return &pageMetadataRepo{
+		extractRWTx: extractRWTx,
+	}
+}
+
+const upsertPageMetadataStmt = `
+INSERT INTO page_metadata (
+	shorturl_id,
+	title,
+	description,
+	image_url,
+	fetched_at
+) VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5
+)
+ON CONFLICT (shorturl_id) DO UPDATE SET
+	title = EXCLUDED.title,
+	description = EXCLUDED.description,
+	image_url = EXCLUDED.image_url,
+	fetched_at = EXCLUDED.fetched_at;
+`
+
+func (p *pageMetadataRepo) UpsertPageMetadata(
+	ctx context.Context, ttx transaction.RWTx, urlID int, page repository.PageMetadata,
+) error {
+	ctx, span := tracing.Start(ctx, "p.UpsertPageMetadata")
+	defer span.End()
+
+	tx, err := p.extractRWTx(ttx)
+	if err != nil {
+		return fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx, upsertPageMetadataStmt,
+		urlID, page.Title, page.Description, page.ImageURL, page.FetchedAt,
+	); err != nil {
+		return fmt.Errorf("failed to upsert: %w", err)
+	}
+
+	return nil
+}
+
+const listPageMetadataStmt = `
+SELECT
+	shorturl_id,
+	title,
+	description,
+	image_url,
+	fetched_at
+FROM page_metadata
+WHERE shorturl_id = ANY($1::INTEGER[]);
+`
+
+func (p *pageMetadataRepo) ListPageMetadata(
+	ctx context.Context, ttx transaction.RWTx, urlIDs []int,
+) (map[int]repository.PageMetadata, error) {
+	ctx, span := tracing.Start(ctx, "p.ListPageMetadata")
+	defer span.End()
+
+	tx, err := p.extractRWTx(ttx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	// pq.Array は []int に対応していない。
+	ids := make([]int64, 0, len(urlIDs))
+	for _, id := range urlIDs {
+		ids = append(ids, int64(id))
+	}
+
+	rows, err := tx.QueryContext(ctx, listPageMetadataStmt, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+
+	pages := map[int]repository.PageMetadata{}
+
+	for rows.Next() {
+		var (
+			id   int
+			page repository.PageMetadata
+		)
+		if err := rows.Scan(&id, &page.Title, &page.Description, &page.ImageURL, &page.FetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+
+		pages[id] = page
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	return pages, nil
+}
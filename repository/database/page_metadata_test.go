@@ -0,0 +1,82 @@
+package database_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/database"
+)
+
+func Test_Database_UpsertPageMetadata(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	fetchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "error of sqlmock.New should be nil")
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(regexp.QuoteMeta(database.UpsertPageMetadataStmt)).
+		WithArgs(1, "Example", "An example page.", "https://example.com/og.png", fetchedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err, "error of BeginTx should be nil")
+
+	pageRepo := database.NewPageMetadataRepo(database.ExtractRWTx)
+
+	// Act
+	err = pageRepo.UpsertPageMetadata(context.Background(), &database.RwTx{tx}, 1, repository.PageMetadata{
+		Title:       "Example",
+		Description: "An example page.",
+		ImageURL:    "https://example.com/og.png",
+		FetchedAt:   fetchedAt,
+	})
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.NoError(t, mock.ExpectationsWereMet(), "all expectations should be met")
+}
+
+func Test_Database_ListPageMetadata(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	fetchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "error of sqlmock.New should be nil")
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(regexp.QuoteMeta(database.ListPageMetadataStmt)).
+		WithArgs(pq.Array([]int64{1, 2})).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"shorturl_id", "title", "description", "image_url", "fetched_at"}).
+				AddRow(1, "Example", "", "", fetchedAt),
+		)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err, "error of BeginTx should be nil")
+
+	pageRepo := database.NewPageMetadataRepo(database.ExtractRWTx)
+
+	// Act
+	got, err := pageRepo.ListPageMetadata(context.Background(), &database.RwTx{tx}, []int{1, 2})
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.Equal(t, map[int]repository.PageMetadata{1: {Title: "Example", FetchedAt: fetchedAt}}, got, "result does not match")
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+type tagRepo struct {
+	extractRWTx func(transaction.RWTx) (*RwTx, error)
+}
+
+func NewTagRepo(
+	extractRWTx func(transaction.RWTx) (*RwTx, error),
+) repository.TagRepository {
+	return &tagRepo{
+		extractRWTx: extractRWTx,
+	}
+}
+
+const deleteURLTagsStmt = `
+DELETE FROM shorturl_tags
+WHERE shorturl_id = $1;
+`
+
+const insertTagsStmt = `
+INSERT INTO tags (
+	name
+)
+SELECT
+	unnest($1::TEXT[])
+ON CONFLICT (name) DO NOTHING;
+`
+
+const insertURLTagsStmt = `
+INSERT INTO shorturl_tags (
+	shorturl_id,
+	tag_id
+)
+SELECT
+	$1,
+	id
+FROM tags
+WHERE name = ANY($2::TEXT[]);
+`
+
+func (t *tagRepo) SetTags(ctx context.Context, ttx transaction.RWTx, urlID int, tags []string) error {
+	ctx, span := tracing.Start(ctx, "t.SetTags")
+	defer span.End()
+
+	tx, err := t.extractRWTx(ttx)
+	if err != nil {
+		return fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteURLTagsStmt, urlID); err != nil {
+		return fmt.Errorf("failed to delete tags of url: %w", err)
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, insertTagsStmt, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to insert tags: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, insertURLTagsStmt, urlID, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to insert tags of url: %w", err)
+	}
+
+	return nil
+}
+
+const listTagsStmt = `
+SELECT
+	st.shorturl_id,
+	t.name
+FROM shorturl_tags st
+JOIN tags t ON t.id = st.tag_id
+WHERE st.shorturl_id = ANY($1::INTEGER[])
+ORDER BY t.name;
+`
+
+func (t *tagRepo) ListTags(ctx context.Context, ttx transaction.RWTx, urlIDs []int) (map[int][]string, error) {
+	ctx, span := tracing.Start(ctx, "t.ListTags")
+	defer span.End()
+
+	tx, err := t.extractRWTx(ttx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	// pq.Array は []int に対応していない。
+	ids := make([]int64, 0, len(urlIDs))
+	for _, id := range urlIDs {
+		ids = append(ids, int64(id))
+	}
+
+	rows, err := tx.QueryContext(ctx, listTagsStmt, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+
+	tags := map[int][]string{}
+
+	for rows.Next() {
+		var (
+			id   int
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+
+		tags[id] = append(tags[id], name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,111 @@
+package database_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/repository/database"
+)
+
+func Test_Database_SetTags(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		tags     []string
+		makeMock func(m sqlmock.Sqlmock)
+	}{
+		"success": {
+			tags: []string{"go", "news"},
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectExec(regexp.QuoteMeta(database.DeleteURLTagsStmt)).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.
+					ExpectExec(regexp.QuoteMeta(database.InsertTagsStmt)).
+					WithArgs(pq.Array([]string{"go", "news"})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.
+					ExpectExec(regexp.QuoteMeta(database.InsertURLTagsStmt)).
+					WithArgs(1, pq.Array([]string{"go", "news"})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		"success: clear": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				// タグが空の場合は削除のみ行われること。
+				m.
+					ExpectExec(regexp.QuoteMeta(database.DeleteURLTagsStmt)).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err, "error of sqlmock.New should be nil")
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tc.makeMock(mock)
+
+			tx, err := db.BeginTx(context.Background(), nil)
+			require.NoError(t, err, "error of BeginTx should be nil")
+
+			tagRepo := database.NewTagRepo(database.ExtractRWTx)
+
+			// Act
+			err = tagRepo.SetTags(context.Background(), &database.RwTx{tx}, 1, tc.tags)
+
+			// Assert
+			require.NoError(t, err, "error should be nil")
+			assert.NoError(t, mock.ExpectationsWereMet(), "all expectations should be met")
+		})
+	}
+}
+
+func Test_Database_ListTags(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "error of sqlmock.New should be nil")
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(regexp.QuoteMeta(database.ListTagsStmt)).
+		WithArgs(pq.Array([]int64{1, 2})).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"shorturl_id", "name"}).
+				AddRow(1, "go").
+				AddRow(2, "go").
+				AddRow(1, "news"),
+		)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err, "error of BeginTx should be nil")
+
+	tagRepo := database.NewTagRepo(database.ExtractRWTx)
+
+	// Act
+	got, err := tagRepo.ListTags(context.Background(), &database.RwTx{tx}, []int{1, 2})
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.Equal(t, map[int][]string{1: {"go", "news"}, 2: {"go"}}, got, "result does not match")
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
 )
 
 type txManager struct {
@@ -26,8 +28,13 @@ func (t *txManager) ReadWriteTransaction(
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
 
+	start := time.Now()
+
 	defer func() {
 		if err != nil {
+			metrics.TxRollbacksTotal.Inc()
+			metrics.TxDuration.WithLabelValues(metrics.ResultRollback).Observe(time.Since(start).Seconds())
+
 			if e := tx.Rollback(); e != nil {
 				err = fmt.Errorf("failed to rollback tx: %w", e)
 			}
@@ -37,7 +44,15 @@ func (t *txManager) ReadWriteTransaction(
 
 		if e := tx.Commit(); e != nil {
 			err = fmt.Errorf("failed to commit tx: %w", e)
+
+			// commit に失敗した変更は反映されないため、rollback としても数える。
+			metrics.TxRollbacksTotal.Inc()
+			metrics.TxDuration.WithLabelValues(metrics.ResultError).Observe(time.Since(start).Seconds())
+
+			return
 		}
+
+		metrics.TxDuration.WithLabelValues(metrics.ResultCommit).Observe(time.Since(start).Seconds())
 	}()
 
 	if err = f(ctx, &RwTx{tx}); err != nil {
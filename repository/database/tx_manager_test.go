@@ -6,11 +6,13 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 	"github.com/kokoichi206-sandbox/url-shortener/repository/database"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
 )
 
 func Test_Database_NewTxManager(t *testing.T) {
@@ -123,3 +125,59 @@ func Test_Database_NewTxManager(t *testing.T) {
 		})
 	}
 }
+
+// global の metrics を比較するため、他のテストと並列に実行しない。
+//
+//nolint:paralleltest
+func Test_Database_NewTxManager_CommitErrorMetrics(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("commit error"))
+
+	txManager := database.NewTxManager(db)
+
+	rollbacks := testutil.ToFloat64(metrics.TxRollbacksTotal)
+	commits, errs := txDurationCount(t, metrics.ResultCommit), txDurationCount(t, metrics.ResultError)
+
+	// Act
+	err = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		return nil
+	})
+
+	// Assert
+	require.Error(t, err, "commit should fail")
+	// commit に失敗したトランザクションは、commit として数えないこと。
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.TxRollbacksTotal)-rollbacks, 0, "rollback count should be equal")
+	assert.Equal(t, commits, txDurationCount(t, metrics.ResultCommit), "commit count should not change")
+	assert.Equal(t, errs+1, txDurationCount(t, metrics.ResultError), "error count should be equal")
+}
+
+// txDurationCount returns the number of the transactions observed with the result.
+func txDurationCount(t *testing.T, result string) uint64 {
+	t.Helper()
+
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+
+	for _, f := range families {
+		if f.GetName() != "urlshortener_db_transaction_duration_seconds" {
+			continue
+		}
+
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "result" && l.GetValue() == result {
+					return m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+
+	return 0
+}
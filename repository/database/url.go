@@ -6,36 +6,44 @@ import (
 	"errors"
 	"fmt"
 
-	tracer "github.com/opentracing/opentracing-go"
+	"github.com/lib/pq"
 
 	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
 )
 
 const searchURLFromShortURLStmt = `
 SELECT
-	url
-FROM shorturl
-WHERE short = $1;
+	s.id,
+	s.url,
+	s.password_hash,
+	s.max_clicks,
+	s.clicks
+FROM shorturl s
+JOIN domains d ON d.id = s.domain_id
+WHERE d.host = $1 AND s.short = $2;
 `
 
-func (d *database) SearchURLFromShortURL(ctx context.Context, shortURL string) (string, error) {
-	span, ctx := tracer.StartSpanFromContext(ctx, "d.SearchURLFromShortURL")
-	defer span.Finish()
+func (d *database) SearchURLFromShortURL(
+	ctx context.Context, domain, shortURL string,
+) (repository.Destination, error) {
+	ctx, span := tracing.Start(ctx, "d.SearchURLFromShortURL")
+	defer span.End()
 
-	row := d.db.QueryRowContext(ctx, searchURLFromShortURLStmt, shortURL)
+	row := d.db.QueryRowContext(ctx, searchURLFromShortURLStmt, domain, shortURL)
 
-	var url string
-	if err := row.Scan(&url); err != nil {
+	var dest repository.Destination
+	if err := row.Scan(&dest.ID, &dest.URL, &dest.PasswordHash, &dest.MaxClicks, &dest.Clicks); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", apperr.ErrShortURLNotFound
+			return repository.Destination{}, apperr.ErrShortURLNotFound
 		}
 
-		return "", fmt.Errorf("failed to scan: %w", err)
+		return repository.Destination{}, fmt.Errorf("failed to scan: %w", err)
 	}
 
-	return url, nil
+	return dest, nil
 }
 
 type urlRepo struct {
@@ -50,58 +58,289 @@ func NewURLRepo(
 	}
 }
 
-const selectShortURLStmt = `
+const selectURLStmt = `
+SELECT
+	s.id,
+	s.url,
+	s.short,
+	s.owner,
+	COALESCE(s.dedup_key, ''),
+	s.title,
+	s.notes,
+	s.password_hash,
+	s.max_clicks,
+	s.clicks,
+	s.created_at
+FROM shorturl s
+JOIN domains d ON d.id = s.domain_id
+WHERE d.host = $1 AND s.short = $2;
+`
+
+func (u *urlRepo) SelectURL(
+	ctx context.Context, ttx transaction.RWTx, domain, shortURL string,
+) (repository.URL, error) {
+	ctx, span := tracing.Start(ctx, "u.SelectURL")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return repository.URL{}, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	row := tx.QueryRowContext(ctx, selectURLStmt, domain, shortURL)
+
+	url := repository.URL{Domain: domain}
+	if err := row.Scan(
+		&url.ID, &url.URL, &url.Short, &url.Owner, &url.DedupKey, &url.Title, &url.Notes, &url.PasswordHash,
+		&url.MaxClicks, &url.Clicks, &url.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.URL{}, apperr.ErrShortURLNotFound
+		}
+
+		return repository.URL{}, fmt.Errorf("failed to scan: %w", err)
+	}
+
+	return url, nil
+}
+
+const selectURLByDedupKeyStmt = `
 SELECT
-	short
-FROM shorturl
-WHERE url = $1;
+	s.id,
+	s.url,
+	s.short,
+	s.owner,
+	s.title,
+	s.notes,
+	s.password_hash,
+	s.max_clicks,
+	s.clicks,
+	s.created_at
+FROM shorturl s
+JOIN domains d ON d.id = s.domain_id
+WHERE d.host = $1 AND s.dedup_key = $2;
 `
 
-func (u *urlRepo) SelectShortURL(ctx context.Context, ttx transaction.RWTx, originalURL string) (string, error) {
-	span, ctx := tracer.StartSpanFromContext(ctx, "u.SelectShortURL")
-	defer span.Finish()
+func (u *urlRepo) SelectURLByDedupKey(
+	ctx context.Context, ttx transaction.RWTx, domain, dedupKey string,
+) (repository.URL, error) {
+	ctx, span := tracing.Start(ctx, "u.SelectURLByDedupKey")
+	defer span.End()
 
 	tx, err := u.extractRWTx(ttx)
 	if err != nil {
-		return "", fmt.Errorf("failed to extract tx: %w", err)
+		return repository.URL{}, fmt.Errorf("failed to extract tx: %w", err)
 	}
 
-	row := tx.QueryRowContext(ctx, selectShortURLStmt, originalURL)
+	row := tx.QueryRowContext(ctx, selectURLByDedupKeyStmt, domain, dedupKey)
 
-	var shortURL string
-	if err := row.Scan(&shortURL); err != nil {
+	url := repository.URL{Domain: domain, DedupKey: dedupKey}
+	if err := row.Scan(
+		&url.ID, &url.URL, &url.Short, &url.Owner, &url.Title, &url.Notes, &url.PasswordHash,
+		&url.MaxClicks, &url.Clicks, &url.CreatedAt,
+	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", apperr.ErrShortURLNotFound
+			return repository.URL{}, apperr.ErrShortURLNotFound
 		}
 
-		return "", fmt.Errorf("failed to scan: %w", err)
+		return repository.URL{}, fmt.Errorf("failed to scan: %w", err)
+	}
+
+	return url, nil
+}
+
+// listURLsStmt matches the urls which have all the tags of $3.
+const listURLsStmt = `
+SELECT
+	s.id,
+	d.host,
+	s.url,
+	s.short,
+	s.owner,
+	COALESCE(s.dedup_key, ''),
+	s.title,
+	s.notes,
+	s.password_hash,
+	s.max_clicks,
+	s.clicks,
+	s.created_at
+FROM shorturl s
+JOIN domains d ON d.id = s.domain_id
+WHERE ($1 = '' OR d.host = $1)
+	AND ($2 = '' OR s.owner = $2)
+	AND (cardinality($3::TEXT[]) = 0 OR s.id IN (
+		SELECT st.shorturl_id
+		FROM shorturl_tags st
+		JOIN tags t ON t.id = st.tag_id
+		WHERE t.name = ANY($3::TEXT[])
+		GROUP BY st.shorturl_id
+		HAVING COUNT(*) = cardinality($3::TEXT[])
+	))
+ORDER BY s.id DESC
+LIMIT NULLIF($4, 0)
+OFFSET $5;
+`
+
+func (u *urlRepo) ListURLs(
+	ctx context.Context, ttx transaction.RWTx, filter repository.URLFilter,
+) ([]repository.URL, error) {
+	ctx, span := tracing.Start(ctx, "u.ListURLs")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx, listURLsStmt,
+		filter.Domain, filter.Owner, pq.Array(filter.Tags), filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+
+	urls := []repository.URL{}
+
+	for rows.Next() {
+		var url repository.URL
+		if err := rows.Scan(
+			&url.ID, &url.Domain, &url.URL, &url.Short, &url.Owner, &url.DedupKey, &url.Title, &url.Notes, &url.PasswordHash,
+			&url.MaxClicks, &url.Clicks, &url.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+
+		urls = append(urls, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
 	}
 
-	return shortURL, nil
+	return urls, nil
 }
 
+// insertURLStmt inserts nothing (and returns no rows) if the domain is not registered.
+// The empty dedup key is stored as NULL, which never conflicts.
 const insertURLStmt = `
 INSERT INTO shorturl (
+	domain_id,
 	url,
-	short
-) VALUES (
-	$1,
-	$2
-);
+	short,
+	owner,
+	dedup_key,
+	title,
+	notes,
+	password_hash,
+	max_clicks
+)
+SELECT
+	id,
+	$2,
+	$3,
+	$4,
+	NULLIF($5, ''),
+	$6,
+	$7,
+	$8,
+	$9
+FROM domains
+WHERE host = $1
+RETURNING
+	id,
+	created_at;
 `
 
-func (u *urlRepo) InsertURL(ctx context.Context, ttx transaction.RWTx, originalURL string, shortURL string) error {
-	span, ctx := tracer.StartSpanFromContext(ctx, "t.InsertURL")
-	defer span.Finish()
+func (u *urlRepo) InsertURL(ctx context.Context, ttx transaction.RWTx, url repository.URL) (repository.URL, error) {
+	ctx, span := tracing.Start(ctx, "t.InsertURL")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return repository.URL{}, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	row := tx.QueryRowContext(
+		ctx, insertURLStmt,
+		url.Domain, url.URL, url.Short, url.Owner, url.DedupKey, url.Title, url.Notes, url.PasswordHash, url.MaxClicks,
+	)
+
+	if err := row.Scan(&url.ID, &url.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.URL{}, apperr.ErrDomainNotFound
+		}
+
+		return repository.URL{}, fmt.Errorf("failed to insert: %w", translateError(err))
+	}
+
+	return url, nil
+}
+
+const updateURLStmt = `
+UPDATE shorturl
+SET
+	title = $2,
+	notes = $3,
+	password_hash = $4,
+	dedup_key = NULLIF($5, '')
+WHERE id = $1;
+`
+
+func (u *urlRepo) UpdateURL(ctx context.Context, ttx transaction.RWTx, url repository.URL) error {
+	ctx, span := tracing.Start(ctx, "u.UpdateURL")
+	defer span.End()
 
 	tx, err := u.extractRWTx(ttx)
 	if err != nil {
 		return fmt.Errorf("failed to extract tx: %w", err)
 	}
 
-	if _, err := tx.ExecContext(ctx, insertURLStmt, originalURL, shortURL); err != nil {
-		return fmt.Errorf("failed to insert: %w", err)
+	res, err := tx.ExecContext(ctx, updateURLStmt, url.ID, url.Title, url.Notes, url.PasswordHash, url.DedupKey)
+	if err != nil {
+		return fmt.Errorf("failed to update: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return apperr.ErrShortURLNotFound
 	}
 
 	return nil
 }
+
+// consumeClickStmt counts the click only if any click is left.
+// The concurrent updates of the same row wait for each other, and the later one re-checks the condition,
+// so that the clicks never exceed max_clicks.
+const consumeClickStmt = `
+UPDATE shorturl
+SET clicks = clicks + 1
+WHERE id = $1 AND clicks < max_clicks
+RETURNING max_clicks - clicks;
+`
+
+func (u *urlRepo) ConsumeClick(ctx context.Context, ttx transaction.RWTx, id int) (int, error) {
+	ctx, span := tracing.Start(ctx, "u.ConsumeClick")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	var left int
+	if err := tx.QueryRowContext(ctx, consumeClickStmt, id).Scan(&left); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, apperr.ErrShortURLExhausted
+		}
+
+		return 0, fmt.Errorf("failed to update: %w", err)
+	}
+
+	return left, nil
+}
@@ -3,15 +3,18 @@ package database_test
 import (
 	"context"
 	"database/sql"
-	"database/sql/driver"
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
 	"github.com/kokoichi206-sandbox/url-shortener/repository/database"
@@ -22,50 +25,54 @@ func Test_Database_SearchURLFromShortURL(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
+		domain   string
 		shortURL string
 	}
 
 	testCases := map[string]struct {
 		args     args
 		makeMock func(m sqlmock.Sqlmock)
-		want     string
+		want     repository.Destination
 		wantErr  string
 	}{
 		"success": {
 			args: args{
+				domain:   "localhost",
 				shortURL: "R0D",
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.
 					ExpectQuery(regexp.QuoteMeta(database.SearchURLFromShortURLStmt)).
-					WithArgs("R0D").
+					WithArgs("localhost", "R0D").
 					WillReturnRows(
-						sqlmock.NewRows([]string{"url"}).
-							AddRow("https://example.com"),
+						sqlmock.NewRows([]string{"id", "url", "password_hash", "max_clicks", "clicks"}).
+							AddRow(1, "https://example.com", "hash", 3, 1),
 					)
 			},
-			want: "https://example.com",
+			want: repository.Destination{ID: 1, URL: "https://example.com", PasswordHash: "hash", MaxClicks: 3, Clicks: 1},
 		},
 		"failure: no row found": {
 			args: args{
+				domain:   "localhost",
 				shortURL: "R0D",
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.
 					ExpectQuery(regexp.QuoteMeta(database.SearchURLFromShortURLStmt)).
-					WithArgs("R0D").
+					WithArgs("localhost", "R0D").
 					WillReturnError(sql.ErrNoRows)
 			},
 			wantErr: apperr.ErrShortURLNotFound.Error(),
 		},
 		"failure: scan error": {
 			args: args{
+				domain:   "localhost",
 				shortURL: "R0D",
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.
 					ExpectQuery(regexp.QuoteMeta(database.SearchURLFromShortURLStmt)).
-					WithArgs("R0D").
+					WithArgs("localhost", "R0D").
 					WillReturnError(errors.New("scan error"))
 			},
 			wantErr: "failed to scan: scan error",
@@ -93,7 +100,7 @@ func Test_Database_SearchURLFromShortURL(t *testing.T) {
 			database := database.New(db, logger)
 
 			// Act
-			got, err := database.SearchURLFromShortURL(context.Background(), tc.args.shortURL)
+			got, err := database.SearchURLFromShortURL(context.Background(), tc.args.domain, tc.args.shortURL)
 
 			// Assert
 			assert.Equal(t, tc.want, got, "result does not match")
@@ -106,32 +113,36 @@ func Test_Database_SearchURLFromShortURL(t *testing.T) {
 	}
 }
 
-func Test_Database_SelectShortURL(t *testing.T) {
+func Test_Database_SelectURLByDedupKey(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
-		originalURL string
+		domain   string
+		dedupKey string
 	}
 
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
 	testCases := map[string]struct {
 		args            args
 		makeMock        func(m sqlmock.Sqlmock)
 		makeExtractRWTx func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error)
-		want            string
+		want            repository.URL
 		wantErr         string
 	}{
 		"success": {
 			args: args{
-				originalURL: "https://example.com",
+				domain:   "localhost",
+				dedupKey: "https://example.com",
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.
-					ExpectQuery(regexp.QuoteMeta(database.SelectShortURLStmt)).
-					WithArgs("https://example.com").
+					ExpectQuery(regexp.QuoteMeta(database.SelectURLByDedupKeyStmt)).
+					WithArgs("localhost", "https://example.com").
 					WillReturnRows(
-						sqlmock.NewRows([]string{"short"}).
-							AddRow("R0D"),
+						sqlmock.NewRows([]string{"id", "url", "short", "owner", "title", "notes", "password_hash", "max_clicks", "clicks", "created_at"}).
+							AddRow(1, "https://example.com", "R0D", "team-a", "Example", "", "", 0, 0, createdAt),
 					)
 			},
 			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
@@ -139,20 +150,30 @@ func Test_Database_SelectShortURL(t *testing.T) {
 					return &database.RwTx{sqlTx}, nil
 				}
 			},
-			want: "R0D",
+			want: repository.URL{
+				ID:        1,
+				Domain:    "localhost",
+				URL:       "https://example.com",
+				Short:     "R0D",
+				Owner:     "team-a",
+				DedupKey:  "https://example.com",
+				Title:     "Example",
+				CreatedAt: createdAt,
+			},
 		},
 		"failure: extract rwtx": {
 			args: args{
-				originalURL: "https://example.com",
+				domain:   "localhost",
+				dedupKey: "https://example.com",
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.
-					ExpectQuery(regexp.QuoteMeta(database.SelectShortURLStmt)).
-					WithArgs("https://example.com").
+					ExpectQuery(regexp.QuoteMeta(database.SelectURLByDedupKeyStmt)).
+					WithArgs("localhost", "https://example.com").
 					WillReturnRows(
-						sqlmock.NewRows([]string{"short"}).
-							AddRow("R0D"),
+						sqlmock.NewRows([]string{"id", "url", "short", "owner", "title", "notes", "password_hash", "max_clicks", "clicks", "created_at"}).
+							AddRow(1, "https://example.com", "R0D", "team-a", "Example", "", "", 0, 0, createdAt),
 					)
 			},
 			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
@@ -164,13 +185,14 @@ func Test_Database_SelectShortURL(t *testing.T) {
 		},
 		"failure: no row found": {
 			args: args{
-				originalURL: "https://wtf.example.com",
+				domain:   "localhost",
+				dedupKey: "https://wtf.example.com",
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.
-					ExpectQuery(regexp.QuoteMeta(database.SelectShortURLStmt)).
-					WithArgs("https://wtf.example.com").
+					ExpectQuery(regexp.QuoteMeta(database.SelectURLByDedupKeyStmt)).
+					WithArgs("localhost", "https://wtf.example.com").
 					WillReturnError(sql.ErrNoRows)
 			},
 			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
@@ -182,13 +204,14 @@ func Test_Database_SelectShortURL(t *testing.T) {
 		},
 		"failure: scan error": {
 			args: args{
-				originalURL: "https://example.com",
+				domain:   "localhost",
+				dedupKey: "https://example.com",
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.
-					ExpectQuery(regexp.QuoteMeta(database.SelectShortURLStmt)).
-					WithArgs("https://example.com").
+					ExpectQuery(regexp.QuoteMeta(database.SelectURLByDedupKeyStmt)).
+					WithArgs("localhost", "https://example.com").
 					WillReturnError(errors.New("scan error"))
 			},
 			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
@@ -225,7 +248,7 @@ func Test_Database_SelectShortURL(t *testing.T) {
 			urlRepo := database.NewURLRepo(tc.makeExtractRWTx(tx))
 
 			// Act
-			got, err := urlRepo.SelectShortURL(context.Background(), rwt, tc.args.originalURL)
+			got, err := urlRepo.SelectURLByDedupKey(context.Background(), rwt, tc.args.domain, tc.args.dedupKey)
 
 			// Assert
 			assert.Equal(t, tc.want, got, "result does not match")
@@ -242,46 +265,67 @@ func Test_Database_InsertURL(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
-		originalURL string
-		shortURL    string
+		url repository.URL
 	}
 
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
 	testCases := map[string]struct {
 		args            args
 		makeMock        func(m sqlmock.Sqlmock)
 		makeExtractRWTx func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error)
-		want            string
+		want            repository.URL
 		wantErr         string
+		wantErrIs       error
 	}{
 		"success": {
 			args: args{
-				originalURL: "https://example.com",
-				shortURL:    "R0D",
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.
-					ExpectExec(regexp.QuoteMeta(database.InsertURLStmt)).
-					WithArgs("https://example.com", "R0D").
-					WillReturnResult(driver.RowsAffected(1))
+					ExpectQuery(regexp.QuoteMeta(database.InsertURLStmt)).
+					WithArgs("localhost", "https://example.com", "R0D", "", "https://example.com", "", "", "", 0).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "created_at"}).
+							AddRow(1, createdAt),
+					)
 			},
 			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
 				return func(r transaction.RWTx) (*database.RwTx, error) {
 					return &database.RwTx{sqlTx}, nil
 				}
 			},
+			want: repository.URL{
+				ID:        1,
+				Domain:    "localhost",
+				URL:       "https://example.com",
+				Short:     "R0D",
+				DedupKey:  "https://example.com",
+				CreatedAt: createdAt,
+			},
 		},
 		"failure: extract rwtx": {
 			args: args{
-				originalURL: "https://example.com",
-				shortURL:    "R0D",
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.
-					ExpectExec(regexp.QuoteMeta(database.InsertURLStmt)).
-					WithArgs("https://example.com", "R0D").
-					WillReturnResult(driver.RowsAffected(1))
+					ExpectQuery(regexp.QuoteMeta(database.InsertURLStmt)).
+					WithArgs("localhost", "https://example.com", "R0D", "", "https://example.com", "", "", "", 0).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
 			},
 			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
 				return func(r transaction.RWTx) (*database.RwTx, error) {
@@ -292,14 +336,18 @@ func Test_Database_InsertURL(t *testing.T) {
 		},
 		"failure: exec error": {
 			args: args{
-				originalURL: "https://example.com",
-				shortURL:    "R0D",
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
 			},
 			makeMock: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.
-					ExpectExec(regexp.QuoteMeta(database.InsertURLStmt)).
-					WithArgs("https://example.com", "R0D").
+					ExpectQuery(regexp.QuoteMeta(database.InsertURLStmt)).
+					WithArgs("localhost", "https://example.com", "R0D", "", "https://example.com", "", "", "", 0).
 					WillReturnError(errors.New("exec error"))
 			},
 			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
@@ -309,6 +357,98 @@ func Test_Database_InsertURL(t *testing.T) {
 			},
 			wantErr: "failed to insert: exec error",
 		},
+		"failure: domain not found": {
+			args: args{
+				url: repository.URL{
+					Domain:   "wtf.example.com",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
+			},
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.InsertURLStmt)).
+					WithArgs("wtf.example.com", "https://example.com", "R0D", "", "https://example.com", "", "", "", 0).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+			},
+			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
+				return func(r transaction.RWTx) (*database.RwTx, error) {
+					return &database.RwTx{sqlTx}, nil
+				}
+			},
+			wantErrIs: apperr.ErrDomainNotFound,
+		},
+		"failure: duplicate url": {
+			args: args{
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
+			},
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.InsertURLStmt)).
+					WithArgs("localhost", "https://example.com", "R0D", "", "https://example.com", "", "", "", 0).
+					WillReturnError(&pq.Error{Code: "23505", Constraint: "shorturl_domain_dedup_key"})
+			},
+			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
+				return func(r transaction.RWTx) (*database.RwTx, error) {
+					return &database.RwTx{sqlTx}, nil
+				}
+			},
+			wantErrIs: repository.ErrURLAlreadyExists,
+		},
+		"failure: duplicate short url": {
+			args: args{
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
+			},
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.InsertURLStmt)).
+					WithArgs("localhost", "https://example.com", "R0D", "", "https://example.com", "", "", "", 0).
+					WillReturnError(&pq.Error{Code: "23505", Constraint: "shorturl_domain_short_key"})
+			},
+			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
+				return func(r transaction.RWTx) (*database.RwTx, error) {
+					return &database.RwTx{sqlTx}, nil
+				}
+			},
+			wantErrIs: repository.ErrShortURLAlreadyExists,
+		},
+		"failure: duplicate short url (pgx)": {
+			args: args{
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
+			},
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.InsertURLStmt)).
+					WithArgs("localhost", "https://example.com", "R0D", "", "https://example.com", "", "", "", 0).
+					WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "shorturl_domain_short_key"})
+			},
+			makeExtractRWTx: func(sqlTx *sql.Tx) func(transaction.RWTx) (*database.RwTx, error) {
+				return func(r transaction.RWTx) (*database.RwTx, error) {
+					return &database.RwTx{sqlTx}, nil
+				}
+			},
+			wantErrIs: repository.ErrShortURLAlreadyExists,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -336,9 +476,16 @@ func Test_Database_InsertURL(t *testing.T) {
 			urlRepo := database.NewURLRepo(tc.makeExtractRWTx(tx))
 
 			// Act
-			err = urlRepo.InsertURL(context.Background(), rwt, tc.args.originalURL, tc.args.shortURL)
+			got, err := urlRepo.InsertURL(context.Background(), rwt, tc.args.url)
 
 			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			if tc.wantErrIs != nil {
+				assert.ErrorIs(t, err, tc.wantErrIs, "result does not match")
+
+				return
+			}
+
 			if tc.wantErr == "" {
 				require.NoError(t, err, "error should be nil")
 			} else {
@@ -347,3 +494,241 @@ func Test_Database_InsertURL(t *testing.T) {
 		})
 	}
 }
+
+func Test_Database_SelectURL(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := map[string]struct {
+		makeMock func(m sqlmock.Sqlmock)
+		want     repository.URL
+		wantErr  error
+	}{
+		"success": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.SelectURLStmt)).
+					WithArgs("localhost", "R0D").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "url", "short", "owner", "dedup_key", "title", "notes", "password_hash", "max_clicks", "clicks", "created_at"}).
+							AddRow(1, "https://example.com", "R0D", "", "https://example.com", "Example", "note", "hash", 1, 0, createdAt),
+					)
+			},
+			want: repository.URL{
+				ID:           1,
+				Domain:       "localhost",
+				URL:          "https://example.com",
+				Short:        "R0D",
+				DedupKey:     "https://example.com",
+				Title:        "Example",
+				Notes:        "note",
+				PasswordHash: "hash",
+				MaxClicks:    1,
+				CreatedAt:    createdAt,
+			},
+		},
+		"failure: no row found": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.SelectURLStmt)).
+					WithArgs("localhost", "R0D").
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: apperr.ErrShortURLNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err, "error of sqlmock.New should be nil")
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tc.makeMock(mock)
+
+			tx, err := db.BeginTx(context.Background(), nil)
+			require.NoError(t, err, "error of BeginTx should be nil")
+
+			urlRepo := database.NewURLRepo(database.ExtractRWTx)
+
+			// Act
+			got, err := urlRepo.SelectURL(context.Background(), &database.RwTx{tx}, "localhost", "R0D")
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			if tc.wantErr == nil {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr, "result does not match")
+			}
+		})
+	}
+}
+
+func Test_Database_ListURLs(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "error of sqlmock.New should be nil")
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(regexp.QuoteMeta(database.ListURLsStmt)).
+		WithArgs("localhost", "team-a", pq.Array([]string{"go", "news"}), 10, 20).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "host", "url", "short", "owner", "dedup_key", "title", "notes", "password_hash", "max_clicks", "clicks", "created_at"}).
+				AddRow(2, "localhost", "https://example.org", "R0E", "team-a", "", "", "", "", 0, 0, createdAt).
+				AddRow(1, "localhost", "https://example.com", "R0D", "team-a", "", "Example", "", "", 0, 0, createdAt),
+		)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err, "error of BeginTx should be nil")
+
+	urlRepo := database.NewURLRepo(database.ExtractRWTx)
+
+	// Act
+	got, err := urlRepo.ListURLs(context.Background(), &database.RwTx{tx}, repository.URLFilter{
+		Domain: "localhost",
+		Owner:  "team-a",
+		Tags:   []string{"go", "news"},
+		Limit:  10,
+		Offset: 20,
+	})
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.Equal(t, []repository.URL{
+		{ID: 2, Domain: "localhost", URL: "https://example.org", Short: "R0E", Owner: "team-a", CreatedAt: createdAt},
+		{ID: 1, Domain: "localhost", URL: "https://example.com", Short: "R0D", Owner: "team-a", Title: "Example", CreatedAt: createdAt},
+	}, got, "result does not match")
+	assert.NoError(t, mock.ExpectationsWereMet(), "all expectations should be met")
+}
+
+func Test_Database_UpdateURL(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		rowsAffected int64
+		wantErr      error
+	}{
+		"success": {
+			rowsAffected: 1,
+		},
+		"failure: not found": {
+			rowsAffected: 0,
+			wantErr:      apperr.ErrShortURLNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err, "error of sqlmock.New should be nil")
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.
+				ExpectExec(regexp.QuoteMeta(database.UpdateURLStmt)).
+				WithArgs(1, "Example", "note", "hash", "").
+				WillReturnResult(sqlmock.NewResult(0, tc.rowsAffected))
+
+			tx, err := db.BeginTx(context.Background(), nil)
+			require.NoError(t, err, "error of BeginTx should be nil")
+
+			urlRepo := database.NewURLRepo(database.ExtractRWTx)
+
+			// Act
+			err = urlRepo.UpdateURL(context.Background(), &database.RwTx{tx}, repository.URL{
+				ID: 1, Title: "Example", Notes: "note", PasswordHash: "hash",
+			})
+
+			// Assert
+			if tc.wantErr == nil {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr, "result does not match")
+			}
+		})
+	}
+}
+
+func Test_Database_ConsumeClick(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		makeMock func(m sqlmock.Sqlmock)
+		want     int
+		wantErr  error
+	}{
+		"success": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.ConsumeClickStmt)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"clicks_left"}).AddRow(2))
+			},
+			want: 2,
+		},
+		"failure: exhausted": {
+			makeMock: func(m sqlmock.Sqlmock) {
+				m.
+					ExpectQuery(regexp.QuoteMeta(database.ConsumeClickStmt)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"clicks_left"}))
+			},
+			wantErr: apperr.ErrShortURLExhausted,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err, "error of sqlmock.New should be nil")
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tc.makeMock(mock)
+
+			tx, err := db.BeginTx(context.Background(), nil)
+			require.NoError(t, err, "error of BeginTx should be nil")
+
+			urlRepo := database.NewURLRepo(database.ExtractRWTx)
+
+			// Act
+			got, err := urlRepo.ConsumeClick(context.Background(), &database.RwTx{tx}, 1)
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			if tc.wantErr == nil {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr, "result does not match")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet(), "all expectations should be met")
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+type database struct {
+	store  *Store
+	logger logger.Logger
+}
+
+func New(
+	store *Store, logger logger.Logger,
+) repository.Database {
+	db := &database{
+		store:  store,
+		logger: logger,
+	}
+
+	return db
+}
+
+func (d *database) Health(ctx context.Context) error {
+	return nil
+}
+
+// Stats always returns zero values, since the store has no connection.
+func (d *database) Stats() repository.DBStats {
+	return repository.DBStats{}
+}
+
+// SchemaVersion always returns the expected version, since the store has no schema.
+func (d *database) SchemaVersion(ctx context.Context) (int, error) {
+	return repository.SchemaVersion, nil
+}
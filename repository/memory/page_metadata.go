@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+type pageMetadataRepo struct {
+	extractRWTx func(transaction.RWTx) (*RwTx, error)
+}
+
+func NewPageMetadataRepo(
+	extractRWTx func(transaction.RWTx) (*RwTx, error),
+) repository.PageMetadataRepository {
+	return &pageMetadataRepo{
+		extractRWTx: extractRWTx,
+	}
+}
+
+func (p *pageMetadataRepo) UpsertPageMetadata(
+	ctx context.Context, ttx transaction.RWTx, urlID int, page repository.PageMetadata,
+) error {
+	_, span := tracing.Start(ctx, "p.UpsertPageMetadata")
+	defer span.End()
+
+	tx, err := p.extractRWTx(ttx)
+	if err != nil {
+		return fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	// Mimic the foreign key of init.sql.
+	if _, ok := tx.data.urls[urlID]; !ok {
+		return apperr.ErrShortURLNotFound
+	}
+
+	tx.data.pages[urlID] = page
+
+	return nil
+}
+
+func (p *pageMetadataRepo) ListPageMetadata(
+	ctx context.Context, ttx transaction.RWTx, urlIDs []int,
+) (map[int]repository.PageMetadata, error) {
+	_, span := tracing.Start(ctx, "p.ListPageMetadata")
+	defer span.End()
+
+	tx, err := p.extractRWTx(ttx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	pages := map[int]repository.PageMetadata{}
+
+	for _, id := range urlIDs {
+		if page, ok := tx.data.pages[id]; ok {
+			pages[id] = page
+		}
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,54 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/memory"
+)
+
+func Test_Memory_PageMetadata(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	store := memory.NewStore()
+	seed(t, store, map[string]string{"https://example.com": "R0D"})
+
+	txManager := memory.NewTxManager(store)
+	pageRepo := memory.NewPageMetadataRepo(memory.ExtractRWTx)
+
+	page := repository.PageMetadata{Title: "Example", FetchedAt: time.Now()}
+
+	// Act
+	err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		if err := pageRepo.UpsertPageMetadata(ctx, tx, 1, repository.PageMetadata{Title: "old"}); err != nil {
+			return err
+		}
+
+		return pageRepo.UpsertPageMetadata(ctx, tx, 1, page)
+	})
+
+	notFound := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		return pageRepo.UpsertPageMetadata(ctx, tx, 100, page)
+	})
+
+	var got map[int]repository.PageMetadata
+
+	_ = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		got, err = pageRepo.ListPageMetadata(ctx, tx, []int{1, 2})
+
+		return err
+	})
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.ErrorIs(t, notFound, apperr.ErrShortURLNotFound, "metadata of unknown url should be refused")
+	assert.Equal(t, map[int]repository.PageMetadata{1: page}, got, "result does not match")
+}
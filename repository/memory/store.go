@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+)
+
+// Store is an in-memory replacement of the tables in init.sql.
+// It is meant for tests and demos, and the data is lost when the process exits.
+type Store struct {
+	mu   sync.RWMutex
+	data *data
+}
+
+// data holds every table of the store.
+// A transaction works on a clone of it, which replaces the committed one on commit.
+// byDedupKey and byShort are keyed by domainKey, since they are unique per domain.
+// The slices in tags are replaced instead of modified, so that the clone can share them.
+type data struct {
+	nextID     int
+	domains    map[string]bool
+	urls       map[int]repository.URL
+	byDedupKey map[string]int
+	byShort    map[string]int
+	tags       map[int][]string
+	pages      map[int]repository.PageMetadata
+}
+
+func NewStore() *Store {
+	return &Store{
+		data: &data{
+			nextID:     1,
+			domains:    map[string]bool{},
+			urls:       map[int]repository.URL{},
+			byDedupKey: map[string]int{},
+			byShort:    map[string]int{},
+			tags:       map[int][]string{},
+			pages:      map[int]repository.PageMetadata{},
+		},
+	}
+}
+
+// AddDomain registers the host as a short domain, like inserting into the domains table.
+func (s *Store) AddDomain(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.domains[host] = true
+}
+
+func domainKey(domain, value string) string {
+	return domain + "\x00" + value
+}
+
+func (d *data) clone() *data {
+	c := &data{
+		nextID:     d.nextID,
+		domains:    make(map[string]bool, len(d.domains)),
+		urls:       make(map[int]repository.URL, len(d.urls)),
+		byDedupKey: make(map[string]int, len(d.byDedupKey)),
+		byShort:    make(map[string]int, len(d.byShort)),
+		tags:       make(map[int][]string, len(d.tags)),
+		pages:      make(map[int]repository.PageMetadata, len(d.pages)),
+	}
+
+	for k, v := range d.domains {
+		c.domains[k] = v
+	}
+
+	for k, v := range d.urls {
+		c.urls[k] = v
+	}
+
+	for k, v := range d.byDedupKey {
+		c.byDedupKey[k] = v
+	}
+
+	for k, v := range d.byShort {
+		c.byShort[k] = v
+	}
+
+	for k, v := range d.tags {
+		c.tags[k] = v
+	}
+
+	for k, v := range d.pages {
+		c.pages[k] = v
+	}
+
+	return c
+}
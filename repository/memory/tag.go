@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+type tagRepo struct {
+	extractRWTx func(transaction.RWTx) (*RwTx, error)
+}
+
+func NewTagRepo(
+	extractRWTx func(transaction.RWTx) (*RwTx, error),
+) repository.TagRepository {
+	return &tagRepo{
+		extractRWTx: extractRWTx,
+	}
+}
+
+func (t *tagRepo) SetTags(ctx context.Context, ttx transaction.RWTx, urlID int, tags []string) error {
+	_, span := tracing.Start(ctx, "t.SetTags")
+	defer span.End()
+
+	tx, err := t.extractRWTx(ttx)
+	if err != nil {
+		return fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	if len(tags) == 0 {
+		delete(tx.data.tags, urlID)
+
+		return nil
+	}
+
+	// clone と共有しているため、元の slice は変更しない。
+	sorted := slices.Clone(tags)
+	slices.Sort(sorted)
+	tx.data.tags[urlID] = slices.Compact(sorted)
+
+	return nil
+}
+
+func (t *tagRepo) ListTags(ctx context.Context, ttx transaction.RWTx, urlIDs []int) (map[int][]string, error) {
+	_, span := tracing.Start(ctx, "t.ListTags")
+	defer span.End()
+
+	tx, err := t.extractRWTx(ttx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	tags := map[int][]string{}
+
+	for _, id := range urlIDs {
+		if ts, ok := tx.data.tags[id]; ok {
+			tags[id] = slices.Clone(ts)
+		}
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,71 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/memory"
+)
+
+func Test_Memory_Tags(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	store := memory.NewStore()
+	seed(t, store, map[string]string{"https://example.com": "R0D", "https://example.org": "R0E"})
+
+	txManager := memory.NewTxManager(store)
+	tagRepo := memory.NewTagRepo(memory.ExtractRWTx)
+
+	list := func() map[int][]string {
+		var tags map[int][]string
+
+		err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+			var err error
+			tags, err = tagRepo.ListTags(ctx, tx, []int{1, 2, 3})
+
+			return err
+		})
+		require.NoError(t, err, "error of ListTags should be nil")
+
+		return tags
+	}
+
+	// Act
+	err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		if err := tagRepo.SetTags(ctx, tx, 1, []string{"news", "go", "go"}); err != nil {
+			return err
+		}
+
+		return tagRepo.SetTags(ctx, tx, 2, []string{"go"})
+	})
+	require.NoError(t, err, "error should be nil")
+
+	set := list()
+
+	// ロールバックされた変更は反映されないこと。
+	_ = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		_ = tagRepo.SetTags(ctx, tx, 1, []string{"other"})
+
+		return errors.New("rollback")
+	})
+
+	rolledBack := list()
+
+	err = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		return tagRepo.SetTags(ctx, tx, 2, nil)
+	})
+	require.NoError(t, err, "error should be nil")
+
+	cleared := list()
+
+	// Assert
+	assert.Equal(t, map[int][]string{1: {"go", "news"}, 2: {"go"}}, set, "tags should be sorted and deduplicated")
+	assert.Equal(t, set, rolledBack, "tags should be rolled back")
+	assert.Equal(t, map[int][]string{1: {"go", "news"}}, cleared, "tags should be cleared")
+}
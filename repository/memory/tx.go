@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"errors"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+)
+
+type RwTx struct {
+	data *data
+}
+
+func (t *RwTx) ROTxImpl() {}
+func (t *RwTx) RWTxImpl() {}
+
+var _ transaction.RWTx = (*RwTx)(nil)
+
+func ExtractRWTx(tx transaction.RWTx) (*RwTx, error) {
+	rwTx, ok := tx.(*RwTx)
+	if !ok {
+		return nil, errors.New("failed to extract rwTx of memory")
+	}
+
+	return rwTx, nil
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
+)
+
+type txManager struct {
+	store *Store
+}
+
+func NewTxManager(store *Store) transaction.TxManager {
+	return &txManager{
+		store: store,
+	}
+}
+
+// ReadWriteTransaction runs f on a snapshot of the store.
+// Transactions are serialized by the store lock, and the snapshot replaces
+// the committed data only when f succeeds.
+func (t *txManager) ReadWriteTransaction(
+	ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error,
+) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	start := time.Now()
+	tx := &RwTx{data: t.store.data.clone()}
+
+	if err := f(ctx, tx); err != nil {
+		// rollback: 変更内容は snapshot ごと捨てる。
+		metrics.TxRollbacksTotal.Inc()
+		metrics.TxDuration.WithLabelValues(metrics.ResultRollback).Observe(time.Since(start).Seconds())
+
+		return fmt.Errorf("failed to execute f: %w", err)
+	}
+
+	// commit
+	t.store.data = tx.data
+
+	metrics.TxDuration.WithLabelValues(metrics.ResultCommit).Observe(time.Since(start).Seconds())
+
+	return nil
+}
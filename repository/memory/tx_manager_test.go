@@ -0,0 +1,89 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/memory"
+)
+
+func Test_Memory_NewTxManager(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		f func(ctx context.Context, tx transaction.RWTx) error
+	}
+
+	urlRepo := memory.NewURLRepo(memory.ExtractRWTx)
+	newURL := repository.URL{Domain: "localhost", URL: "https://example.com", Short: "R0D"}
+
+	testCases := map[string]struct {
+		args      args
+		wantErr   string
+		wantShort string
+	}{
+		"success": {
+			args: args{
+				f: func(ctx context.Context, tx transaction.RWTx) error {
+					_, err := urlRepo.InsertURL(ctx, tx, newURL)
+
+					return err
+				},
+			},
+			// f が正常終了した時は、変更内容が commit されること。
+			wantShort: "R0D",
+		},
+		"success: rollback due to function error": {
+			args: args{
+				f: func(ctx context.Context, tx transaction.RWTx) error {
+					if _, err := urlRepo.InsertURL(ctx, tx, newURL); err != nil {
+						return err
+					}
+
+					return errors.New("f error")
+				},
+			},
+			// f が異常終了した時は、変更内容が rollback されること。
+			wantErr: "failed to execute f: f error",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			store := memory.NewStore()
+			store.AddDomain("localhost")
+			txManager := memory.NewTxManager(store)
+			db := memory.New(store, nil)
+
+			// Act
+			err := txManager.ReadWriteTransaction(context.Background(), tc.args.f)
+
+			// Assert
+			if tc.wantErr == "" {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.Equal(t, tc.wantErr, err.Error(), "result does not match")
+			}
+
+			_, err = db.SearchURLFromShortURL(context.Background(), "localhost", "R0D")
+			if tc.wantShort == "" {
+				assert.ErrorIs(t, err, apperr.ErrShortURLNotFound, "changes should be rolled back")
+			} else {
+				assert.NoError(t, err, "changes should be committed")
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+func (d *database) SearchURLFromShortURL(
+	ctx context.Context, domain, shortURL string,
+) (repository.Destination, error) {
+	_, span := tracing.Start(ctx, "d.SearchURLFromShortURL")
+	defer span.End()
+
+	d.store.mu.RLock()
+	defer d.store.mu.RUnlock()
+
+	id, ok := d.store.data.byShort[domainKey(domain, shortURL)]
+	if !ok {
+		return repository.Destination{}, apperr.ErrShortURLNotFound
+	}
+
+	url := d.store.data.urls[id]
+
+	return repository.Destination{
+		ID:           url.ID,
+		URL:          url.URL,
+		PasswordHash: url.PasswordHash,
+		MaxClicks:    url.MaxClicks,
+		Clicks:       url.Clicks,
+	}, nil
+}
+
+type urlRepo struct {
+	extractRWTx func(transaction.RWTx) (*RwTx, error)
+}
+
+func NewURLRepo(
+	extractRWTx func(transaction.RWTx) (*RwTx, error),
+) repository.URLRepository {
+	return &urlRepo{
+		extractRWTx: extractRWTx,
+	}
+}
+
+func (u *urlRepo) SelectURL(
+	ctx context.Context, ttx transaction.RWTx, domain, shortURL string,
+) (repository.URL, error) {
+	_, span := tracing.Start(ctx, "u.SelectURL")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return repository.URL{}, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	id, ok := tx.data.byShort[domainKey(domain, shortURL)]
+	if !ok {
+		return repository.URL{}, apperr.ErrShortURLNotFound
+	}
+
+	return tx.data.urls[id], nil
+}
+
+func (u *urlRepo) SelectURLByDedupKey(
+	ctx context.Context, ttx transaction.RWTx, domain, dedupKey string,
+) (repository.URL, error) {
+	_, span := tracing.Start(ctx, "u.SelectURLByDedupKey")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return repository.URL{}, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	id, ok := tx.data.byDedupKey[domainKey(domain, dedupKey)]
+	if !ok {
+		return repository.URL{}, apperr.ErrShortURLNotFound
+	}
+
+	return tx.data.urls[id], nil
+}
+
+func (u *urlRepo) InsertURL(ctx context.Context, ttx transaction.RWTx, url repository.URL) (repository.URL, error) {
+	_, span := tracing.Start(ctx, "u.InsertURL")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return repository.URL{}, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	if !tx.data.domains[url.Domain] {
+		return repository.URL{}, apperr.ErrDomainNotFound
+	}
+
+	// Mimic the unique constraints of init.sql, where the empty dedup key (NULL) never conflicts.
+	dedupKey, shortKey := domainKey(url.Domain, url.DedupKey), domainKey(url.Domain, url.Short)
+
+	if _, ok := tx.data.byDedupKey[dedupKey]; ok && url.DedupKey != "" {
+		return repository.URL{}, fmt.Errorf("failed to insert: %w", repository.ErrURLAlreadyExists)
+	}
+
+	if _, ok := tx.data.byShort[shortKey]; ok {
+		return repository.URL{}, fmt.Errorf("failed to insert: %w", repository.ErrShortURLAlreadyExists)
+	}
+
+	url.ID = tx.data.nextID
+	url.CreatedAt = time.Now()
+	url.Clicks = 0
+	// tags are stored by tagRepo.
+	url.Tags = nil
+	tx.data.nextID++
+
+	tx.data.urls[url.ID] = url
+	tx.data.byShort[shortKey] = url.ID
+
+	if url.DedupKey != "" {
+		tx.data.byDedupKey[dedupKey] = url.ID
+	}
+
+	return url, nil
+}
+
+func (u *urlRepo) ListURLs(
+	ctx context.Context, ttx transaction.RWTx, filter repository.URLFilter,
+) ([]repository.URL, error) {
+	_, span := tracing.Start(ctx, "u.ListURLs")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	urls := []repository.URL{}
+
+	// ID の降順 (新しい順) に返す。
+	for id := tx.data.nextID - 1; id > 0; id-- {
+		url, ok := tx.data.urls[id]
+		if !ok || !matches(url, tx.data.tags[id], filter) {
+			continue
+		}
+
+		urls = append(urls, url)
+	}
+
+	if filter.Offset >= len(urls) {
+		return []repository.URL{}, nil
+	}
+
+	urls = urls[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(urls) {
+		urls = urls[:filter.Limit]
+	}
+
+	return urls, nil
+}
+
+func matches(url repository.URL, tags []string, filter repository.URLFilter) bool {
+	if filter.Domain != "" && url.Domain != filter.Domain {
+		return false
+	}
+
+	if filter.Owner != "" && url.Owner != filter.Owner {
+		return false
+	}
+
+	for _, tag := range filter.Tags {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (u *urlRepo) UpdateURL(ctx context.Context, ttx transaction.RWTx, url repository.URL) error {
+	_, span := tracing.Start(ctx, "u.UpdateURL")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	current, ok := tx.data.urls[url.ID]
+	if !ok {
+		return apperr.ErrShortURLNotFound
+	}
+
+	current.Title = url.Title
+	current.Notes = url.Notes
+	current.PasswordHash = url.PasswordHash
+
+	if url.DedupKey != current.DedupKey {
+		newKey := domainKey(current.Domain, url.DedupKey)
+		if _, ok := tx.data.byDedupKey[newKey]; ok && url.DedupKey != "" {
+			return fmt.Errorf("failed to update: %w", repository.ErrURLAlreadyExists)
+		}
+
+		if current.DedupKey != "" {
+			delete(tx.data.byDedupKey, domainKey(current.Domain, current.DedupKey))
+		}
+
+		if url.DedupKey != "" {
+			tx.data.byDedupKey[newKey] = url.ID
+		}
+
+		current.DedupKey = url.DedupKey
+	}
+
+	tx.data.urls[url.ID] = current
+
+	return nil
+}
+
+func (u *urlRepo) ConsumeClick(ctx context.Context, ttx transaction.RWTx, id int) (int, error) {
+	_, span := tracing.Start(ctx, "u.ConsumeClick")
+	defer span.End()
+
+	tx, err := u.extractRWTx(ttx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to extract tx: %w", err)
+	}
+
+	// トランザクションは排他的に実行されるため、読んでから更新しても競合しない。
+	url, ok := tx.data.urls[id]
+	if !ok || url.Clicks >= url.MaxClicks {
+		return 0, apperr.ErrShortURLExhausted
+	}
+
+	url.Clicks++
+	tx.data.urls[id] = url
+
+	return url.MaxClicks - url.Clicks, nil
+}
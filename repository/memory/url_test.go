@@ -0,0 +1,530 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/memory"
+)
+
+// seed registers the domains "localhost" and "short.example.com",
+// and inserts the given urls (original -> short) on "localhost" into the store with the url as the dedup key.
+func seed(t *testing.T, store *memory.Store, urls map[string]string) {
+	t.Helper()
+
+	store.AddDomain("localhost")
+	store.AddDomain("short.example.com")
+
+	txManager := memory.NewTxManager(store)
+	urlRepo := memory.NewURLRepo(memory.ExtractRWTx)
+
+	err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		for url, short := range urls {
+			newURL := repository.URL{Domain: "localhost", URL: url, Short: short, DedupKey: url}
+			if _, err := urlRepo.InsertURL(ctx, tx, newURL); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err, "error of seed should be nil")
+}
+
+func Test_Memory_SearchURLFromShortURL(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		domain   string
+		shortURL string
+	}
+
+	testCases := map[string]struct {
+		args    args
+		want    repository.Destination
+		wantErr string
+	}{
+		"success": {
+			args: args{
+				domain:   "localhost",
+				shortURL: "R0D",
+			},
+			want: repository.Destination{ID: 1, URL: "https://example.com"},
+		},
+		"failure: no row found": {
+			args: args{
+				domain:   "localhost",
+				shortURL: "NUL",
+			},
+			wantErr: apperr.ErrShortURLNotFound.Error(),
+		},
+		"failure: no row found on other domain": {
+			args: args{
+				domain:   "short.example.com",
+				shortURL: "R0D",
+			},
+			wantErr: apperr.ErrShortURLNotFound.Error(),
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			store := memory.NewStore()
+			seed(t, store, map[string]string{"https://example.com": "R0D"})
+
+			database := memory.New(store, nil)
+
+			// Act
+			got, err := database.SearchURLFromShortURL(context.Background(), tc.args.domain, tc.args.shortURL)
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			if tc.wantErr == "" {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.Equal(t, tc.wantErr, err.Error(), "result does not match")
+			}
+		})
+	}
+}
+
+func Test_Memory_SelectURLByDedupKey(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		domain   string
+		dedupKey string
+	}
+
+	testCases := map[string]struct {
+		args        args
+		extractRWTx func(transaction.RWTx) (*memory.RwTx, error)
+		want        string
+		wantErr     string
+	}{
+		"success": {
+			args: args{
+				domain:   "localhost",
+				dedupKey: "https://example.com",
+			},
+			extractRWTx: memory.ExtractRWTx,
+			want:        "R0D",
+		},
+		"failure: extract rwtx": {
+			args: args{
+				domain:   "localhost",
+				dedupKey: "https://example.com",
+			},
+			extractRWTx: func(r transaction.RWTx) (*memory.RwTx, error) {
+				return nil, errors.New("extract rwtx error")
+			},
+			wantErr: "failed to extract tx: extract rwtx error",
+		},
+		"failure: no row found": {
+			args: args{
+				domain:   "localhost",
+				dedupKey: "https://wtf.example.com",
+			},
+			extractRWTx: memory.ExtractRWTx,
+			wantErr:     apperr.ErrShortURLNotFound.Error(),
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			store := memory.NewStore()
+			seed(t, store, map[string]string{"https://example.com": "R0D"})
+
+			txManager := memory.NewTxManager(store)
+			urlRepo := memory.NewURLRepo(tc.extractRWTx)
+
+			var (
+				got repository.URL
+				err error
+			)
+
+			// Act
+			_ = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+				got, err = urlRepo.SelectURLByDedupKey(ctx, tx, tc.args.domain, tc.args.dedupKey)
+
+				return err
+			})
+
+			// Assert
+			assert.Equal(t, tc.want, got.Short, "result does not match")
+			if tc.wantErr == "" {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.Equal(t, tc.wantErr, err.Error(), "result does not match")
+			}
+		})
+	}
+}
+
+func Test_Memory_InsertURL(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		url repository.URL
+	}
+
+	testCases := map[string]struct {
+		args    args
+		wantErr error
+	}{
+		"success": {
+			args: args{
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com/new",
+					Short:    "XYZ",
+					DedupKey: "https://example.com/new",
+				},
+			},
+		},
+		"failure: duplicate url": {
+			args: args{
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "XYZ",
+					DedupKey: "https://example.com",
+				},
+			},
+			wantErr: repository.ErrURLAlreadyExists,
+		},
+		// dedup key が空の場合は、同じ URL でも登録できること。
+		"success: same url without dedup key": {
+			args: args{
+				url: repository.URL{
+					Domain: "localhost",
+					URL:    "https://example.com",
+					Short:  "XYZ",
+				},
+			},
+		},
+		"success: same url with other dedup key": {
+			args: args{
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com",
+					Short:    "XYZ",
+					Owner:    "team-a",
+					DedupKey: "team-a https://example.com",
+				},
+			},
+		},
+		"failure: duplicate short url": {
+			args: args{
+				url: repository.URL{
+					Domain:   "localhost",
+					URL:      "https://example.com/new",
+					Short:    "R0D",
+					DedupKey: "https://example.com/new",
+				},
+			},
+			wantErr: repository.ErrShortURLAlreadyExists,
+		},
+		// short url は domain ごとに一意であれば良い。
+		"success: same url and short url on other domain": {
+			args: args{
+				url: repository.URL{
+					Domain:   "short.example.com",
+					URL:      "https://example.com",
+					Short:    "R0D",
+					DedupKey: "https://example.com",
+				},
+			},
+		},
+		"failure: domain not found": {
+			args: args{
+				url: repository.URL{
+					Domain:   "wtf.example.com",
+					URL:      "https://example.com/new",
+					Short:    "XYZ",
+					DedupKey: "https://example.com/new",
+				},
+			},
+			wantErr: apperr.ErrDomainNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			store := memory.NewStore()
+			seed(t, store, map[string]string{"https://example.com": "R0D"})
+
+			txManager := memory.NewTxManager(store)
+			urlRepo := memory.NewURLRepo(memory.ExtractRWTx)
+
+			var (
+				got repository.URL
+				err error
+			)
+
+			// Act
+			_ = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+				got, err = urlRepo.InsertURL(ctx, tx, tc.args.url)
+
+				return err
+			})
+
+			// Assert
+			if tc.wantErr == nil {
+				require.NoError(t, err, "error should be nil")
+				assert.Equal(t, tc.args.url.Short, got.Short, "result does not match")
+				assert.False(t, got.CreatedAt.IsZero(), "created_at should be set")
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr, "result does not match")
+			}
+		})
+	}
+}
+
+func Test_Memory_ListURLs(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+	store.AddDomain("short.example.com")
+
+	txManager := memory.NewTxManager(store)
+	urlRepo := memory.NewURLRepo(memory.ExtractRWTx)
+	tagRepo := memory.NewTagRepo(memory.ExtractRWTx)
+
+	seeds := []struct {
+		url  repository.URL
+		tags []string
+	}{
+		{url: repository.URL{Domain: "localhost", URL: "https://a.example.com", Short: "a", Owner: "team-a"}, tags: []string{"go", "news"}},
+		{url: repository.URL{Domain: "localhost", URL: "https://b.example.com", Short: "b", Owner: "team-b"}, tags: []string{"go"}},
+		{url: repository.URL{Domain: "short.example.com", URL: "https://c.example.com", Short: "c"}},
+	}
+
+	err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		for _, s := range seeds {
+			url, err := urlRepo.InsertURL(ctx, tx, s.url)
+			if err != nil {
+				return err
+			}
+
+			if err := tagRepo.SetTags(ctx, tx, url.ID, s.tags); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err, "error of seed should be nil")
+
+	testCases := map[string]struct {
+		filter repository.URLFilter
+		want   []string
+	}{
+		"success: all in newest first": {
+			want: []string{"c", "b", "a"},
+		},
+		"success: domain": {
+			filter: repository.URLFilter{Domain: "localhost"},
+			want:   []string{"b", "a"},
+		},
+		"success: owner": {
+			filter: repository.URLFilter{Owner: "team-a"},
+			want:   []string{"a"},
+		},
+		"success: all tags must match": {
+			filter: repository.URLFilter{Tags: []string{"go", "news"}},
+			want:   []string{"a"},
+		},
+		"success: limit and offset": {
+			filter: repository.URLFilter{Limit: 1, Offset: 1},
+			want:   []string{"b"},
+		},
+		"success: offset out of range": {
+			filter: repository.URLFilter{Offset: 10},
+			want:   []string{},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []repository.URL
+
+			// Act
+			err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+				var err error
+				got, err = urlRepo.ListURLs(ctx, tx, tc.filter)
+
+				return err
+			})
+
+			// Assert
+			require.NoError(t, err, "error should be nil")
+
+			shorts := []string{}
+			for _, url := range got {
+				shorts = append(shorts, url.Short)
+			}
+
+			assert.Equal(t, tc.want, shorts, "result does not match")
+		})
+	}
+}
+
+func Test_Memory_UpdateURL(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	store := memory.NewStore()
+	seed(t, store, map[string]string{"https://example.com": "R0D"})
+
+	txManager := memory.NewTxManager(store)
+	urlRepo := memory.NewURLRepo(memory.ExtractRWTx)
+
+	// Act
+	err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		url, err := urlRepo.SelectURL(ctx, tx, "localhost", "R0D")
+		if err != nil {
+			return err
+		}
+
+		url.Title, url.Notes, url.PasswordHash, url.DedupKey = "Example", "note", "hash", ""
+		// title, notes と password_hash 以外は更新されないこと。
+		url.URL = "https://changed.example.com"
+
+		return urlRepo.UpdateURL(ctx, tx, url)
+	})
+
+	notFound := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		return urlRepo.UpdateURL(ctx, tx, repository.URL{ID: 100})
+	})
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.ErrorIs(t, notFound, apperr.ErrShortURLNotFound, "error does not match")
+
+	var got repository.URL
+
+	_ = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		got, err = urlRepo.SelectURL(ctx, tx, "localhost", "R0D")
+
+		return err
+	})
+
+	assert.Equal(t, "Example", got.Title, "title does not match")
+	assert.Equal(t, "note", got.Notes, "notes does not match")
+	assert.Equal(t, "hash", got.PasswordHash, "password hash does not match")
+	assert.Empty(t, got.DedupKey, "dedup key does not match")
+
+	// 重複排除のキーを外した短縮 URL は、もう共有されないこと。
+	_ = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		_, err = urlRepo.SelectURLByDedupKey(ctx, tx, "localhost", "https://example.com")
+
+		return nil
+	})
+
+	assert.ErrorIs(t, err, apperr.ErrShortURLNotFound, "error does not match")
+	assert.Equal(t, "https://example.com", got.URL, "url should not be changed")
+}
+
+func Test_Memory_ConsumeClick(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+
+	txManager := memory.NewTxManager(store)
+	urlRepo := memory.NewURLRepo(memory.ExtractRWTx)
+
+	var url repository.URL
+
+	err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		var err error
+
+		url, err = urlRepo.InsertURL(ctx, tx, repository.URL{
+			Domain: "localhost", URL: "https://example.com", Short: "R0D", MaxClicks: 3,
+		})
+
+		return err
+	})
+	require.NoError(t, err, "error of InsertURL should be nil")
+
+	// Act
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		left      []int
+		exhausted int
+	)
+
+	// 同時にクリックされても、max_clicks を超えて消費されないこと。
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var n int
+
+			err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+				var err error
+
+				n, err = urlRepo.ConsumeClick(ctx, tx, url.ID)
+
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if errors.Is(err, apperr.ErrShortURLExhausted) {
+				exhausted++
+			} else if assert.NoError(t, err, "error should be nil") {
+				left = append(left, n)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Assert
+	assert.ElementsMatch(t, []int{2, 1, 0}, left, "clicks left does not match")
+	assert.Equal(t, 7, exhausted, "exhausted clicks does not match")
+
+	dest, err := memory.New(store, nil).SearchURLFromShortURL(context.Background(), "localhost", "R0D")
+	require.NoError(t, err, "error should be nil")
+	assert.Equal(t, 3, dest.Clicks, "clicks does not match")
+}
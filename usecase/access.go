@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The access token lets the visitor who entered the password open the short url again without it,
+// until it expires. It is signed with the password hash, so that changing the password revokes it.
+const (
+	defaultAccessTokenTTL = time.Hour
+	accessKeySize         = 32
+)
+
+// accessToken returns "<expiry in unix seconds>.<signature>".
+func (u *usecase) accessToken(domain, shortURL, passwordHash string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return exp + "." + base64.RawURLEncoding.EncodeToString(u.signAccess(domain, shortURL, passwordHash, exp))
+}
+
+// validAccessToken reports whether the token is issued for the short url and not expired.
+func (u *usecase) validAccessToken(token, domain, shortURL, passwordHash string) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() >= unix {
+		return false
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(got, u.signAccess(domain, shortURL, passwordHash, exp))
+}
+
+func (u *usecase) signAccess(domain, shortURL, passwordHash, exp string) []byte {
+	mac := hmac.New(sha256.New, u.accessKey)
+	// 値の境界をずらした偽造を防ぐため、NUL で区切る。
+	mac.Write([]byte(domain + "\x00" + shortURL + "\x00" + passwordHash + "\x00" + exp))
+
+	return mac.Sum(nil)
+}
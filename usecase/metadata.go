@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
+	"github.com/kokoichi206-sandbox/url-shortener/util/pagemeta"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
+)
+
+// MetadataQueue receives the short urls whose page metadata are fetched in the background.
+type MetadataQueue interface {
+	// Enqueue must not block, and returns false if the url is dropped (e.g. the queue is full).
+	Enqueue(urlID int, originalURL string) bool
+}
+
+type metadataJob struct {
+	urlID       int
+	originalURL string
+}
+
+// MetadataWorker fetches the page metadata of the enqueued short urls and stores them.
+// The failures are only logged, since the metadata are optional.
+type MetadataWorker struct {
+	txManager transaction.TxManager
+	pageRepo  repository.PageMetadataRepository
+	fetcher   pagemeta.Fetcher
+	logger    logger.Logger
+
+	jobs chan metadataJob
+	wg   sync.WaitGroup
+	// ctx is canceled when Close gives up waiting, to abort the in-flight fetches.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards closed, so that Enqueue never sends to the closed channel.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewMetadataWorker starts the workers which drain the queue of the given size.
+// Close must be called to stop them.
+func NewMetadataWorker(
+	txManager transaction.TxManager, pageRepo repository.PageMetadataRepository,
+	fetcher pagemeta.Fetcher, logger logger.Logger, workers, queueSize int,
+) *MetadataWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &MetadataWorker{
+		txManager: txManager,
+		pageRepo:  pageRepo,
+		fetcher:   fetcher,
+		logger:    logger,
+		jobs:      make(chan metadataJob, queueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	for i := 0; i < workers; i++ {
+		w.wg.Add(1)
+
+		go w.run()
+	}
+
+	return w
+}
+
+func (w *MetadataWorker) Enqueue(urlID int, originalURL string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		metrics.MetadataFetchesTotal.WithLabelValues(metrics.ResultDropped).Inc()
+
+		return false
+	}
+
+	select {
+	case w.jobs <- metadataJob{urlID: urlID, originalURL: originalURL}:
+		return true
+	default:
+		metrics.MetadataFetchesTotal.WithLabelValues(metrics.ResultDropped).Inc()
+
+		return false
+	}
+}
+
+// Close stops accepting the urls, and waits for the queued ones until the deadline of the context.
+// The remaining ones are abandoned after the deadline.
+func (w *MetadataWorker) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.jobs)
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+
+		return fmt.Errorf("failed to wait for metadata workers: %w", ctx.Err())
+	}
+}
+
+func (w *MetadataWorker) run() {
+	defer w.wg.Done()
+
+	for job := range w.jobs {
+		// 締め切りを過ぎた後は、残りを取得せずに捨てる。
+		if w.ctx.Err() != nil {
+			continue
+		}
+
+		w.process(job)
+	}
+}
+
+func (w *MetadataWorker) process(job metadataJob) {
+	ctx, span := tracing.Start(w.ctx, "w.FetchMetadata")
+	defer span.End()
+
+	meta, err := w.fetcher.Fetch(ctx, job.originalURL)
+	if err != nil {
+		result := metrics.ResultError
+		if errors.Is(err, pagemeta.ErrDisallowed) {
+			result = metrics.ResultDisallowed
+		}
+
+		metrics.MetadataFetchesTotal.WithLabelValues(result).Inc()
+		w.logger.Infof(ctx, "failed to fetch page metadata of %d: %v", job.urlID, err)
+
+		return
+	}
+
+	page := repository.PageMetadata{
+		Title:       meta.Title,
+		Description: meta.Description,
+		ImageURL:    meta.ImageURL,
+		FetchedAt:   time.Now(),
+	}
+
+	if err := w.txManager.ReadWriteTransaction(ctx, func(ctx context.Context, tx transaction.RWTx) error {
+		//nolint:wrapcheck
+		return w.pageRepo.UpsertPageMetadata(ctx, tx, job.urlID, page)
+	}); err != nil {
+		metrics.MetadataFetchesTotal.WithLabelValues(metrics.ResultError).Inc()
+		w.logger.Warnf(ctx, "failed to store page metadata of %d: %v", job.urlID, err)
+
+		return
+	}
+
+	metrics.MetadataFetchesTotal.WithLabelValues(metrics.ResultSuccess).Inc()
+}
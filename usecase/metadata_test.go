@@ -0,0 +1,187 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
+	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
+	"github.com/kokoichi206-sandbox/url-shortener/repository/memory"
+	"github.com/kokoichi206-sandbox/url-shortener/usecase"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/pagemeta"
+)
+
+// fakeFetcher returns the metadata per url, and fails for the others.
+type fakeFetcher struct {
+	pages map[string]pagemeta.Metadata
+}
+
+func (f fakeFetcher) Fetch(_ context.Context, pageURL string) (pagemeta.Metadata, error) {
+	if meta, ok := f.pages[pageURL]; ok {
+		return meta, nil
+	}
+
+	return pagemeta.Metadata{}, errors.New("fetch error")
+}
+
+// recordingQueue records the enqueued urls, and accepts them only if accept is true.
+type recordingQueue struct {
+	accept bool
+
+	mu   sync.Mutex
+	urls []string
+}
+
+func (q *recordingQueue) Enqueue(_ int, originalURL string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.urls = append(q.urls, originalURL)
+
+	return q.accept
+}
+
+func Test_MetadataWorker(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	store := memory.NewStore()
+	store.AddDomain("localhost")
+
+	txManager := memory.NewTxManager(store)
+	pageRepo := memory.NewPageMetadataRepo(memory.ExtractRWTx)
+	urlRepo := memory.NewURLRepo(memory.ExtractRWTx)
+
+	var ids []int
+
+	err := txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		for _, short := range []string{"a", "b"} {
+			url, err := urlRepo.InsertURL(ctx, tx, repository.URL{Domain: "localhost", URL: "https://" + short + ".example.com/", Short: short})
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, url.ID)
+		}
+
+		return nil
+	})
+	require.NoError(t, err, "error of seed should be nil")
+
+	fetcher := fakeFetcher{pages: map[string]pagemeta.Metadata{
+		"https://a.example.com/": {Title: "A", Description: "page a", ImageURL: "https://a.example.com/og.png"},
+	}}
+	b := bytes.NewBuffer([]byte{})
+	w := usecase.NewMetadataWorker(txManager, pageRepo, fetcher, logger.NewBasicLogger(b, "test", "metadata"), 2, 10)
+
+	// Act
+	okA := w.Enqueue(ids[0], "https://a.example.com/")
+	okB := w.Enqueue(ids[1], "https://b.example.com/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	closeErr := w.Close(ctx)
+	afterClose := w.Enqueue(ids[0], "https://a.example.com/")
+
+	// Assert
+	assert.True(t, okA, "url should be enqueued")
+	assert.True(t, okB, "url should be enqueued")
+	require.NoError(t, closeErr, "queued urls should be processed before close")
+	assert.False(t, afterClose, "url should be dropped after close")
+
+	var pages map[int]repository.PageMetadata
+
+	_ = txManager.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx transaction.RWTx) error {
+		pages, err = pageRepo.ListPageMetadata(ctx, tx, ids)
+
+		return err
+	})
+
+	require.Len(t, pages, 1, "only the fetched page should be stored")
+	assert.Equal(t, "A", pages[ids[0]].Title, "title does not match")
+	assert.Equal(t, "page a", pages[ids[0]].Description, "description does not match")
+	assert.Equal(t, "https://a.example.com/og.png", pages[ids[0]].ImageURL, "image url does not match")
+	assert.False(t, pages[ids[0]].FetchedAt.IsZero(), "fetched_at should be set")
+	assert.Contains(t, b.String(), "fetch error", "failure should be logged")
+}
+
+func Test_MetadataWorker_QueueFull(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	// worker がいないため、キューは消費されない。
+	w := usecase.NewMetadataWorker(nil, nil, fakeFetcher{}, nil, 0, 1)
+
+	// Act
+	first := w.Enqueue(1, "https://example.com/")
+	second := w.Enqueue(2, "https://example.com/")
+
+	// Assert
+	assert.True(t, first, "url should be enqueued")
+	assert.False(t, second, "url should be dropped when the queue is full")
+}
+
+func Test_Usecase_GenerateURL_MetadataQueue(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		accept  bool
+		wantLog string
+	}{
+		"success": {
+			accept: true,
+		},
+		"success: queue is full": {
+			accept:  false,
+			wantLog: "metadata queue is full",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			logger := logger.NewBasicLogger(b, "test", "generateURL")
+
+			store := memory.NewStore()
+			store.AddDomain("localhost")
+
+			q := &recordingQueue{accept: tc.accept}
+			u := usecase.New(
+				memory.New(store, logger), memory.NewTxManager(store), memory.NewURLRepo(memory.ExtractRWTx),
+				memory.NewTagRepo(memory.ExtractRWTx), memory.NewPageMetadataRepo(memory.ExtractRWTx), logger,
+				usecase.WithMetadataQueue(q),
+			)
+
+			params := usecase.GenerateURLParams{Domain: "localhost", OriginalURL: "https://example.com"}
+
+			// Act
+			_, created, err := u.GenerateURL(context.Background(), params)
+			require.NoError(t, err, "error should be nil")
+			require.True(t, created, "url should be created")
+
+			// 既存の短縮 URL が返される場合は、再取得しないこと。
+			_, created, err = u.GenerateURL(context.Background(), params)
+			require.NoError(t, err, "error should be nil")
+			require.False(t, created, "existing url should be returned")
+
+			// Assert
+			assert.Equal(t, []string{"https://example.com/"}, q.urls, "only the created url should be enqueued")
+			assert.Contains(t, b.String(), tc.wantLog, "log does not match")
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	repository "github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 )
 
 // MockDatabase is a mock of Database interface.
@@ -48,17 +49,46 @@ func (mr *MockDatabaseMockRecorder) Health(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockDatabase)(nil).Health), ctx)
 }
 
+// SchemaVersion mocks base method.
+func (m *MockDatabase) SchemaVersion(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SchemaVersion", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SchemaVersion indicates an expected call of SchemaVersion.
+func (mr *MockDatabaseMockRecorder) SchemaVersion(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SchemaVersion", reflect.TypeOf((*MockDatabase)(nil).SchemaVersion), ctx)
+}
+
 // SearchURLFromShortURL mocks base method.
-func (m *MockDatabase) SearchURLFromShortURL(ctx context.Context, shortURL string) (string, error) {
+func (m *MockDatabase) SearchURLFromShortURL(ctx context.Context, domain, shortURL string) (repository.Destination, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SearchURLFromShortURL", ctx, shortURL)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "SearchURLFromShortURL", ctx, domain, shortURL)
+	ret0, _ := ret[0].(repository.Destination)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SearchURLFromShortURL indicates an expected call of SearchURLFromShortURL.
-func (mr *MockDatabaseMockRecorder) SearchURLFromShortURL(ctx, shortURL interface{}) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) SearchURLFromShortURL(ctx, domain, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchURLFromShortURL", reflect.TypeOf((*MockDatabase)(nil).SearchURLFromShortURL), ctx, domain, shortURL)
+}
+
+// Stats mocks base method.
+func (m *MockDatabase) Stats() repository.DBStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(repository.DBStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockDatabaseMockRecorder) Stats() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchURLFromShortURL", reflect.TypeOf((*MockDatabase)(nil).SearchURLFromShortURL), ctx, shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockDatabase)(nil).Stats))
 }
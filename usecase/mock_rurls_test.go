@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	repository "github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	transaction "github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 )
 
@@ -35,31 +36,195 @@ func (m *MockURLRepository) EXPECT() *MockURLRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ConsumeClick mocks base method.
+func (m *MockURLRepository) ConsumeClick(ctx context.Context, tx transaction.RWTx, id int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeClick", ctx, tx, id)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeClick indicates an expected call of ConsumeClick.
+func (mr *MockURLRepositoryMockRecorder) ConsumeClick(ctx, tx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeClick", reflect.TypeOf((*MockURLRepository)(nil).ConsumeClick), ctx, tx, id)
+}
+
 // InsertURL mocks base method.
-func (m *MockURLRepository) InsertURL(ctx context.Context, tx transaction.RWTx, originalURL, shortURL string) error {
+func (m *MockURLRepository) InsertURL(ctx context.Context, tx transaction.RWTx, url repository.URL) (repository.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertURL", ctx, tx, originalURL, shortURL)
+	ret := m.ctrl.Call(m, "InsertURL", ctx, tx, url)
+	ret0, _ := ret[0].(repository.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertURL indicates an expected call of InsertURL.
+func (mr *MockURLRepositoryMockRecorder) InsertURL(ctx, tx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertURL", reflect.TypeOf((*MockURLRepository)(nil).InsertURL), ctx, tx, url)
+}
+
+// ListURLs mocks base method.
+func (m *MockURLRepository) ListURLs(ctx context.Context, tx transaction.RWTx, filter repository.URLFilter) ([]repository.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", ctx, tx, filter)
+	ret0, _ := ret[0].([]repository.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockURLRepositoryMockRecorder) ListURLs(ctx, tx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockURLRepository)(nil).ListURLs), ctx, tx, filter)
+}
+
+// SelectURL mocks base method.
+func (m *MockURLRepository) SelectURL(ctx context.Context, tx transaction.RWTx, domain, shortURL string) (repository.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectURL", ctx, tx, domain, shortURL)
+	ret0, _ := ret[0].(repository.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectURL indicates an expected call of SelectURL.
+func (mr *MockURLRepositoryMockRecorder) SelectURL(ctx, tx, domain, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectURL", reflect.TypeOf((*MockURLRepository)(nil).SelectURL), ctx, tx, domain, shortURL)
+}
+
+// SelectURLByDedupKey mocks base method.
+func (m *MockURLRepository) SelectURLByDedupKey(ctx context.Context, tx transaction.RWTx, domain, dedupKey string) (repository.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectURLByDedupKey", ctx, tx, domain, dedupKey)
+	ret0, _ := ret[0].(repository.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectURLByDedupKey indicates an expected call of SelectURLByDedupKey.
+func (mr *MockURLRepositoryMockRecorder) SelectURLByDedupKey(ctx, tx, domain, dedupKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectURLByDedupKey", reflect.TypeOf((*MockURLRepository)(nil).SelectURLByDedupKey), ctx, tx, domain, dedupKey)
+}
+
+// UpdateURL mocks base method.
+func (m *MockURLRepository) UpdateURL(ctx context.Context, tx transaction.RWTx, url repository.URL) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateURL", ctx, tx, url)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// InsertURL indicates an expected call of InsertURL.
-func (mr *MockURLRepositoryMockRecorder) InsertURL(ctx, tx, originalURL, shortURL interface{}) *gomock.Call {
+// UpdateURL indicates an expected call of UpdateURL.
+func (mr *MockURLRepositoryMockRecorder) UpdateURL(ctx, tx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockURLRepository)(nil).UpdateURL), ctx, tx, url)
+}
+
+// MockTagRepository is a mock of TagRepository interface.
+type MockTagRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTagRepositoryMockRecorder
+}
+
+// MockTagRepositoryMockRecorder is the mock recorder for MockTagRepository.
+type MockTagRepositoryMockRecorder struct {
+	mock *MockTagRepository
+}
+
+// NewMockTagRepository creates a new mock instance.
+func NewMockTagRepository(ctrl *gomock.Controller) *MockTagRepository {
+	mock := &MockTagRepository{ctrl: ctrl}
+	mock.recorder = &MockTagRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTagRepository) EXPECT() *MockTagRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ListTags mocks base method.
+func (m *MockTagRepository) ListTags(ctx context.Context, tx transaction.RWTx, urlIDs []int) (map[int][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTags", ctx, tx, urlIDs)
+	ret0, _ := ret[0].(map[int][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTags indicates an expected call of ListTags.
+func (mr *MockTagRepositoryMockRecorder) ListTags(ctx, tx, urlIDs interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertURL", reflect.TypeOf((*MockURLRepository)(nil).InsertURL), ctx, tx, originalURL, shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockTagRepository)(nil).ListTags), ctx, tx, urlIDs)
 }
 
-// SelectShortURL mocks base method.
-func (m *MockURLRepository) SelectShortURL(ctx context.Context, tx transaction.RWTx, originalURL string) (string, error) {
+// SetTags mocks base method.
+func (m *MockTagRepository) SetTags(ctx context.Context, tx transaction.RWTx, urlID int, tags []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectShortURL", ctx, tx, originalURL)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "SetTags", ctx, tx, urlID, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTags indicates an expected call of SetTags.
+func (mr *MockTagRepositoryMockRecorder) SetTags(ctx, tx, urlID, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTags", reflect.TypeOf((*MockTagRepository)(nil).SetTags), ctx, tx, urlID, tags)
+}
+
+// MockPageMetadataRepository is a mock of PageMetadataRepository interface.
+type MockPageMetadataRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPageMetadataRepositoryMockRecorder
+}
+
+// MockPageMetadataRepositoryMockRecorder is the mock recorder for MockPageMetadataRepository.
+type MockPageMetadataRepositoryMockRecorder struct {
+	mock *MockPageMetadataRepository
+}
+
+// NewMockPageMetadataRepository creates a new mock instance.
+func NewMockPageMetadataRepository(ctrl *gomock.Controller) *MockPageMetadataRepository {
+	mock := &MockPageMetadataRepository{ctrl: ctrl}
+	mock.recorder = &MockPageMetadataRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPageMetadataRepository) EXPECT() *MockPageMetadataRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ListPageMetadata mocks base method.
+func (m *MockPageMetadataRepository) ListPageMetadata(ctx context.Context, tx transaction.RWTx, urlIDs []int) (map[int]repository.PageMetadata, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPageMetadata", ctx, tx, urlIDs)
+	ret0, _ := ret[0].(map[int]repository.PageMetadata)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// SelectShortURL indicates an expected call of SelectShortURL.
-func (mr *MockURLRepositoryMockRecorder) SelectShortURL(ctx, tx, originalURL interface{}) *gomock.Call {
+// ListPageMetadata indicates an expected call of ListPageMetadata.
+func (mr *MockPageMetadataRepositoryMockRecorder) ListPageMetadata(ctx, tx, urlIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPageMetadata", reflect.TypeOf((*MockPageMetadataRepository)(nil).ListPageMetadata), ctx, tx, urlIDs)
+}
+
+// UpsertPageMetadata mocks base method.
+func (m *MockPageMetadataRepository) UpsertPageMetadata(ctx context.Context, tx transaction.RWTx, urlID int, page repository.PageMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertPageMetadata", ctx, tx, urlID, page)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertPageMetadata indicates an expected call of UpsertPageMetadata.
+func (mr *MockPageMetadataRepositoryMockRecorder) UpsertPageMetadata(ctx, tx, urlID, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectShortURL", reflect.TypeOf((*MockURLRepository)(nil).SelectShortURL), ctx, tx, originalURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertPageMetadata", reflect.TypeOf((*MockPageMetadataRepository)(nil).UpsertPageMetadata), ctx, tx, urlID, page)
 }
@@ -0,0 +1,50 @@
+package usecase
+
+import "time"
+
+// Option configures the usecase.
+type Option func(u *usecase)
+
+// DedupPolicy decides when the existing short url is returned for the same original url.
+type DedupPolicy string
+
+const (
+	// DedupGlobal shares the short url among everyone on the domain.
+	DedupGlobal DedupPolicy = "global"
+	// DedupPerOwner shares the short url only among the same owner,
+	// so that each owner has its own link and stats.
+	DedupPerOwner DedupPolicy = "per-owner"
+	// DedupNever always creates a new short url.
+	DedupNever DedupPolicy = "never"
+)
+
+// WithDedupPolicy sets the dedup policy (default: DedupGlobal).
+func WithDedupPolicy(p DedupPolicy) Option {
+	return func(u *usecase) {
+		if p != "" {
+			u.dedupPolicy = p
+		}
+	}
+}
+
+// WithMetadataQueue enqueues the newly created short urls to fetch the metadata of their pages.
+func WithMetadataQueue(q MetadataQueue) Option {
+	return func(u *usecase) {
+		u.metadataQueue = q
+	}
+}
+
+// WithAccessToken sets the key to sign the access tokens of the password protected short urls,
+// and how long they are valid (default: 1h).
+// A random key is used by default, which invalidates the tokens on restart and differs among the servers.
+func WithAccessToken(key []byte, ttl time.Duration) Option {
+	return func(u *usecase) {
+		if len(key) > 0 {
+			u.accessKey = key
+		}
+
+		if ttl != 0 {
+			u.accessTokenTTL = ttl
+		}
+	}
+}
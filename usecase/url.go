@@ -6,93 +6,513 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/lib/pq"
-	tracer "github.com/opentracing/opentracing-go"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
+	"github.com/kokoichi206-sandbox/url-shortener/util/metrics"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tracing"
 )
 
 const (
 	shortenedURLLength = 3
 )
 
-func (u *usecase) SearchOriginalURL(ctx context.Context, shortURL string) (string, error) {
-	span, ctx := tracer.StartSpanFromContext(ctx, "d.SearchURLFromShortURL")
-	defer span.Finish()
+func (u *usecase) SearchOriginalURL(
+	ctx context.Context, domain, shortURL, accessToken string,
+) (repository.Destination, error) {
+	ctx, span := tracing.Start(ctx, "d.SearchURLFromShortURL")
+	defer span.End()
 
-	url, err := u.database.SearchURLFromShortURL(ctx, shortURL)
+	dest, err := u.searchDestination(ctx, domain, shortURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to search url from database: %w", err)
+		return repository.Destination{}, err
 	}
 
-	return url, nil
+	if dest.PasswordHash != "" && !u.validAccessToken(accessToken, domain, shortURL, dest.PasswordHash) {
+		return repository.Destination{}, apperr.ErrPasswordRequired
+	}
+
+	if err := u.consumeClick(ctx, &dest); err != nil {
+		return repository.Destination{}, err
+	}
+
+	metrics.RedirectsTotal.WithLabelValues(metrics.ResultHit).Inc()
+
+	return dest, nil
 }
 
-func (u *usecase) GenerateURL(ctx context.Context, originalURL string) (string, error) {
-	span, ctx := tracer.StartSpanFromContext(ctx, "d.GenerateURL")
-	defer span.Finish()
+func (u *usecase) UnlockURL(ctx context.Context, domain, shortURL, password string) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "u.UnlockURL")
+	defer span.End()
+
+	dest, err := u.searchDestination(ctx, domain, shortURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	if dest.PasswordHash == "" {
+		if err := u.consumeClick(ctx, &dest); err != nil {
+			return "", "", err
+		}
+
+		metrics.RedirectsTotal.WithLabelValues(metrics.ResultHit).Inc()
+
+		return dest.URL, "", nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(dest.PasswordHash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return "", "", apperr.ErrPasswordIncorrect
+		}
+
+		return "", "", fmt.Errorf("failed to compare password: %w", err)
+	}
+
+	if err := u.consumeClick(ctx, &dest); err != nil {
+		return "", "", err
+	}
+
+	metrics.RedirectsTotal.WithLabelValues(metrics.ResultHit).Inc()
+
+	token := u.accessToken(domain, shortURL, dest.PasswordHash, time.Now().Add(u.accessTokenTTL))
+
+	return dest.URL, token, nil
+}
+
+func (u *usecase) searchDestination(ctx context.Context, domain, shortURL string) (repository.Destination, error) {
+	dest, err := u.database.SearchURLFromShortURL(ctx, domain, shortURL)
+	if err != nil {
+		if errors.Is(err, apperr.ErrShortURLNotFound) {
+			metrics.RedirectsTotal.WithLabelValues(metrics.ResultMiss).Inc()
+		}
+
+		return repository.Destination{}, fmt.Errorf("failed to search url from database: %w", err)
+	}
+
+	// 使い切った短縮 URL は、パスワードを尋ねる前に断る。
+	if dest.Exhausted() {
+		metrics.RedirectsTotal.WithLabelValues(metrics.ResultExhausted).Inc()
+
+		return repository.Destination{}, apperr.ErrShortURLExhausted
+	}
+
+	return dest, nil
+}
+
+// consumeClick counts the click of the click-limited short url, and updates the clicks of the destination.
+// The clicks checked by searchDestination may be stale, and the count in the transaction is the final one.
+func (u *usecase) consumeClick(ctx context.Context, dest *repository.Destination) error {
+	if dest.MaxClicks == 0 {
+		return nil
+	}
+
+	var left int
+
+	if err := u.txManager.ReadWriteTransaction(ctx, func(ctx context.Context, tx transaction.RWTx) error {
+		var err error
+
+		left, err = u.urlRepo.ConsumeClick(ctx, tx, dest.ID)
+		if err != nil {
+			return fmt.Errorf("failed to consume click: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		if errors.Is(err, apperr.ErrShortURLExhausted) {
+			metrics.RedirectsTotal.WithLabelValues(metrics.ResultExhausted).Inc()
+		}
+
+		return fmt.Errorf("failed to exec txManager.ReadWriteTransaction: %w", err)
+	}
+
+	dest.Clicks = dest.MaxClicks - left
+
+	return nil
+}
+
+// GenerateURLParams is the parameters of GenerateURL.
+type GenerateURLParams struct {
+	Domain      string
+	OriginalURL string
+	// Owner is the one who creates the short url, which is used by DedupPerOwner.
+	Owner string
+	// ForceNew always creates a new short url regardless of the dedup policy.
+	ForceNew bool
+	// Title, Notes and Tags are set only when the short url is newly created.
+	Title string
+	Notes string
+	Tags  []string
+	// Password protects the short url if not empty.
+	// The protected short url is always newly created, and never returned by the dedup policy.
+	Password string
+	// MaxClicks limits the redirects of the short url if not 0 (e.g. 1 for the one-time link).
+	// The click-limited short url is always newly created as well.
+	MaxClicks int
+}
+
+func (u *usecase) GenerateURL(ctx context.Context, params GenerateURLParams) (repository.URL, bool, error) {
+	ctx, span := tracing.Start(ctx, "d.GenerateURL")
+	defer span.End()
+
+	originalURL, err := normalizeURL(params.OriginalURL)
+	if err != nil {
+		return repository.URL{}, false, fmt.Errorf("failed to normalize url: %w", err)
+	}
+
+	newURL := repository.URL{
+		Domain:    params.Domain,
+		URL:       originalURL,
+		Owner:     params.Owner,
+		DedupKey:  u.dedupKey(originalURL, params.Owner),
+		Title:     params.Title,
+		Notes:     params.Notes,
+		Tags:      normalizeTags(params.Tags),
+		MaxClicks: params.MaxClicks,
+	}
+	// 回数制限のある短縮 URL は、共有すると他の作成者の回数を消費してしまう。
+	if params.ForceNew || params.MaxClicks > 0 {
+		newURL.DedupKey = ""
+	}
+
+	if params.Password != "" {
+		newURL.DedupKey = ""
+
+		if newURL.PasswordHash, err = hashPassword(params.Password); err != nil {
+			return repository.URL{}, false, err
+		}
+	}
 
 	maxRetries := 3
 	retries := 0
 
 	for {
 		if retries >= maxRetries {
-			return "", fmt.Errorf("failed to insert short url due to duplicate key error: (retry count: %v)", retries)
+			return repository.URL{}, false, fmt.Errorf(
+				"failed to insert short url due to duplicate key error: (retry count: %v)", retries,
+			)
 		}
 
-		shortURL, err := u.fetchOrGenerateShortURL(ctx, originalURL)
+		record, created, err := u.fetchOrGenerateShortURL(ctx, newURL)
 		if err != nil {
-			var pqErr *pq.Error
-
-			// Error code 23505 means 'unique_violation' error in PostgreSQL.
-			// This error occurs when attempting to insert a short URL that already exists in the database.
+			switch {
+			// The generated short URL already exists in the database.
 			// In this case, regenerate a new short URL and retry the insertion process.
-			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			case errors.Is(err, repository.ErrShortURLAlreadyExists):
+				metrics.SlugCollisionsTotal.Inc()
+				metrics.SlugGenerationRetriesTotal.WithLabelValues(metrics.ReasonShortURLCollision).Inc()
+
+				retries++
+
+				continue
+			// The same URL has been inserted by another request after our select.
+			// In this case, retry so that the existing short URL is selected.
+			case errors.Is(err, repository.ErrURLAlreadyExists):
+				metrics.SlugGenerationRetriesTotal.WithLabelValues(metrics.ReasonURLConflict).Inc()
+
 				retries++
 
 				continue
 			}
 
-			return "", fmt.Errorf("failed to insert short url to database: %w", err)
+			return repository.URL{}, false, fmt.Errorf("failed to insert short url to database: %w", err)
+		}
+
+		// コミット後に登録し、取得の完了は待たない。
+		if created && u.metadataQueue != nil && !u.metadataQueue.Enqueue(record.ID, record.URL) {
+			u.logger.Warnf(ctx, "metadata queue is full, and the page metadata of %d is not fetched", record.ID)
 		}
 
-		return shortURL, nil
+		return record, created, nil
 	}
 }
 
-func (u *usecase) fetchOrGenerateShortURL(ctx context.Context, originalURL string) (string, error) {
-	var shortURL string
+// dedupKey returns the key of the short urls to be shared by the dedup policy,
+// which is empty if the short url is never shared.
+func (u *usecase) dedupKey(originalURL, owner string) string {
+	switch u.dedupPolicy {
+	case DedupPerOwner:
+		// 正規化された URL は空白を含まないため、owner との区切りに空白を使う。
+		return owner + " " + originalURL
+	case DedupNever:
+		return ""
+	default:
+		// DedupGlobal
+		return originalURL
+	}
+}
+
+// fetchOrGenerateShortURL returns the short url with the same dedup key if any,
+// otherwise inserts the new url with a generated short url.
+func (u *usecase) fetchOrGenerateShortURL(
+	ctx context.Context, newURL repository.URL,
+) (repository.URL, bool, error) {
+	var (
+		record  repository.URL
+		created bool
+	)
 
 	if err := u.txManager.ReadWriteTransaction(ctx, func(ctx context.Context, tx transaction.RWTx) error {
 		var err error
 
-		shortURL, err = u.urlRepo.SelectShortURL(ctx, tx, originalURL)
-		if err != nil && !errors.Is(err, apperr.ErrShortURLNotFound) {
-			return fmt.Errorf("failed to select short url from database: %w", err)
-		}
+		if newURL.DedupKey != "" {
+			record, err = u.urlRepo.SelectURLByDedupKey(ctx, tx, newURL.Domain, newURL.DedupKey)
+			if err == nil {
+				return u.fillDetail(ctx, tx, &record)
+			}
 
-		if shortURL != "" {
-			return nil
+			if !errors.Is(err, apperr.ErrShortURLNotFound) {
+				return fmt.Errorf("failed to select short url from database: %w", err)
+			}
 		}
 
-		shortURL, err = u.generateShortURL(shortenedURLLength)
+		newURL.Short, err = u.generateShortURL(shortenedURLLength)
 		if err != nil {
 			return fmt.Errorf("failed to generate random string: %w", err)
 		}
 
-		err = u.urlRepo.InsertURL(ctx, tx, originalURL, shortURL)
+		record, err = u.urlRepo.InsertURL(ctx, tx, newURL)
 		if err != nil {
 			return fmt.Errorf("failed to insert short url to database: %w", err)
 		}
 
+		if err := u.tagRepo.SetTags(ctx, tx, record.ID, newURL.Tags); err != nil {
+			return fmt.Errorf("failed to set tags: %w", err)
+		}
+
+		record.Tags = newURL.Tags
+		created = true
+
 		return nil
 	}); err != nil {
-		return "", fmt.Errorf("failed to exec txManager.ReadWriteTransaction: %w", err)
+		return repository.URL{}, false, fmt.Errorf("failed to exec txManager.ReadWriteTransaction: %w", err)
+	}
+
+	return record, created, nil
+}
+
+func (u *usecase) GetURL(ctx context.Context, domain, shortURL string) (repository.URL, error) {
+	ctx, span := tracing.Start(ctx, "u.GetURL")
+	defer span.End()
+
+	var record repository.URL
+
+	if err := u.txManager.ReadWriteTransaction(ctx, func(ctx context.Context, tx transaction.RWTx) error {
+		var err error
+
+		record, err = u.urlRepo.SelectURL(ctx, tx, domain, shortURL)
+		if err != nil {
+			return fmt.Errorf("failed to select short url from database: %w", err)
+		}
+
+		return u.fillDetail(ctx, tx, &record)
+	}); err != nil {
+		return repository.URL{}, fmt.Errorf("failed to exec txManager.ReadWriteTransaction: %w", err)
+	}
+
+	return record, nil
+}
+
+// UpdateURLParams is the parameters of UpdateURL.
+// The nil fields are unchanged, the empty Tags clears the tags and the empty Password removes the password.
+type UpdateURLParams struct {
+	Domain   string
+	ShortURL string
+	// Owner is the one who requests the update, which must match the owner of the short url.
+	// The short urls without the owner (e.g. shared by the global dedup) are updated only by the admin.
+	Owner string
+	// Admin updates any short url regardless of the owner.
+	Admin    bool
+	Title    *string
+	Notes    *string
+	Tags     []string
+	Password *string
+}
+
+func (u *usecase) UpdateURL(ctx context.Context, params UpdateURLParams) (repository.URL, error) {
+	ctx, span := tracing.Start(ctx, "u.UpdateURL")
+	defer span.End()
+
+	// bcrypt は遅いため、トランザクションの外でハッシュ化する。
+	var passwordHash string
+
+	if params.Password != nil && *params.Password != "" {
+		var err error
+
+		if passwordHash, err = hashPassword(*params.Password); err != nil {
+			return repository.URL{}, err
+		}
+	}
+
+	var record repository.URL
+
+	if err := u.txManager.ReadWriteTransaction(ctx, func(ctx context.Context, tx transaction.RWTx) error {
+		var err error
+
+		record, err = u.urlRepo.SelectURL(ctx, tx, params.Domain, params.ShortURL)
+		if err != nil {
+			return fmt.Errorf("failed to select short url from database: %w", err)
+		}
+
+		if !params.Admin && (record.Owner == "" || record.Owner != params.Owner) {
+			return apperr.ErrNotOwner
+		}
+
+		if params.Title != nil || params.Notes != nil || params.Password != nil {
+			if params.Title != nil {
+				record.Title = *params.Title
+			}
+
+			if params.Notes != nil {
+				record.Notes = *params.Notes
+			}
+
+			if params.Password != nil {
+				record.PasswordHash = passwordHash
+			}
+
+			// パスワード付きの短縮 URL は、重複排除で他の作成者に返さない。
+			if record.PasswordHash != "" {
+				record.DedupKey = ""
+			}
+
+			if err := u.urlRepo.UpdateURL(ctx, tx, record); err != nil {
+				return fmt.Errorf("failed to update short url: %w", err)
+			}
+		}
+
+		if params.Tags != nil {
+			if err := u.tagRepo.SetTags(ctx, tx, record.ID, normalizeTags(params.Tags)); err != nil {
+				return fmt.Errorf("failed to set tags: %w", err)
+			}
+		}
+
+		return u.fillDetail(ctx, tx, &record)
+	}); err != nil {
+		return repository.URL{}, fmt.Errorf("failed to exec txManager.ReadWriteTransaction: %w", err)
+	}
+
+	return record, nil
+}
+
+func (u *usecase) ListURLs(ctx context.Context, filter repository.URLFilter) ([]repository.URL, error) {
+	ctx, span := tracing.Start(ctx, "u.ListURLs")
+	defer span.End()
+
+	filter.Tags = normalizeTags(filter.Tags)
+
+	var urls []repository.URL
+
+	if err := u.txManager.ReadWriteTransaction(ctx, func(ctx context.Context, tx transaction.RWTx) error {
+		var err error
+
+		urls, err = u.urlRepo.ListURLs(ctx, tx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list short urls: %w", err)
+		}
+
+		return u.fillDetails(ctx, tx, urls)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to exec txManager.ReadWriteTransaction: %w", err)
+	}
+
+	return urls, nil
+}
+
+// fillDetails sets the tags and the page metadata of the urls, which are stored apart from the urls.
+func (u *usecase) fillDetails(ctx context.Context, tx transaction.RWTx, urls []repository.URL) error {
+	ids := make([]int, 0, len(urls))
+	for _, url := range urls {
+		ids = append(ids, url.ID)
+	}
+
+	tags, err := u.tagRepo.ListTags(ctx, tx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	pages, err := u.pageRepo.ListPageMetadata(ctx, tx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to list page metadata: %w", err)
+	}
+
+	for i := range urls {
+		urls[i].Tags = tags[urls[i].ID]
+
+		if page, ok := pages[urls[i].ID]; ok {
+			urls[i].Page = &page
+		}
+	}
+
+	return nil
+}
+
+func (u *usecase) fillDetail(ctx context.Context, tx transaction.RWTx, url *repository.URL) error {
+	urls := []repository.URL{*url}
+	if err := u.fillDetails(ctx, tx, urls); err != nil {
+		return err
+	}
+
+	*url = urls[0]
+
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// normalizeTags returns the lowercased, sorted and deduplicated tags.
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+// normalizeURL returns the equivalent form of the url (RFC 3986 section 6),
+// so that the same url is shortened to the same short url.
+// The scheme and the host are lowercased, the default port is removed and the empty path becomes "/".
+func normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
 	}
 
-	return shortURL, nil
+	return u.String(), nil
 }
 
 // [a-zA-Z0-9] からランダムに n 文字の文字列を生成する。
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	gomock "github.com/golang/mock/gomock"
-	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
 	"github.com/kokoichi206-sandbox/url-shortener/model/apperr"
 	"github.com/kokoichi206-sandbox/url-shortener/usecase"
@@ -22,13 +24,17 @@ func Test_Usecase_SearchOriginalURL(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
-		shortURL string
+		shortURL    string
+		accessToken string
 	}
 
+	limited := repository.Destination{ID: 1, URL: "https://example.com", MaxClicks: 3, Clicks: 1}
+
 	testCases := map[string]struct {
 		args             args
 		makeMockDatabase func(m *MockDatabase)
-		want             string
+		makeURLsRepo     func(m *MockURLRepository)
+		want             repository.Destination
 		wantErr          string
 	}{
 		"success": {
@@ -38,10 +44,29 @@ func Test_Usecase_SearchOriginalURL(t *testing.T) {
 			makeMockDatabase: func(m *MockDatabase) {
 				m.
 					EXPECT().
-					SearchURLFromShortURL(gomock.Any(), "R0D").
-					Return("https://example.com", nil)
+					SearchURLFromShortURL(gomock.Any(), "localhost", "R0D").
+					Return(repository.Destination{URL: "https://example.com"}, nil)
 			},
-			want: "https://example.com",
+			want: repository.Destination{URL: "https://example.com"},
+		},
+		"success: click-limited": {
+			args: args{
+				shortURL: "R0D",
+			},
+			makeMockDatabase: func(m *MockDatabase) {
+				m.
+					EXPECT().
+					SearchURLFromShortURL(gomock.Any(), "localhost", "R0D").
+					Return(limited, nil)
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				// 他のリクエストが先に消費していても、トランザクション内の結果が使われること。
+				m.
+					EXPECT().
+					ConsumeClick(gomock.Any(), gomock.Any(), 1).
+					Return(0, nil)
+			},
+			want: repository.Destination{ID: 1, URL: "https://example.com", MaxClicks: 3, Clicks: 3},
 		},
 		"failure: no url in repository": {
 			args: args{
@@ -50,11 +75,55 @@ func Test_Usecase_SearchOriginalURL(t *testing.T) {
 			makeMockDatabase: func(m *MockDatabase) {
 				m.
 					EXPECT().
-					SearchURLFromShortURL(gomock.Any(), "NUL").
-					Return("", apperr.ErrShortURLNotFound)
+					SearchURLFromShortURL(gomock.Any(), "localhost", "NUL").
+					Return(repository.Destination{}, apperr.ErrShortURLNotFound)
 			},
 			wantErr: "failed to search url from database: short url not found",
 		},
+		"failure: password required": {
+			args: args{
+				shortURL: "R0D",
+				// 他の短縮 URL のトークンや不正な値は無視されること。
+				accessToken: "9999999999.invalid",
+			},
+			makeMockDatabase: func(m *MockDatabase) {
+				m.
+					EXPECT().
+					SearchURLFromShortURL(gomock.Any(), "localhost", "R0D").
+					Return(repository.Destination{URL: "https://example.com", PasswordHash: "hash"}, nil)
+			},
+			wantErr: "password required",
+		},
+		"failure: exhausted": {
+			args: args{
+				shortURL: "R0D",
+			},
+			makeMockDatabase: func(m *MockDatabase) {
+				m.
+					EXPECT().
+					SearchURLFromShortURL(gomock.Any(), "localhost", "R0D").
+					Return(repository.Destination{ID: 1, URL: "https://example.com", MaxClicks: 3, Clicks: 3}, nil)
+			},
+			wantErr: "short url has no clicks left",
+		},
+		"failure: exhausted by another request": {
+			args: args{
+				shortURL: "R0D",
+			},
+			makeMockDatabase: func(m *MockDatabase) {
+				m.
+					EXPECT().
+					SearchURLFromShortURL(gomock.Any(), "localhost", "R0D").
+					Return(limited, nil)
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					ConsumeClick(gomock.Any(), gomock.Any(), 1).
+					Return(0, apperr.ErrShortURLExhausted)
+			},
+			wantErr: "failed to consume click: short url has no clicks left",
+		},
 		"failure: db error": {
 			args: args{
 				shortURL: "R0D",
@@ -62,8 +131,8 @@ func Test_Usecase_SearchOriginalURL(t *testing.T) {
 			makeMockDatabase: func(m *MockDatabase) {
 				m.
 					EXPECT().
-					SearchURLFromShortURL(gomock.Any(), "R0D").
-					Return("", errors.New("db error"))
+					SearchURLFromShortURL(gomock.Any(), "localhost", "R0D").
+					Return(repository.Destination{}, errors.New("db error"))
 			},
 			wantErr: "failed to search url from database: db error",
 		},
@@ -83,13 +152,24 @@ func Test_Usecase_SearchOriginalURL(t *testing.T) {
 			m := NewMockDatabase(ctrl)
 			tc.makeMockDatabase(m)
 
+			mu := NewMockURLRepository(ctrl)
+			if tc.makeURLsRepo != nil {
+				tc.makeURLsRepo(mu)
+			}
+
+			txManager := &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			}
+
 			b := bytes.NewBuffer([]byte{})
 			logger.NewBasicLogger(b, "test", "searchOriginalURL")
 
-			u := usecase.New(m, nil, nil, nil)
+			u := usecase.New(m, txManager, mu, nil, nil, nil)
 
 			// Act
-			got, err := u.SearchOriginalURL(context.Background(), tc.args.shortURL)
+			got, err := u.SearchOriginalURL(context.Background(), "localhost", tc.args.shortURL, tc.args.accessToken)
 
 			// Assert
 			assert.Equal(t, tc.want, got, "result does not match")
@@ -102,11 +182,153 @@ func Test_Usecase_SearchOriginalURL(t *testing.T) {
 	}
 }
 
+func Test_Usecase_UnlockURL(t *testing.T) {
+	t.Parallel()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err, "error of GenerateFromPassword should be nil")
+
+	protected := repository.Destination{URL: "https://example.com/internal", PasswordHash: string(hash)}
+
+	testCases := map[string]struct {
+		dest           repository.Destination
+		password       string
+		opts           []usecase.Option
+		want           string
+		wantToken      bool
+		wantErr        string
+		wantSearchErr  string
+		changePassword bool
+	}{
+		"success": {
+			dest:      protected,
+			password:  "correct horse",
+			want:      "https://example.com/internal",
+			wantToken: true,
+		},
+		"success: not protected": {
+			dest: repository.Destination{URL: "https://example.com"},
+			want: "https://example.com",
+		},
+		"success: expired token": {
+			dest:          protected,
+			password:      "correct horse",
+			opts:          []usecase.Option{usecase.WithAccessToken([]byte("key"), -time.Second)},
+			want:          "https://example.com/internal",
+			wantToken:     true,
+			wantSearchErr: "password required",
+		},
+		"success: token revoked by changing password": {
+			dest:           protected,
+			password:       "correct horse",
+			want:           "https://example.com/internal",
+			wantToken:      true,
+			changePassword: true,
+			wantSearchErr:  "password required",
+		},
+		"failure: incorrect password": {
+			dest:     protected,
+			password: "wrong",
+			wantErr:  "password is incorrect",
+		},
+		"failure: exhausted": {
+			dest: repository.Destination{
+				URL: protected.URL, PasswordHash: protected.PasswordHash, MaxClicks: 1, Clicks: 1,
+			},
+			password: "correct horse",
+			wantErr:  "short url has no clicks left",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dest := tc.dest
+
+			m := NewMockDatabase(ctrl)
+			m.
+				EXPECT().
+				SearchURLFromShortURL(gomock.Any(), "localhost", "R0D").
+				DoAndReturn(func(context.Context, string, string) (repository.Destination, error) {
+					return dest, nil
+				}).
+				AnyTimes()
+
+			u := usecase.New(m, nil, nil, nil, nil, nil, tc.opts...)
+
+			// Act
+			got, token, err := u.UnlockURL(context.Background(), "localhost", "R0D", tc.password)
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			assert.Equal(t, tc.wantToken, token != "", "token does not match")
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr, "error does not match")
+
+				return
+			}
+			require.NoError(t, err, "error should be nil")
+
+			if !tc.wantToken {
+				return
+			}
+
+			// 発行されたトークンでパスワード無しに解決できること。
+			if tc.changePassword {
+				dest.PasswordHash += "changed"
+			}
+
+			found, err := u.SearchOriginalURL(context.Background(), "localhost", "R0D", token)
+			if tc.wantSearchErr != "" {
+				assert.ErrorContains(t, err, tc.wantSearchErr, "error does not match")
+			} else {
+				require.NoError(t, err, "error should be nil")
+				assert.Equal(t, tc.want, found.URL, "result does not match")
+			}
+		})
+	}
+}
+
+// newEmptyPageRepo returns the page metadata repository which has no metadata.
+func newEmptyPageRepo(ctrl *gomock.Controller) *MockPageMetadataRepository {
+	m := NewMockPageMetadataRepository(ctrl)
+	m.
+		EXPECT().
+		ListPageMetadata(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(map[int]repository.PageMetadata{}, nil)
+
+	return m
+}
+
 func Test_Usecase_GenerateURL(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
 		originalURL string
+		owner       string
+		forceNew    bool
+		tags        []string
+		password    string
+		maxClicks   int
+	}
+
+	// newURL returns the url to be inserted by the global dedup policy.
+	newURL := func(short string) repository.URL {
+		return repository.URL{
+			Domain:   "localhost",
+			URL:      "https://example.com/",
+			Short:    short,
+			DedupKey: "https://example.com/",
+		}
 	}
 
 	// FIXME: 1 つのテストケースでのみ使う変数をここで定義するのは微妙。
@@ -115,11 +337,15 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 
 	testCases := map[string]struct {
 		args             args
+		opts             []usecase.Option
 		makeMockDatabase func(m *MockDatabase)
 		makeURLsRepo     func(m *MockURLRepository)
+		makeTagRepo      func(m *MockTagRepository)
 		myMockTxManager  *myMockTxManager // FIXME: gomock で引数のメソッドを実行する方法がわからないため自作。
 		genShortURL      func(n int) (string, error)
 		want             string
+		wantTags         []string
+		wantCreated      bool
 		wantErr          string
 	}{
 		"success": {
@@ -129,12 +355,12 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			makeURLsRepo: func(m *MockURLRepository) {
 				m.
 					EXPECT().
-					SelectShortURL(gomock.Any(), gomock.Any(), "https://example.com").
-					Return("", apperr.ErrShortURLNotFound)
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
 				m.
 					EXPECT().
-					InsertURL(gomock.Any(), gomock.Any(), "https://example.com", "R0D").
-					Return(nil)
+					InsertURL(gomock.Any(), gomock.Any(), newURL("R0D")).
+					Return(repository.URL{Short: "R0D"}, nil)
 			},
 			myMockTxManager: &myMockTxManager{
 				// ReadWriteTransaction の中を実行させるために、ここで実行する関数を定義する。
@@ -146,6 +372,210 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			genShortURL: func(n int) (string, error) {
 				return "R0D", nil
 			},
+			want:        "R0D",
+			wantCreated: true,
+		},
+		"success: with tags": {
+			args: args{
+				originalURL: "https://example.com",
+				tags:        []string{" Go", "news", "go"},
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				url := newURL("R0D")
+				// タグは正規化されて保存されること。
+				url.Tags = []string{"go", "news"}
+
+				m.
+					EXPECT().
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+				m.
+					EXPECT().
+					InsertURL(gomock.Any(), gomock.Any(), url).
+					Return(repository.URL{ID: 1, Short: "R0D"}, nil)
+			},
+			makeTagRepo: func(m *MockTagRepository) {
+				m.
+					EXPECT().
+					SetTags(gomock.Any(), gomock.Any(), 1, []string{"go", "news"}).
+					Return(nil)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			genShortURL: func(n int) (string, error) {
+				return "R0D", nil
+			},
+			want:        "R0D",
+			wantTags:    []string{"go", "news"},
+			wantCreated: true,
+		},
+		"success: per-owner policy": {
+			args: args{
+				originalURL: "https://example.com",
+				owner:       "team-a",
+			},
+			opts: []usecase.Option{usecase.WithDedupPolicy(usecase.DedupPerOwner)},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					// owner ごとに既存の短縮 URL が検索されること。
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "team-a https://example.com/").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+				m.
+					EXPECT().
+					InsertURL(gomock.Any(), gomock.Any(), repository.URL{
+						Domain:   "localhost",
+						URL:      "https://example.com/",
+						Short:    "R0D",
+						Owner:    "team-a",
+						DedupKey: "team-a https://example.com/",
+					}).
+					Return(repository.URL{Short: "R0D"}, nil)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			genShortURL: func(n int) (string, error) {
+				return "R0D", nil
+			},
+			want:        "R0D",
+			wantCreated: true,
+		},
+		"success: never policy": {
+			args: args{
+				originalURL: "https://example.com",
+			},
+			opts: []usecase.Option{usecase.WithDedupPolicy(usecase.DedupNever)},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					// 既存の短縮 URL は検索されないこと。
+					InsertURL(gomock.Any(), gomock.Any(), repository.URL{
+						Domain: "localhost",
+						URL:    "https://example.com/",
+						Short:  "R0D",
+					}).
+					Return(repository.URL{Short: "R0D"}, nil)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			genShortURL: func(n int) (string, error) {
+				return "R0D", nil
+			},
+			want:        "R0D",
+			wantCreated: true,
+		},
+		"success: with password": {
+			args: args{
+				originalURL: "https://example.com",
+				password:    "correct horse",
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					// パスワード付きの短縮 URL は共有されないため、既存の短縮 URL は検索されないこと。
+					InsertURL(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ transaction.RWTx, url repository.URL) (repository.URL, error) {
+						if url.DedupKey != "" {
+							return repository.URL{}, fmt.Errorf("unexpected dedup key: %q", url.DedupKey)
+						}
+
+						if err := bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte("correct horse")); err != nil {
+							return repository.URL{}, fmt.Errorf("unexpected password hash: %w", err)
+						}
+
+						return repository.URL{Short: "R0D"}, nil
+					})
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			genShortURL: func(n int) (string, error) {
+				return "R0D", nil
+			},
+			want:        "R0D",
+			wantCreated: true,
+		},
+		"success: with max clicks": {
+			args: args{
+				originalURL: "https://example.com",
+				maxClicks:   1,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				url := newURL("R0D")
+				// 回数制限のある短縮 URL は共有されないこと。
+				url.DedupKey = ""
+				url.MaxClicks = 1
+
+				m.
+					EXPECT().
+					InsertURL(gomock.Any(), gomock.Any(), url).
+					Return(repository.URL{Short: "R0D", MaxClicks: 1}, nil)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			genShortURL: func(n int) (string, error) {
+				return "R0D", nil
+			},
+			want:        "R0D",
+			wantCreated: true,
+		},
+		"success: force new": {
+			args: args{
+				originalURL: "https://example.com",
+				owner:       "team-a",
+				forceNew:    true,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					InsertURL(gomock.Any(), gomock.Any(), repository.URL{
+						Domain: "localhost",
+						URL:    "https://example.com/",
+						Short:  "R0D",
+						Owner:  "team-a",
+					}).
+					Return(repository.URL{Short: "R0D"}, nil)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			genShortURL: func(n int) (string, error) {
+				return "R0D", nil
+			},
+			want:        "R0D",
+			wantCreated: true,
+		},
+		"success: normalized url": {
+			args: args{
+				originalURL: "HTTPS://Example.COM:443",
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{Short: "R0D"}, nil)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
 			want: "R0D",
 		},
 		"success: existing url": {
@@ -155,8 +585,8 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			makeURLsRepo: func(m *MockURLRepository) {
 				m.
 					EXPECT().
-					SelectShortURL(gomock.Any(), gomock.Any(), "https://example.com").
-					Return("R0D", apperr.ErrShortURLNotFound)
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{Short: "R0D"}, nil)
 			},
 			myMockTxManager: &myMockTxManager{
 				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
@@ -172,21 +602,21 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			makeURLsRepo: func(m *MockURLRepository) {
 				m.
 					EXPECT().
-					SelectShortURL(gomock.Any(), gomock.Any(), "https://example.com").
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
 					Times(2).
-					Return("", apperr.ErrShortURLNotFound)
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
 				m.
 					EXPECT().
 					// 1 回目は失敗させる。
-					InsertURL(gomock.Any(), gomock.Any(), "https://example.com", "R0D").
+					InsertURL(gomock.Any(), gomock.Any(), newURL("R0D")).
 					Times(1).
-					Return(fmt.Errorf("test error: %w", &pq.Error{Code: "23505"}))
+					Return(repository.URL{}, fmt.Errorf("test error: %w", repository.ErrShortURLAlreadyExists))
 				m.
 					EXPECT().
 					// 2 回目は成功させる。
-					InsertURL(gomock.Any(), gomock.Any(), "https://example.com", "XYZ").
+					InsertURL(gomock.Any(), gomock.Any(), newURL("XYZ")).
 					Times(1).
-					Return(nil)
+					Return(repository.URL{Short: "XYZ"}, nil)
 			},
 			myMockTxManager: &myMockTxManager{
 				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
@@ -201,7 +631,40 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 				return "XYZ", nil
 			},
 			// 2 回目のリトライで成功したことを確認する。
-			want: "XYZ",
+			want:        "XYZ",
+			wantCreated: true,
+		},
+		"success: url inserted by another request (select on the 2nd try)": {
+			args: args{
+				originalURL: "https://example.com",
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				gomock.InOrder(
+					m.
+						EXPECT().
+						SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+						Return(repository.URL{}, apperr.ErrShortURLNotFound),
+					m.
+						EXPECT().
+						// 他のリクエストが先に同じ URL を登録した。
+						InsertURL(gomock.Any(), gomock.Any(), newURL("R0D")).
+						Return(repository.URL{}, fmt.Errorf("test error: %w", repository.ErrURLAlreadyExists)),
+					m.
+						EXPECT().
+						// 2 回目は登録済みの短縮 URL が取得されること。
+						SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+						Return(repository.URL{Short: "ABC"}, nil),
+				)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			genShortURL: func(n int) (string, error) {
+				return "R0D", nil
+			},
+			want: "ABC",
 		},
 		"failure: select url": {
 			args: args{
@@ -210,8 +673,8 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			makeURLsRepo: func(m *MockURLRepository) {
 				m.
 					EXPECT().
-					SelectShortURL(gomock.Any(), gomock.Any(), "https://example.com").
-					Return("", errors.New("db error"))
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{}, errors.New("db error"))
 			},
 			myMockTxManager: &myMockTxManager{
 				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
@@ -227,8 +690,8 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			makeURLsRepo: func(m *MockURLRepository) {
 				m.
 					EXPECT().
-					SelectShortURL(gomock.Any(), gomock.Any(), "https://example.com").
-					Return("", apperr.ErrShortURLNotFound)
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
 			},
 			myMockTxManager: &myMockTxManager{
 				// ReadWriteTransaction の中を実行させるために、ここで実行する関数を定義する。
@@ -248,12 +711,12 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			makeURLsRepo: func(m *MockURLRepository) {
 				m.
 					EXPECT().
-					SelectShortURL(gomock.Any(), gomock.Any(), "https://example.com").
-					Return("", apperr.ErrShortURLNotFound)
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
 				m.
 					EXPECT().
-					InsertURL(gomock.Any(), gomock.Any(), "https://example.com", gomock.Any()).
-					Return(errors.New("db error"))
+					InsertURL(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(repository.URL{}, errors.New("db error"))
 			},
 			myMockTxManager: &myMockTxManager{
 				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
@@ -262,6 +725,28 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			},
 			wantErr: "failed to exec txManager.ReadWriteTransaction: failed to insert short url to database: db error",
 		},
+		"failure: domain not found": {
+			args: args{
+				originalURL: "https://example.com",
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+				m.
+					EXPECT().
+					InsertURL(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1). // domain が無い場合はリトライされないこと。
+					Return(repository.URL{}, apperr.ErrDomainNotFound)
+			},
+			myMockTxManager: &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			},
+			wantErr: "failed to insert short url to database: .*domain not found",
+		},
 		"failure: duplicate key (many times)": {
 			args: args{
 				originalURL: "https://example.com",
@@ -269,14 +754,14 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			makeURLsRepo: func(m *MockURLRepository) {
 				m.
 					EXPECT().
-					SelectShortURL(gomock.Any(), gomock.Any(), "https://example.com").
+					SelectURLByDedupKey(gomock.Any(), gomock.Any(), "localhost", "https://example.com/").
 					Times(3). // max で 3 回までリトライされること。
-					Return("", apperr.ErrShortURLNotFound)
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
 				m.
 					EXPECT().
-					InsertURL(gomock.Any(), gomock.Any(), "https://example.com", gomock.Any()).
+					InsertURL(gomock.Any(), gomock.Any(), gomock.Any()).
 					Times(3).
-					Return(fmt.Errorf("test error: %w", &pq.Error{Code: "23505"}))
+					Return(repository.URL{}, fmt.Errorf("test error: %w", repository.ErrShortURLAlreadyExists))
 			},
 			myMockTxManager: &myMockTxManager{
 				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
@@ -301,19 +786,37 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 			ur := NewMockURLRepository(ctrl)
 			tc.makeURLsRepo(ur)
 
+			tr := NewMockTagRepository(ctrl)
+			if tc.makeTagRepo != nil {
+				tc.makeTagRepo(tr)
+			} else {
+				tr.EXPECT().SetTags(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+				tr.EXPECT().ListTags(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(map[int][]string{}, nil)
+			}
+
 			b := bytes.NewBuffer([]byte{})
 			logger.NewBasicLogger(b, "test", "generateURL")
 
-			u := usecase.New(nil, tc.myMockTxManager, ur, nil)
+			u := usecase.New(nil, tc.myMockTxManager, ur, tr, newEmptyPageRepo(ctrl), nil, tc.opts...)
 			if tc.genShortURL != nil {
 				u.SetGenerateShortURL(tc.genShortURL)
 			}
 
 			// Act
-			got, err := u.GenerateURL(context.Background(), tc.args.originalURL)
+			got, created, err := u.GenerateURL(context.Background(), usecase.GenerateURLParams{
+				Domain:      "localhost",
+				OriginalURL: tc.args.originalURL,
+				Owner:       tc.args.owner,
+				ForceNew:    tc.args.forceNew,
+				Tags:        tc.args.tags,
+				Password:    tc.args.password,
+				MaxClicks:   tc.args.maxClicks,
+			})
 
 			// Assert
-			assert.Regexp(t, tc.want, got, "result does not match")
+			assert.Equal(t, tc.want, got.Short, "result does not match")
+			assert.Equal(t, tc.wantTags, got.Tags, "tags do not match")
+			assert.Equal(t, tc.wantCreated, created, "created does not match")
 			if tc.wantErr == "" {
 				require.NoError(t, err, "error should be nil")
 			} else {
@@ -323,6 +826,379 @@ func Test_Usecase_GenerateURL(t *testing.T) {
 	}
 }
 
+func Test_Usecase_UpdateURL(t *testing.T) {
+	t.Parallel()
+
+	title, empty, password := "Example", "", "correct horse"
+
+	testCases := map[string]struct {
+		params       usecase.UpdateURLParams
+		makeURLsRepo func(m *MockURLRepository)
+		makeTagRepo  func(m *MockTagRepository)
+		want         repository.URL
+		wantErr      string
+	}{
+		"success": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Admin: true, Title: &title, Tags: []string{"News", "go"},
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", Notes: "note"}, nil)
+				m.
+					EXPECT().
+					// 指定されていない notes は変更されないこと。
+					UpdateURL(gomock.Any(), gomock.Any(), repository.URL{ID: 1, Short: "R0D", Title: "Example", Notes: "note"}).
+					Return(nil)
+			},
+			makeTagRepo: func(m *MockTagRepository) {
+				m.
+					EXPECT().
+					SetTags(gomock.Any(), gomock.Any(), 1, []string{"go", "news"}).
+					Return(nil)
+				m.
+					EXPECT().
+					ListTags(gomock.Any(), gomock.Any(), []int{1}).
+					Return(map[int][]string{1: {"go", "news"}}, nil)
+			},
+			want: repository.URL{ID: 1, Short: "R0D", Title: "Example", Notes: "note", Tags: []string{"go", "news"}},
+		},
+		"success: only tags": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Admin: true, Tags: []string{},
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D"}, nil)
+			},
+			makeTagRepo: func(m *MockTagRepository) {
+				m.
+					EXPECT().
+					// 空のタグはタグを全て削除すること。
+					SetTags(gomock.Any(), gomock.Any(), 1, []string{}).
+					Return(nil)
+				m.
+					EXPECT().
+					ListTags(gomock.Any(), gomock.Any(), []int{1}).
+					Return(map[int][]string{}, nil)
+			},
+			want: repository.URL{ID: 1, Short: "R0D"},
+		},
+		"success: remove password": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Admin: true, Password: &empty,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", PasswordHash: "hash"}, nil)
+				m.
+					EXPECT().
+					UpdateURL(gomock.Any(), gomock.Any(), repository.URL{ID: 1, Short: "R0D"}).
+					Return(nil)
+			},
+			makeTagRepo: func(m *MockTagRepository) {
+				m.
+					EXPECT().
+					ListTags(gomock.Any(), gomock.Any(), []int{1}).
+					Return(map[int][]string{}, nil)
+			},
+			want: repository.URL{ID: 1, Short: "R0D"},
+		},
+		"success: set password": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Admin: true, Password: &password,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", DedupKey: "https://example.com/"}, nil)
+				m.
+					EXPECT().
+					// パスワード付きの短縮 URL は、重複排除で他の作成者に返されないこと。
+					UpdateURL(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ transaction.RWTx, url repository.URL) error {
+						if url.DedupKey != "" {
+							return fmt.Errorf("unexpected dedup key: %q", url.DedupKey)
+						}
+
+						return bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(password))
+					})
+			},
+			makeTagRepo: func(m *MockTagRepository) {
+				m.
+					EXPECT().
+					ListTags(gomock.Any(), gomock.Any(), []int{1}).
+					Return(map[int][]string{}, nil)
+			},
+			want: repository.URL{ID: 1, Short: "R0D"},
+		},
+		"success: by owner": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Owner: "team-a", Title: &title,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", Owner: "team-a"}, nil)
+				m.
+					EXPECT().
+					UpdateURL(gomock.Any(), gomock.Any(), repository.URL{ID: 1, Short: "R0D", Owner: "team-a", Title: "Example"}).
+					Return(nil)
+			},
+			makeTagRepo: func(m *MockTagRepository) {
+				m.
+					EXPECT().
+					ListTags(gomock.Any(), gomock.Any(), []int{1}).
+					Return(map[int][]string{}, nil)
+			},
+			want: repository.URL{ID: 1, Short: "R0D", Owner: "team-a", Title: "Example"},
+		},
+		"failure: not found": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Title: &title,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+			},
+			makeTagRepo: func(m *MockTagRepository) {},
+			wantErr:     "short url not found",
+		},
+		"failure: not owner": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Owner: "team-b", Tags: []string{},
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", Owner: "team-a"}, nil)
+			},
+			// 所有者以外はタグも変更できないこと。
+			makeTagRepo: func(m *MockTagRepository) {},
+			wantErr:     "short url can be updated only by its owner",
+		},
+		"failure: no owner": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Owner: "team-a", Title: &title,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", DedupKey: "https://example.com/"}, nil)
+			},
+			// 所有者のない短縮 URL は共有されているため、管理者以外は変更できないこと。
+			makeTagRepo: func(m *MockTagRepository) {},
+			wantErr:     "short url can be updated only by its owner",
+		},
+		"failure: no owner header": {
+			params: usecase.UpdateURLParams{
+				Domain: "localhost", ShortURL: "R0D", Title: &title,
+			},
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", Owner: "team-a"}, nil)
+			},
+			makeTagRepo: func(m *MockTagRepository) {},
+			wantErr:     "short url can be updated only by its owner",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ur := NewMockURLRepository(ctrl)
+			tc.makeURLsRepo(ur)
+
+			tr := NewMockTagRepository(ctrl)
+			tc.makeTagRepo(tr)
+
+			tm := &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			}
+
+			u := usecase.New(nil, tm, ur, tr, newEmptyPageRepo(ctrl), nil)
+
+			// Act
+			got, err := u.UpdateURL(context.Background(), tc.params)
+
+			// Assert
+			// ハッシュは毎回異なるため、設定されたことのみ確認する。
+			if tc.params.Password != nil && *tc.params.Password != "" {
+				assert.NotEmpty(t, got.PasswordHash, "password hash should be set")
+				got.PasswordHash = ""
+			}
+			assert.Equal(t, tc.want, got, "result does not match")
+			if tc.wantErr == "" {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.ErrorContains(t, err, tc.wantErr, "error does not match")
+			}
+		})
+	}
+}
+
+func Test_Usecase_ListURLs(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ur := NewMockURLRepository(ctrl)
+	ur.
+		EXPECT().
+		// タグの条件は正規化されること。
+		ListURLs(gomock.Any(), gomock.Any(), repository.URLFilter{Tags: []string{"go"}, Limit: 10}).
+		Return([]repository.URL{{ID: 2, Short: "b"}, {ID: 1, Short: "a"}}, nil)
+
+	tr := NewMockTagRepository(ctrl)
+	tr.
+		EXPECT().
+		ListTags(gomock.Any(), gomock.Any(), []int{2, 1}).
+		Return(map[int][]string{1: {"go"}, 2: {"go", "news"}}, nil)
+
+	fetchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	pr := NewMockPageMetadataRepository(ctrl)
+	pr.
+		EXPECT().
+		ListPageMetadata(gomock.Any(), gomock.Any(), []int{2, 1}).
+		Return(map[int]repository.PageMetadata{1: {Title: "A", FetchedAt: fetchedAt}}, nil)
+
+	tm := &myMockTxManager{
+		ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+			return f(ctx, nil)
+		},
+	}
+
+	u := usecase.New(nil, tm, ur, tr, pr, nil)
+
+	// Act
+	got, err := u.ListURLs(context.Background(), repository.URLFilter{Tags: []string{"Go "}, Limit: 10})
+
+	// Assert
+	require.NoError(t, err, "error should be nil")
+	assert.Equal(t, []repository.URL{
+		{ID: 2, Short: "b", Tags: []string{"go", "news"}},
+		{ID: 1, Short: "a", Tags: []string{"go"}, Page: &repository.PageMetadata{Title: "A", FetchedAt: fetchedAt}},
+	}, got, "result does not match")
+}
+
+func Test_Usecase_GetURL(t *testing.T) {
+	t.Parallel()
+
+	fetchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := map[string]struct {
+		makeURLsRepo func(m *MockURLRepository)
+		makeTagRepo  func(m *MockTagRepository)
+		makePageRepo func(m *MockPageMetadataRepository)
+		want         repository.URL
+		wantErr      string
+	}{
+		"success": {
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{ID: 1, Short: "R0D", URL: "https://example.com"}, nil)
+			},
+			makeTagRepo: func(m *MockTagRepository) {
+				m.
+					EXPECT().
+					ListTags(gomock.Any(), gomock.Any(), []int{1}).
+					Return(map[int][]string{1: {"go"}}, nil)
+			},
+			makePageRepo: func(m *MockPageMetadataRepository) {
+				m.
+					EXPECT().
+					ListPageMetadata(gomock.Any(), gomock.Any(), []int{1}).
+					Return(map[int]repository.PageMetadata{1: {Title: "Example", FetchedAt: fetchedAt}}, nil)
+			},
+			want: repository.URL{
+				ID: 1, Short: "R0D", URL: "https://example.com", Tags: []string{"go"},
+				Page: &repository.PageMetadata{Title: "Example", FetchedAt: fetchedAt},
+			},
+		},
+		"failure: not found": {
+			makeURLsRepo: func(m *MockURLRepository) {
+				m.
+					EXPECT().
+					SelectURL(gomock.Any(), gomock.Any(), "localhost", "R0D").
+					Return(repository.URL{}, apperr.ErrShortURLNotFound)
+			},
+			makeTagRepo:  func(m *MockTagRepository) {},
+			makePageRepo: func(m *MockPageMetadataRepository) {},
+			wantErr:      "short url not found",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ur := NewMockURLRepository(ctrl)
+			tc.makeURLsRepo(ur)
+
+			tr := NewMockTagRepository(ctrl)
+			tc.makeTagRepo(tr)
+
+			pr := NewMockPageMetadataRepository(ctrl)
+			tc.makePageRepo(pr)
+
+			tm := &myMockTxManager{
+				ReadWriteTransactionFunc: func(ctx context.Context, f func(ctx context.Context, tx transaction.RWTx) error) error {
+					return f(ctx, nil)
+				},
+			}
+
+			u := usecase.New(nil, tm, ur, tr, pr, nil)
+
+			// Act
+			got, err := u.GetURL(context.Background(), "localhost", "R0D")
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			if tc.wantErr == "" {
+				require.NoError(t, err, "error should be nil")
+			} else {
+				assert.ErrorContains(t, err, tc.wantErr, "error does not match")
+			}
+		})
+	}
+}
+
 func Test_Usecase_GetRoomUsers(t *testing.T) {
 	t.Parallel()
 
@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
 
 	"github.com/kokoichi206-sandbox/url-shortener/domain/repository"
 	"github.com/kokoichi206-sandbox/url-shortener/domain/transaction"
@@ -10,32 +13,71 @@ import (
 
 type Usecase interface {
 	Health(ctx context.Context) error
+	DBStats() repository.DBStats
 
-	SearchOriginalURL(ctx context.Context, shortURL string) (string, error)
-	GenerateURL(ctx context.Context, originalURL string) (string, error)
+	// SearchOriginalURL and GenerateURL handle the short url on the domain (host),
+	// since the same short url can be used on different domains.
+	// SearchOriginalURL returns apperr.ErrPasswordRequired for the password protected short url,
+	// unless the access token issued by UnlockURL is given.
+	// It counts the click of the click-limited short url, or returns apperr.ErrShortURLExhausted.
+	SearchOriginalURL(ctx context.Context, domain, shortURL, accessToken string) (repository.Destination, error)
+	// UnlockURL checks the password of the short url, and returns the original url and the access token,
+	// or apperr.ErrPasswordIncorrect. The token is empty if the short url is not protected.
+	// It counts the click as well as SearchOriginalURL.
+	UnlockURL(ctx context.Context, domain, shortURL, password string) (string, string, error)
+	// GenerateURL returns the short url of the normalized original url,
+	// and whether it is newly created (false if the existing one is returned by the dedup policy).
+	GenerateURL(ctx context.Context, params GenerateURLParams) (repository.URL, bool, error)
+	// GetURL returns the short url with the tags and the page metadata.
+	GetURL(ctx context.Context, domain, shortURL string) (repository.URL, error)
+	// UpdateURL updates the title, the notes, the tags and the password of the short url.
+	UpdateURL(ctx context.Context, params UpdateURLParams) (repository.URL, error)
+	// ListURLs returns the short urls with the tags and the page metadata, newest first.
+	ListURLs(ctx context.Context, filter repository.URLFilter) ([]repository.URL, error)
 }
 
 type usecase struct {
 	database         repository.Database
 	txManager        transaction.TxManager
 	urlRepo          repository.URLRepository
+	tagRepo          repository.TagRepository
+	pageRepo         repository.PageMetadataRepository
 	generateShortURL func(n int) (string, error)
+	dedupPolicy      DedupPolicy
+	metadataQueue    MetadataQueue
+	accessKey        []byte
+	accessTokenTTL   time.Duration
 
 	logger logger.Logger
 }
 
 func New(
-	database repository.Database, txManager transaction.TxManager, urlRepo repository.URLRepository,
-	logger logger.Logger,
+	database repository.Database, txManager transaction.TxManager,
+	urlRepo repository.URLRepository, tagRepo repository.TagRepository,
+	pageRepo repository.PageMetadataRepository,
+	logger logger.Logger, opts ...Option,
 ) *usecase {
 	usecase := &usecase{
 		database:         database,
 		txManager:        txManager,
 		urlRepo:          urlRepo,
+		tagRepo:          tagRepo,
+		pageRepo:         pageRepo,
 		generateShortURL: generateRandomString,
+		dedupPolicy:      DedupGlobal,
+		accessKey:        make([]byte, accessKeySize),
+		accessTokenTTL:   defaultAccessTokenTTL,
 		logger:           logger,
 	}
 
+	if _, err := rand.Read(usecase.accessKey); err != nil {
+		panic(fmt.Sprintf("failed to generate access key: %v", err))
+	}
+
+	for _, opt := range opts {
+		opt(usecase)
+	}
+
 	return usecase
 }
 
@@ -44,3 +86,7 @@ func (u *usecase) Health(ctx context.Context) error {
 	//nolint: wrapcheck
 	return u.database.Health(ctx)
 }
+
+func (u *usecase) DBStats() repository.DBStats {
+	return u.database.Stats()
+}
@@ -11,6 +11,12 @@ type requestIDKye struct{}
 func WithRequestID(parent context.Context) context.Context {
 	reqID := uuid.New().String()
 
+	return ContextWithRequestID(parent, reqID)
+}
+
+// ContextWithRequestID returns the context with the given request id,
+// e.g. the one received from the client.
+func ContextWithRequestID(parent context.Context, reqID string) context.Context {
 	return context.WithValue(parent, requestIDKye{}, reqID)
 }
 
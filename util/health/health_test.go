@@ -0,0 +1,87 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/health"
+)
+
+func Test_Registry_Check(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		checks     map[string]health.CheckFunc
+		wantStatus string
+		wantChecks map[string]string
+		wantErrors map[string]string
+	}{
+		"success: no checks": {
+			wantStatus: health.StatusOK,
+			wantChecks: map[string]string{},
+		},
+		"success": {
+			checks: map[string]health.CheckFunc{
+				"database": func(ctx context.Context) error { return nil },
+				"cache":    func(ctx context.Context) error { return nil },
+			},
+			wantStatus: health.StatusOK,
+			wantChecks: map[string]string{"database": health.StatusOK, "cache": health.StatusOK},
+		},
+		"failure: one check fails": {
+			checks: map[string]health.CheckFunc{
+				"database": func(ctx context.Context) error { return errors.New("connection refused") },
+				"cache":    func(ctx context.Context) error { return nil },
+			},
+			wantStatus: health.StatusFail,
+			wantChecks: map[string]string{"database": health.StatusFail, "cache": health.StatusOK},
+			wantErrors: map[string]string{"database": "connection refused"},
+		},
+		"failure: timeout": {
+			checks: map[string]health.CheckFunc{
+				// context を無視する check でも timeout すること。
+				"slow": func(ctx context.Context) error {
+					time.Sleep(time.Second)
+
+					return nil
+				},
+			},
+			wantStatus: health.StatusFail,
+			wantChecks: map[string]string{"slow": health.StatusFail},
+			wantErrors: map[string]string{"slow": "check timed out"},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			r := health.NewRegistry(50 * time.Millisecond)
+			for name, fn := range tc.checks {
+				r.Register(name, fn)
+			}
+
+			// Act
+			got := r.Check(context.Background())
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, got.Status, "status should be equal")
+
+			statuses := map[string]string{}
+			for name, res := range got.Checks {
+				statuses[name] = res.Status
+				assert.Equal(t, tc.wantErrors[name], res.Error, "error should be equal")
+			}
+
+			assert.Equal(t, tc.wantChecks, statuses, "statuses of checks should be equal")
+		})
+	}
+}
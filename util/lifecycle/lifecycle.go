@@ -0,0 +1,96 @@
+// Package lifecycle manages the readiness of the server and the components closed on shutdown.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+// CloseFunc closes the component until the deadline of the context.
+type CloseFunc func(ctx context.Context) error
+
+type component struct {
+	name  string
+	close CloseFunc
+}
+
+// Manager closes the registered components in the reverse order of the registration,
+// so that a component is closed before the ones it depends on (e.g. http server before database).
+type Manager struct {
+	logger logger.Logger
+	ready  atomic.Bool
+
+	mu         sync.Mutex
+	components []component
+	closed     bool
+}
+
+// New returns the manager which is ready.
+func New(logger logger.Logger) *Manager {
+	m := &Manager{logger: logger}
+	m.ready.Store(true)
+
+	return m
+}
+
+// Ready reports whether the server can receive the traffic.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// SetNotReady makes the readiness probe fail,
+// so that the load balancer stops sending new requests before shutdown.
+func (m *Manager) SetNotReady() {
+	m.ready.Store(false)
+}
+
+// Register adds the component closed on Shutdown.
+func (m *Manager) Register(name string, close CloseFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.components = append(m.components, component{name: name, close: close})
+}
+
+// Shutdown marks the server as not ready and closes the components in reverse order.
+// Every component is closed even if some fail, and the errors are joined.
+// Calling Shutdown more than once does nothing.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.SetNotReady()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+
+	m.closed = true
+
+	var errs []error
+
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c := m.components[i]
+		start := time.Now()
+
+		if err := c.close(ctx); err != nil {
+			m.logger.WithFields(logger.String("component", c.name), logger.Err(err)).
+				Error(ctx, "failed to close component")
+
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", c.name, err))
+
+			continue
+		}
+
+		m.logger.WithFields(logger.String("component", c.name), logger.Duration("elapsed", time.Since(start))).
+			Info(ctx, "component closed")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package lifecycle_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/lifecycle"
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_Manager_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		failures  map[string]error
+		wantOrder []string
+		wantErr   string
+	}{
+		"success": {
+			wantOrder: []string{"http server", "database", "tracer"},
+		},
+		"failure: continues after error": {
+			failures: map[string]error{
+				"database": errors.New("db error"),
+			},
+			wantOrder: []string{"http server", "database", "tracer"},
+			wantErr:   "failed to close database: db error",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			m := lifecycle.New(logger.NewBasicLogger(b, "test", "lifecycle"))
+
+			var closed []string
+
+			for _, c := range []string{"tracer", "database", "http server"} {
+				c := c
+				m.Register(c, func(ctx context.Context) error {
+					// 閉じる時点では not ready になっていること。
+					assert.False(t, m.Ready(), "should not be ready while closing")
+
+					closed = append(closed, c)
+
+					return tc.failures[c]
+				})
+			}
+
+			require.True(t, m.Ready(), "should be ready before shutdown")
+
+			// Act
+			err := m.Shutdown(context.Background())
+			errAgain := m.Shutdown(context.Background())
+
+			// Assert
+			if tc.wantErr != "" {
+				require.Error(t, err, "error should be returned")
+				assert.Equal(t, tc.wantErr, err.Error(), "error message should be equal")
+			} else {
+				require.NoError(t, err, "error should not be returned")
+			}
+
+			require.NoError(t, errAgain, "second shutdown should do nothing")
+			assert.Equal(t, tc.wantOrder, closed, "components should be closed in reverse order")
+		})
+	}
+}
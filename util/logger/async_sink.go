@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type asyncRecord struct {
+	level  Level
+	record []byte
+}
+
+// AsyncSink writes the records to the underlying sink in the background,
+// so that logging never blocks the caller (e.g. the redirect path).
+// When the buffer is full, the records are dropped and counted instead of blocking.
+type AsyncSink struct {
+	sink    Sink
+	buffer  chan asyncRecord
+	dropped atomic.Uint64
+
+	// mu guards closed, so that WriteRecord never sends to the closed buffer.
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewAsyncSink starts the goroutine which drains the buffer of the given size.
+// Close must be called to flush the remaining records.
+func NewAsyncSink(sink Sink, size int) *AsyncSink {
+	a := &AsyncSink{
+		sink:   sink,
+		buffer: make(chan asyncRecord, size),
+		done:   make(chan struct{}),
+	}
+
+	go a.run()
+
+	return a
+}
+
+func (a *AsyncSink) run() {
+	defer close(a.done)
+
+	for r := range a.buffer {
+		//nolint:errcheck
+		a.sink.WriteRecord(r.level, r.record)
+	}
+}
+
+// WriteRecord enqueues the record without blocking.
+// The record is copied, since the caller may reuse it.
+// The records written after Close are dropped.
+func (a *AsyncSink) WriteRecord(lv Level, record []byte) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		a.dropped.Add(1)
+
+		return nil
+	}
+
+	r := asyncRecord{
+		level:  lv,
+		record: append([]byte{}, record...),
+	}
+
+	select {
+	case a.buffer <- r:
+	default:
+		a.dropped.Add(1)
+	}
+
+	return nil
+}
+
+// Dropped returns the number of the records dropped due to the overflow.
+func (a *AsyncSink) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Close flushes the buffered records and stops the goroutine.
+func (a *AsyncSink) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.buffer)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+
+	return nil
+}
@@ -5,102 +5,179 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kokoichi206-sandbox/url-shortener/util"
 )
 
+// callerSkip is the number of frames between the caller of the logger and runtime.Caller.
+const callerSkip = 2
+
 type basicLogger struct {
-	level   Level
-	writer  io.Writer
+	levels  *LevelController
+	sink    Sink
 	host    string
 	service string
-}
+	fields  []Field
 
-type logMessage struct {
-	Host      string `json:"hostname"`
-	Service   string `json:"service"`
-	Message   string `json:"message"`
-	Status    string `json:"status"`
-	RequestID string `json:"request_id,omitempty"`
+	// mu serializes the writes of the loggers derived by With.
+	mu  *sync.Mutex
+	now func() time.Time
 }
 
 func NewBasicLogger(
 	writer io.Writer,
 	host string,
 	service string,
+) Logger {
+	return NewBasicLoggerWithSink(NewWriterSink(writer, Degub), host, service)
+}
+
+// NewBasicLoggerWithSink returns the logger which writes to the sink
+// (e.g. the tee of stdout and a rotating file).
+func NewBasicLoggerWithSink(
+	sink Sink,
+	host string,
+	service string,
 ) Logger {
 	logger := &basicLogger{
-		level:   Info,
-		writer:  writer,
+		levels:  NewLevelController(Info),
+		sink:    sink,
 		host:    host,
 		service: service,
+		mu:      &sync.Mutex{},
+		now:     time.Now,
 	}
 
 	return logger
 }
 
 func (b *basicLogger) Critical(ctx context.Context, msg string) {
-	b.Print(ctx, Critical, msg)
+	b.print(ctx, Critical, msg)
 }
 
 func (b *basicLogger) Error(ctx context.Context, msg string) {
-	b.Print(ctx, Error, msg)
+	b.print(ctx, Error, msg)
 }
 
 func (b *basicLogger) Warn(ctx context.Context, msg string) {
-	b.Print(ctx, Warn, msg)
+	b.print(ctx, Warn, msg)
 }
 
 func (b *basicLogger) Info(ctx context.Context, msg string) {
-	b.Print(ctx, Info, msg)
+	b.print(ctx, Info, msg)
 }
 
 func (b *basicLogger) Debug(ctx context.Context, msg string) {
-	b.Print(ctx, Degub, msg)
+	b.print(ctx, Degub, msg)
 }
 
 func (b *basicLogger) Criticalf(ctx context.Context, msg string, a ...interface{}) {
-	b.Print(ctx, Critical, fmt.Sprintf(msg, a...))
+	b.print(ctx, Critical, fmt.Sprintf(msg, a...))
 }
 
 func (b *basicLogger) Errorf(ctx context.Context, msg string, a ...interface{}) {
-	b.Print(ctx, Error, fmt.Sprintf(msg, a...))
+	b.print(ctx, Error, fmt.Sprintf(msg, a...))
 }
 
 func (b *basicLogger) Warnf(ctx context.Context, msg string, a ...interface{}) {
-	b.Print(ctx, Warn, fmt.Sprintf(msg, a...))
+	b.print(ctx, Warn, fmt.Sprintf(msg, a...))
 }
 
 func (b *basicLogger) Infof(ctx context.Context, msg string, a ...interface{}) {
-	b.Print(ctx, Info, fmt.Sprintf(msg, a...))
+	b.print(ctx, Info, fmt.Sprintf(msg, a...))
 }
 
 func (b *basicLogger) Debugf(ctx context.Context, msg string, a ...interface{}) {
-	b.Print(ctx, Degub, fmt.Sprintf(msg, a...))
+	b.print(ctx, Degub, fmt.Sprintf(msg, a...))
 }
 
 func (b *basicLogger) Print(ctx context.Context, level Level, msg string) {
-	if !shouldPrint(b.level, level) {
+	b.print(ctx, level, msg)
+}
+
+func (b *basicLogger) With(key string, value interface{}) Logger {
+	return b.WithFields(Any(key, value))
+}
+
+func (b *basicLogger) WithFields(fields ...Field) Logger {
+	c := *b
+	c.fields = append(append([]Field{}, b.fields...), fields...)
+
+	return &c
+}
+
+// print writes one record as a line of json.
+// The fields of the logger and the context are written first,
+// so that they never overwrite the fixed keys.
+func (b *basicLogger) print(ctx context.Context, level Level, msg string) {
+	pc, file, line, ok := runtime.Caller(callerSkip)
+	if !b.levels.enabled(level, pc) {
 		return
 	}
 
-	logMsg := logMessage{
-		Host:      b.host,
-		Service:   b.service,
-		Message:   msg,
-		Status:    level.String(),
-		RequestID: util.GetRequestID(ctx),
+	record := map[string]interface{}{}
+
+	for _, f := range b.fields {
+		record[f.Key] = f.Value
+	}
+
+	for _, f := range fieldsFromContext(ctx) {
+		record[f.Key] = f.Value
+	}
+
+	record["timestamp"] = b.now().Format(time.RFC3339Nano)
+	record["hostname"] = b.host
+	record["service"] = b.service
+	record["message"] = msg
+	record["status"] = level.String()
+
+	if ok {
+		record["caller"] = filepath.Base(filepath.Dir(file)) + "/" + filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+
+	if ctx != nil {
+		if reqID := util.GetRequestID(ctx); reqID != "" {
+			record["request_id"] = reqID
+		}
+
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			record["trace_id"] = sc.TraceID().String()
+			record["span_id"] = sc.SpanID().String()
+		}
 	}
 
-	//nolint:errchkjson
-	jsonBytes, _ := json.Marshal(logMsg)
+	jsonBytes, err := json.Marshal(record)
+	if err != nil {
+		// 値が json に変換できない場合でも、メッセージだけは残す。
+		//nolint:errchkjson
+		jsonBytes, _ = json.Marshal(map[string]string{
+			"timestamp": record["timestamp"].(string), //nolint:forcetypeassert
+			"message":   msg,
+			"status":    level.String(),
+			"log_error": err.Error(),
+		})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
 	//nolint:errcheck
-	b.writer.Write(jsonBytes)
+	b.sink.WriteRecord(level, append(jsonBytes, '\n'))
 }
 
 // Set Level after struct is initialized.
 // The default log level is set to Info.
 func (b *basicLogger) SetLevel(lv Level) {
-	b.level = lv
+	b.levels.SetLevel(lv)
+}
+
+func (b *basicLogger) Levels() *LevelController {
+	return b.levels
 }
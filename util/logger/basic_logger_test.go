@@ -0,0 +1,151 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_BasicLogger_Print(t *testing.T) {
+	t.Parallel()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+
+	testCases := map[string]struct {
+		log  func(ctx context.Context, l logger.Logger)
+		ctx  func() context.Context
+		want map[string]interface{}
+	}{
+		"success": {
+			log: func(ctx context.Context, l logger.Logger) {
+				l.Infof(ctx, "hello %s", "world")
+			},
+			want: map[string]interface{}{
+				"timestamp": "2024-01-02T03:04:05Z",
+				"hostname":  "test",
+				"service":   "logger",
+				"message":   "hello world",
+				"status":    "INFO",
+			},
+		},
+		"success: with fields": {
+			log: func(ctx context.Context, l logger.Logger) {
+				l.With("user", "alice").WithFields(logger.Int("count", 3), logger.Duration("latency", 1500*time.Millisecond)).Warn(ctx, "hello")
+			},
+			want: map[string]interface{}{
+				"timestamp": "2024-01-02T03:04:05Z",
+				"hostname":  "test",
+				"service":   "logger",
+				"message":   "hello",
+				"status":    "WARN",
+				"user":      "alice",
+				"count":     float64(3),
+				"latency":   "1.5s",
+			},
+		},
+		"success: fields in context and span": {
+			log: func(ctx context.Context, l logger.Logger) {
+				l.Error(ctx, "hello")
+			},
+			ctx: func() context.Context {
+				ctx := logger.NewContext(context.Background(), logger.String("route", "/:shortURL"))
+
+				return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: traceID,
+					SpanID:  spanID,
+				}))
+			},
+			want: map[string]interface{}{
+				"timestamp": "2024-01-02T03:04:05Z",
+				"hostname":  "test",
+				"service":   "logger",
+				"message":   "hello",
+				"status":    "ERROR",
+				"route":     "/:shortURL",
+				"trace_id":  "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span_id":   "00f067aa0ba902b7",
+			},
+		},
+		"success: fixed keys are not overwritten": {
+			log: func(ctx context.Context, l logger.Logger) {
+				l.With("message", "overwritten").Info(ctx, "hello")
+			},
+			want: map[string]interface{}{
+				"timestamp": "2024-01-02T03:04:05Z",
+				"hostname":  "test",
+				"service":   "logger",
+				"message":   "hello",
+				"status":    "INFO",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			l := logger.NewBasicLogger(b, "test", "logger")
+			logger.SetNow(l, func() time.Time {
+				return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			})
+
+			ctx := context.Background()
+			if tc.ctx != nil {
+				ctx = tc.ctx()
+			}
+
+			// Act
+			tc.log(ctx, l)
+
+			// Assert
+			assert.True(t, strings.HasSuffix(b.String(), "}\n"), "record should end with newline")
+
+			var got map[string]interface{}
+			require.NoError(t, json.Unmarshal(b.Bytes(), &got), "record should be json")
+
+			// 呼び出し元のファイルが caller に入ること。
+			assert.Regexp(t, `^logger/basic_logger_test\.go:\d+$`, got["caller"], "caller does not match")
+			delete(got, "caller")
+
+			assert.Equal(t, tc.want, got, "record does not match")
+		})
+	}
+}
+
+func Test_BasicLogger_Err(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	b := bytes.NewBuffer([]byte{})
+	l := logger.NewBasicLogger(b, "test", "logger")
+
+	// Act
+	l.WithFields(logger.Err(errors.New("db error"))).Error(context.Background(), "failed")
+
+	// Assert
+	var got struct {
+		Error struct {
+			Message string `json:"message"`
+			Stack   string `json:"stack"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &got), "record should be json")
+	assert.Equal(t, "db error", got.Error.Message, "error message does not match")
+	assert.Contains(t, got.Error.Stack, "Test_BasicLogger_Err", "stack should contain the caller")
+}
@@ -0,0 +1,22 @@
+package logger
+
+import "time"
+
+// SetNow fixes the timestamp of the records.
+func SetNow(l Logger, now func() time.Time) {
+	//nolint:forcetypeassert
+	l.(*basicLogger).now = now
+}
+
+// CompressFile compresses the rotated file.
+//
+//nolint:gochecknoglobals
+var CompressFile = compressFile
+
+// SetRotatingFileNow fixes the time used for the names of the rotated files.
+func SetRotatingFileNow(r *RotatingFile, now func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.now = now
+}
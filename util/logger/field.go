@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Field is a key-value pair which is added to the log record.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration is written in the string format like "1.5s".
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value.String()}
+}
+
+// Any is written by encoding/json, so value must be marshalable.
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+type errorValue struct {
+	Message string `json:"message"`
+	Stack   string `json:"stack"`
+}
+
+// Err adds the error message and the stack trace of where Err is called.
+func Err(err error) Field {
+	const maxDepth = 32
+
+	pcs := make([]uintptr, maxDepth)
+	// skip runtime.Callers and Err itself.
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack strings.Builder
+
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&stack, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+
+		if !more {
+			break
+		}
+	}
+
+	var msg string
+	if err != nil {
+		msg = err.Error()
+	}
+
+	return Field{Key: "error", Value: errorValue{Message: msg, Stack: stack.String()}}
+}
+
+type fieldsKey struct{}
+
+// NewContext returns the context with the fields,
+// which are added to every record logged with the context (e.g. in the same request).
+func NewContext(parent context.Context, fields ...Field) context.Context {
+	merged := append(append([]Field{}, fieldsFromContext(parent)...), fields...)
+
+	return context.WithValue(parent, fieldsKey{}, merged)
+}
+
+func fieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, ok := ctx.Value(fieldsKey{}).([]Field)
+	if !ok {
+		return nil
+	}
+
+	return fields
+}
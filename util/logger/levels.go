@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelController holds the log levels which can be changed at runtime.
+// It is shared by the loggers derived by With, and safe for concurrent use.
+type LevelController struct {
+	level atomic.Int32
+	// overrides maps the package (e.g. "repository" or "repository/database")
+	// to its level. The map is never modified after stored.
+	overrides atomic.Pointer[map[string]Level]
+	// mu serializes the updates of overrides.
+	mu sync.Mutex
+}
+
+func NewLevelController(lv Level) *LevelController {
+	c := &LevelController{}
+	c.level.Store(int32(lv))
+	c.overrides.Store(&map[string]Level{})
+
+	return c
+}
+
+// Level returns the default level, which is used when no override matches.
+func (c *LevelController) Level() Level {
+	return Level(c.level.Load())
+}
+
+func (c *LevelController) SetLevel(lv Level) {
+	c.level.Store(int32(lv))
+}
+
+// Overrides returns a copy of the levels per package.
+func (c *LevelController) Overrides() map[string]Level {
+	overrides := *c.overrides.Load()
+
+	copied := make(map[string]Level, len(overrides))
+	for k, v := range overrides {
+		copied[k] = v
+	}
+
+	return copied
+}
+
+// SetOverrides replaces all the levels per package.
+func (c *LevelController) SetOverrides(overrides map[string]Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	copied := make(map[string]Level, len(overrides))
+	for k, v := range overrides {
+		copied[strings.Trim(k, "/")] = v
+	}
+
+	c.overrides.Store(&copied)
+}
+
+// SetOverride sets the level of the package.
+// The package matches its sub packages too, e.g. "repository" matches "repository/database".
+func (c *LevelController) SetOverride(pkg string, lv Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	overrides := c.Overrides()
+	overrides[strings.Trim(pkg, "/")] = lv
+
+	c.overrides.Store(&overrides)
+}
+
+// enabled reports whether the record of lv logged at pc should be printed.
+func (c *LevelController) enabled(lv Level, pc uintptr) bool {
+	overrides := *c.overrides.Load()
+	if len(overrides) == 0 {
+		return shouldPrint(c.Level(), lv)
+	}
+
+	if setting, ok := matchOverride(overrides, packageOf(pc)); ok {
+		return shouldPrint(setting, lv)
+	}
+
+	return shouldPrint(c.Level(), lv)
+}
+
+// matchOverride returns the level of the longest package which matches pkgPath.
+func matchOverride(overrides map[string]Level, pkgPath string) (Level, bool) {
+	var (
+		matched string
+		level   Level
+	)
+
+	for pkg, lv := range overrides {
+		if len(pkg) <= len(matched) {
+			continue
+		}
+
+		if pkgPath == pkg || strings.HasSuffix(pkgPath, "/"+pkg) || strings.Contains(pkgPath, "/"+pkg+"/") {
+			matched = pkg
+			level = lv
+		}
+	}
+
+	return level, matched != ""
+}
+
+// packageOf returns the import path of the function at pc,
+// e.g. "github.com/foo/bar/repository/database".
+func packageOf(pc uintptr) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+
+	// e.g. github.com/foo/bar/repository/database.(*urlRepo).InsertURL
+	name := fn.Name()
+
+	lastSlash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[lastSlash+1:], "."); dot >= 0 {
+		return name[:lastSlash+1+dot]
+	}
+
+	return name
+}
+
+// ParseOverrides parses the levels per package like "repository=debug,handler=warn".
+func ParseOverrides(s string) (map[string]Level, error) {
+	overrides := map[string]Level{}
+
+	for _, kv := range strings.Split(s, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+
+		pkg, name, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid log level override: %s", kv)
+		}
+
+		lv, err := ParseLevel(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		overrides[strings.TrimSpace(pkg)] = lv
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,156 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_LevelController_Overrides(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		level     logger.Level
+		overrides map[string]logger.Level
+		wantPrint bool
+	}{
+		"success: default level": {
+			level:     logger.Error,
+			wantPrint: false,
+		},
+		"success: override of this package": {
+			level: logger.Error,
+			// このテストの package は util/logger_test。
+			overrides: map[string]logger.Level{"util/logger_test": logger.Degub},
+			wantPrint: true,
+		},
+		"success: override of parent package": {
+			level:     logger.Error,
+			overrides: map[string]logger.Level{"util": logger.Degub},
+			wantPrint: true,
+		},
+		"success: the longest override wins": {
+			level: logger.Degub,
+			overrides: map[string]logger.Level{
+				"util":             logger.Degub,
+				"util/logger_test": logger.Error,
+			},
+			wantPrint: false,
+		},
+		"success: override of other package": {
+			level:     logger.Error,
+			overrides: map[string]logger.Level{"repository": logger.Degub},
+			wantPrint: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			b := bytes.NewBuffer([]byte{})
+			l := logger.NewBasicLogger(b, "test", "levels")
+			l.Levels().SetLevel(tc.level)
+			l.Levels().SetOverrides(tc.overrides)
+
+			// Act
+			l.Info(context.Background(), "hello")
+
+			// Assert
+			assert.Equal(t, tc.wantPrint, b.Len() > 0, "print result does not match")
+		})
+	}
+}
+
+func Test_ParseOverrides(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s       string
+		want    map[string]logger.Level
+		wantErr string
+	}{
+		"success": {
+			s: "repository=debug, handler=WARN",
+			want: map[string]logger.Level{
+				"repository": logger.Degub,
+				"handler":    logger.Warn,
+			},
+		},
+		"success: empty": {
+			s:    "",
+			want: map[string]logger.Level{},
+		},
+		"failure: no level": {
+			s:       "repository",
+			wantErr: "invalid log level override: repository",
+		},
+		"failure: unknown level": {
+			s:       "repository=verbose",
+			wantErr: "unknown log level: verbose",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got, err := logger.ParseOverrides(tc.s)
+
+			// Assert
+			if tc.wantErr == "" {
+				require.NoError(t, err, "error should be nil")
+				assert.Equal(t, tc.want, got, "result does not match")
+			} else {
+				assert.Equal(t, tc.wantErr, err.Error(), "result does not match")
+			}
+		})
+	}
+}
+
+// Test_LevelController_Concurrent は -race で実行されることを想定している。
+func Test_LevelController_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	l := logger.NewBasicLogger(io.Discard, "test", "levels")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				l.With("j", j).Info(context.Background(), "hello")
+			}
+		}()
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				l.Levels().SetLevel(logger.Level(j%4 - 1))
+				l.Levels().SetOverride("util", logger.Level(i%4-1))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
@@ -8,6 +8,14 @@ type Logger interface {
 	LogFunc
 	FormatLogFunc
 	Print(ctx context.Context, lv Level, msg string)
+
+	// With returns the logger which adds the key-value pair to every record.
+	With(key string, value interface{}) Logger
+	// WithFields returns the logger which adds the fields to every record.
+	WithFields(fields ...Field) Logger
+
+	// Levels returns the levels which can be changed at runtime.
+	Levels() *LevelController
 }
 
 type LogFunc interface {
@@ -40,7 +48,7 @@ const (
 func (lv Level) String() string {
 	switch lv {
 	case Degub:
-		return "DEGUG"
+		return "DEBUG"
 	case Info:
 		return "INFO"
 	case Warn:
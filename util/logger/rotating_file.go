@@ -0,0 +1,360 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "20060102T150405.000"
+	compressSuffix   = ".gz"
+	// Permission of the log files.
+	logFileMode = 0o644
+)
+
+// RotateConfig is the settings of RotatingFile.
+// Zero values disable each feature.
+type RotateConfig struct {
+	// Path of the current log file.
+	// The rotated files are placed in the same directory (e.g. app-20240102T030405.000.log),
+	// with the counter if rotated twice in the same millisecond (e.g. app-20240102T030405.000-1.log).
+	Path string
+	// MaxSize is the max size of a file in bytes.
+	MaxSize int64
+	// MaxAge is the max duration for which a file is written.
+	MaxAge time.Duration
+	// MaxBackups is the number of the rotated files to keep.
+	MaxBackups int
+	// Compress compresses the rotated files with gzip.
+	Compress bool
+}
+
+// RotatingFile is the io.Writer which rotates the file by size and age.
+// Compressing and removing the old files are done in the background.
+type RotatingFile struct {
+	cfg RotateConfig
+	now func() time.Time
+
+	mu sync.Mutex
+	// file is nil if the file could not be reopened after the rotation.
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	millCh chan struct{}
+	done   chan struct{}
+}
+
+func NewRotatingFile(cfg RotateConfig) (*RotatingFile, error) {
+	r := &RotatingFile{
+		cfg:    cfg,
+		now:    time.Now,
+		millCh: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	go r.runMill()
+
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.cfg.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log dir: %w", err)
+	}
+
+	//nolint:gosec
+	file, err := os.OpenFile(r.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		//nolint:errcheck
+		file.Close()
+
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = r.now()
+
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+
+	// ローテート後にファイルを開けなかった場合は、開き直す。
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	//nolint:wrapcheck
+	return n, err
+}
+
+func (r *RotatingFile) shouldRotate(size int64) bool {
+	// 空のファイルは、1 レコードが MaxSize を超えていてもローテートしない。
+	if r.cfg.MaxSize > 0 && r.size > 0 && r.size+size > r.cfg.MaxSize {
+		return true
+	}
+
+	return r.cfg.MaxAge > 0 && r.now().Sub(r.openedAt) >= r.cfg.MaxAge
+}
+
+// rotate renames the current file and opens the new one.
+// If the rename fails, the current file is reopened so that the next writes do not go to the closed file.
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+
+	if err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	if err := os.Rename(r.cfg.Path, r.backupName(r.now())); err != nil {
+		return errors.Join(fmt.Errorf("failed to rename log file: %w", err), r.open())
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	select {
+	case r.millCh <- struct{}{}:
+	default:
+		// mill is already requested.
+	}
+
+	return nil
+}
+
+// backupName returns the name of the rotated file which does not exist yet,
+// adding the counter if the file rotated in the same millisecond exists.
+func (r *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(r.cfg.Path)
+	prefix := strings.TrimSuffix(r.cfg.Path, ext) + "-" + t.Format(backupTimeFormat)
+
+	name := prefix + ext
+	for i := 1; exists(name) || exists(name+compressSuffix); i++ {
+		name = prefix + "-" + strconv.Itoa(i) + ext
+	}
+
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+
+	return err == nil
+}
+
+func (r *RotatingFile) runMill() {
+	defer close(r.done)
+
+	for range r.millCh {
+		//nolint:errcheck
+		r.mill()
+	}
+}
+
+// mill compresses the rotated files and removes the old ones.
+func (r *RotatingFile) mill() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	if r.cfg.Compress {
+		for i, b := range backups {
+			if strings.HasSuffix(b, compressSuffix) {
+				continue
+			}
+
+			if err := compressFile(b); err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			backups[i] = b + compressSuffix
+		}
+	}
+
+	if r.cfg.MaxBackups > 0 && len(backups) > r.cfg.MaxBackups {
+		// backups は古い順に並んでいる。
+		for _, b := range backups[:len(backups)-r.cfg.MaxBackups] {
+			if err := os.Remove(b); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove old log file: %w", err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups returns the rotated files sorted from the oldest.
+func (r *RotatingFile) backups() ([]string, error) {
+	ext := filepath.Ext(r.cfg.Path)
+	prefix := strings.TrimSuffix(r.cfg.Path, ext) + "-"
+
+	matches, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob log files: %w", err)
+	}
+
+	type backup struct {
+		path    string
+		ts      string
+		counter int
+	}
+
+	var found []backup
+
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(m, prefix), compressSuffix), ext)
+
+		// the time format does not contain "-", so the one after it is the counter.
+		counter := 0
+		if i := strings.LastIndex(ts, "-"); i >= 0 {
+			if counter, err = strconv.Atoi(ts[i+1:]); err != nil {
+				continue
+			}
+
+			ts = ts[:i]
+		}
+
+		if _, err := time.Parse(backupTimeFormat, ts); err == nil {
+			found = append(found, backup{path: m, ts: ts, counter: counter})
+		}
+	}
+
+	// the time format is sortable as string.
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].ts != found[j].ts {
+			return found[i].ts < found[j].ts
+		}
+
+		return found[i].counter < found[j].counter
+	})
+
+	backups := make([]string, 0, len(found))
+	for _, b := range found {
+		backups = append(backups, b.path)
+	}
+
+	return backups, nil
+}
+
+// compressFile compresses the file to path.gz, and removes the original one.
+// The partial path.gz is removed on failure, so that the file is not counted twice as the backups.
+func compressFile(path string) error {
+	//nolint:gosec
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer src.Close()
+
+	//nolint:gosec
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, logFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed log file: %w", err)
+	}
+
+	if err := writeGzip(dst, src); err != nil {
+		//nolint:errcheck
+		os.Remove(path + compressSuffix)
+
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove compressed log file: %w", err)
+	}
+
+	return nil
+}
+
+// writeGzip compresses src into dst, and closes dst.
+func writeGzip(dst *os.File, src io.Reader) error {
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		//nolint:errcheck
+		dst.Close()
+
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		//nolint:errcheck
+		dst.Close()
+
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close compressed log file: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the current file and waits for the background compression.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+
+	close(r.millCh)
+	<-r.done
+
+	//nolint:wrapcheck
+	return err
+}
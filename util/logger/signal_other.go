@@ -0,0 +1,8 @@
+//go:build !unix
+
+package logger
+
+import "context"
+
+// HandleLevelSignals does nothing, since SIGUSR1 and SIGUSR2 are not available.
+func HandleLevelSignals(_ context.Context, _ Logger) {}
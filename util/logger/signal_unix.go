@@ -0,0 +1,41 @@
+//go:build unix
+
+package logger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleLevelSignals changes the default level of the logger by signals until ctx is done.
+// SIGUSR1 makes the logger more verbose (e.g. INFO -> DEBUG),
+// and SIGUSR2 makes it less verbose (e.g. INFO -> WARN).
+func HandleLevelSignals(ctx context.Context, l Logger) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				levels := l.Levels()
+				lv := levels.Level()
+
+				if sig == syscall.SIGUSR1 {
+					lv = max(lv-1, Degub)
+				} else {
+					lv = min(lv+1, Critical)
+				}
+
+				levels.SetLevel(lv)
+				l.Warnf(ctx, "log level is changed to %s by %s", lv, sig)
+			}
+		}
+	}()
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sink receives the encoded records with their level.
+type Sink interface {
+	WriteRecord(lv Level, record []byte) error
+}
+
+type writerSink struct {
+	writer   io.Writer
+	minLevel Level
+}
+
+// NewWriterSink returns the sink which writes the records of minLevel or higher to the writer.
+func NewWriterSink(writer io.Writer, minLevel Level) Sink {
+	return &writerSink{
+		writer:   writer,
+		minLevel: minLevel,
+	}
+}
+
+func (s *writerSink) WriteRecord(lv Level, record []byte) error {
+	if !shouldPrint(s.minLevel, lv) {
+		return nil
+	}
+
+	if _, err := s.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+type teeSink struct {
+	sinks []Sink
+}
+
+// NewTeeSink returns the sink which writes the records to every sink.
+// Each sink filters the records by its own minimum level.
+func NewTeeSink(sinks ...Sink) Sink {
+	return &teeSink{
+		sinks: sinks,
+	}
+}
+
+func (t *teeSink) WriteRecord(lv Level, record []byte) error {
+	var errs []error
+
+	// 1 つの sink が失敗しても、他の sink には書き込む。
+	for _, s := range t.sinks {
+		if err := s.WriteRecord(lv, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseLevel parses the level name like "debug" or "INFO".
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return Degub, nil
+	case "info":
+		return Info, nil
+	case "warn":
+		return Warn, nil
+	case "error":
+		return Error, nil
+	case "critical":
+		return Critical, nil
+	default:
+		return Info, fmt.Errorf("unknown log level: %s", name)
+	}
+}
@@ -0,0 +1,317 @@
+package logger_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+func Test_TeeSink_WriteRecord(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	all := bytes.NewBuffer([]byte{})
+	errOnly := bytes.NewBuffer([]byte{})
+
+	sink := logger.NewTeeSink(
+		logger.NewWriterSink(all, logger.Degub),
+		logger.NewWriterSink(errOnly, logger.Error),
+	)
+
+	// Act
+	require.NoError(t, sink.WriteRecord(logger.Info, []byte("info\n")))
+	require.NoError(t, sink.WriteRecord(logger.Error, []byte("error\n")))
+
+	// Assert
+	// sink ごとの最低レベルでフィルタされること。
+	assert.Equal(t, "info\nerror\n", all.String(), "all records should be written")
+	assert.Equal(t, "error\n", errOnly.String(), "only error records should be written")
+}
+
+// blockingWriter blocks until unblock is closed.
+type blockingWriter struct {
+	unblock chan struct{}
+	buf     bytes.Buffer
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.unblock
+
+	return b.buf.Write(p)
+}
+
+func Test_AsyncSink_WriteRecord(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	w := &blockingWriter{unblock: make(chan struct{})}
+	async := logger.NewAsyncSink(logger.NewWriterSink(w, logger.Degub), 2)
+
+	// Act
+	// 1 件目は writer で block され、2, 3 件目が buffer に入り、残りは捨てられる。
+	// goroutine が 1 件目を取り出すタイミングは不定なので、十分な件数を書き込む。
+	for i := 0; i < 10; i++ {
+		require.NoError(t, async.WriteRecord(logger.Info, []byte("x")), "write should never fail")
+	}
+
+	close(w.unblock)
+	require.NoError(t, async.Close())
+
+	// Assert
+	written := uint64(w.buf.Len())
+	assert.Equal(t, uint64(10), written+async.Dropped(), "records should be written or dropped")
+	assert.LessOrEqual(t, written, uint64(3), "overflowed records should be dropped")
+
+	// Close 後の書き込みは捨てられること。
+	require.NoError(t, async.WriteRecord(logger.Info, []byte("x")))
+	assert.Equal(t, uint64(11), written+async.Dropped(), "records after close should be dropped")
+}
+
+func Test_RotatingFile_Write(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		cfg         func(dir string) logger.RotateConfig
+		writes      []string
+		wantCurrent string
+		wantBackups []string
+	}{
+		"success: rotate by size": {
+			cfg: func(dir string) logger.RotateConfig {
+				return logger.RotateConfig{
+					Path:    filepath.Join(dir, "app.log"),
+					MaxSize: 10,
+				}
+			},
+			writes:      []string{"12345\n", "67890\n", "abcde\n"},
+			wantCurrent: "abcde\n",
+			wantBackups: []string{"12345\n", "67890\n"},
+		},
+		"success: keep max backups with compression": {
+			cfg: func(dir string) logger.RotateConfig {
+				return logger.RotateConfig{
+					Path:       filepath.Join(dir, "app.log"),
+					MaxSize:    10,
+					MaxBackups: 1,
+					Compress:   true,
+				}
+			},
+			writes:      []string{"12345\n", "67890\n", "abcde\n"},
+			wantCurrent: "abcde\n",
+			wantBackups: []string{"67890\n"},
+		},
+		"success: no rotation": {
+			cfg: func(dir string) logger.RotateConfig {
+				return logger.RotateConfig{
+					Path:    filepath.Join(dir, "app.log"),
+					MaxSize: 100,
+					MaxAge:  time.Hour,
+				}
+			},
+			writes:      []string{"12345\n", "67890\n"},
+			wantCurrent: "12345\n67890\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			dir := t.TempDir()
+			cfg := tc.cfg(dir)
+
+			file, err := logger.NewRotatingFile(cfg)
+			require.NoError(t, err)
+
+			// Act
+			for _, w := range tc.writes {
+				_, err := file.Write([]byte(w))
+				require.NoError(t, err)
+				// 同じミリ秒ではカウンタが付き、glob の順序が書き込み順にならないため、時刻をずらす。
+				time.Sleep(2 * time.Millisecond)
+			}
+
+			require.NoError(t, file.Close())
+
+			// Assert
+			current, err := os.ReadFile(cfg.Path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCurrent, string(current), "current file does not match")
+
+			backups, err := filepath.Glob(filepath.Join(dir, "app-*"))
+			require.NoError(t, err)
+
+			got := make([]string, 0, len(backups))
+			for _, b := range backups {
+				got = append(got, readLogFile(t, b, cfg.Compress))
+			}
+
+			assert.Equal(t, len(tc.wantBackups), len(got), "number of backups does not match")
+			if len(tc.wantBackups) > 0 {
+				assert.Equal(t, tc.wantBackups, got, "backups do not match")
+			}
+		})
+	}
+}
+
+func Test_RotatingFile_SameMillisecond(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	dir := t.TempDir()
+	cfg := logger.RotateConfig{
+		Path:       filepath.Join(dir, "app.log"),
+		MaxSize:    10,
+		MaxBackups: 2,
+	}
+
+	file, err := logger.NewRotatingFile(cfg)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger.SetRotatingFileNow(file, func() time.Time { return now })
+
+	// Act
+	// 同じミリ秒にローテートしても、バックアップが上書きされないこと。
+	for _, w := range []string{"12345\n", "67890\n", "abcde\n", "fghij\n"} {
+		_, err := file.Write([]byte(w))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, file.Close())
+
+	// Assert
+	current, err := os.ReadFile(cfg.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "fghij\n", string(current), "current file does not match")
+
+	// 古いものから削除されること。
+	_, err = os.Stat(filepath.Join(dir, "app-20240102T030405.000.log"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "oldest backup should be removed")
+	assert.Equal(t, "67890\n", readLogFile(t, filepath.Join(dir, "app-20240102T030405.000-1.log"), false))
+	assert.Equal(t, "abcde\n", readLogFile(t, filepath.Join(dir, "app-20240102T030405.000-2.log"), false))
+}
+
+func Test_RotatingFile_RotateFailure(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	dir := t.TempDir()
+	cfg := logger.RotateConfig{
+		Path:    filepath.Join(dir, "app.log"),
+		MaxSize: 10,
+	}
+
+	file, err := logger.NewRotatingFile(cfg)
+	require.NoError(t, err)
+
+	_, err = file.Write([]byte("12345\n"))
+	require.NoError(t, err)
+
+	// rename が失敗するように、書き込み中のファイルを消す。
+	require.NoError(t, os.Remove(cfg.Path))
+
+	// Act
+	_, rotateErr := file.Write([]byte("67890\n"))
+	_, err = file.Write([]byte("abcde\n"))
+
+	// Assert
+	require.Error(t, rotateErr, "rotation should fail")
+	require.NoError(t, err, "file should be reopened after the failed rotation")
+	require.NoError(t, file.Close())
+
+	current, err := os.ReadFile(cfg.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "abcde\n", string(current), "current file does not match")
+}
+
+func Test_CompressFile(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		// dir makes the source unreadable.
+		dir     bool
+		wantErr string
+	}{
+		"success": {},
+		"failure: read error": {
+			dir:     true,
+			wantErr: "failed to compress log file",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			path := filepath.Join(t.TempDir(), "app-20240102T030405.000.log")
+			if tc.dir {
+				require.NoError(t, os.Mkdir(path, 0o755))
+			} else {
+				require.NoError(t, os.WriteFile(path, []byte("12345\n"), 0o600))
+			}
+
+			// Act
+			err := logger.CompressFile(path)
+
+			// Assert
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr, "error does not match")
+
+				// 途中まで書き込まれた .gz は削除され、元のファイルは残ること。
+				_, err = os.Stat(path + ".gz")
+				assert.ErrorIs(t, err, os.ErrNotExist, "partial compressed file should be removed")
+				assert.DirExists(t, path, "source should be kept")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "12345\n", readLogFile(t, path+".gz", true), "compressed file does not match")
+			assert.NoFileExists(t, path, "source should be removed")
+		})
+	}
+}
+
+func readLogFile(t *testing.T, path string, compressed bool) string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var r io.Reader = f
+
+	if compressed {
+		require.True(t, strings.HasSuffix(path, ".gz"), "backup should be compressed")
+
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		defer gz.Close()
+
+		r = gz
+	}
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(b)
+}
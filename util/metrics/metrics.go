@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "urlshortener"
+
+// Registry is the registry which every metric of this server is registered to.
+// Go runtime and process metrics are also included.
+//
+//nolint:gochecknoglobals
+var Registry = prometheus.NewRegistry()
+
+// Metrics of http requests.
+//
+//nolint:gochecknoglobals
+var (
+	HTTPRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of http requests by route and status.",
+		},
+		[]string{"method", "route", "status"},
+	)
+	HTTPRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of http requests by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status"},
+	)
+)
+
+// Metrics of short urls.
+//
+//nolint:gochecknoglobals
+var (
+	RedirectsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Number of short url lookups by result (hit, miss or exhausted).",
+		},
+		[]string{"result"},
+	)
+	SlugCollisionsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "slug_collisions_total",
+			Help:      "Number of generated short urls which already existed.",
+		},
+	)
+	SlugGenerationRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "slug_generation_retries_total",
+			Help:      "Number of retries in short url generation by reason.",
+		},
+		[]string{"reason"},
+	)
+)
+
+// Metrics of the background jobs.
+//
+//nolint:gochecknoglobals
+var (
+	MetadataFetchesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "page_metadata_fetches_total",
+			Help:      "Number of page metadata fetches by result (success, error, disallowed or dropped).",
+		},
+		[]string{"result"},
+	)
+)
+
+// Metrics of database transactions.
+//
+//nolint:gochecknoglobals
+var (
+	TxDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_transaction_duration_seconds",
+			Help:      "Duration of read-write transactions by result (commit, rollback or error on commit).",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"result"},
+	)
+	TxRollbacksTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_transaction_rollbacks_total",
+			Help:      "Number of rolled back transactions, including the ones failed to commit.",
+		},
+	)
+)
+
+// Label values.
+const (
+	ResultHit       = "hit"
+	ResultMiss      = "miss"
+	ResultExhausted = "exhausted"
+	ResultCommit    = "commit"
+	ResultRollback  = "rollback"
+
+	ResultSuccess    = "success"
+	ResultError      = "error"
+	ResultDisallowed = "disallowed"
+	ResultDropped    = "dropped"
+
+	ReasonShortURLCollision = "short_url_collision"
+	ReasonURLConflict       = "url_conflict"
+)
+
+//nolint:gochecknoinits
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		RedirectsTotal,
+		SlugCollisionsTotal,
+		SlugGenerationRetriesTotal,
+		MetadataFetchesTotal,
+		TxDuration,
+		TxRollbacksTotal,
+	)
+}
+
+// Handler returns the http handler which serves the metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// NewLogDroppedCollector returns a collector which exports the number of the log records
+// dropped by the async writer.
+func NewLogDroppedCollector(dropped func() uint64) prometheus.Collector {
+	return prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "log_records_dropped_total",
+			Help:      "Number of log records dropped due to the overflow of the async writer.",
+		},
+		func() float64 {
+			return float64(dropped())
+		},
+	)
+}
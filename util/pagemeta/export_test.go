@@ -0,0 +1,8 @@
+package pagemeta
+
+import "strings"
+
+// RobotsAllowed reports whether robots.txt allows the path for the product token.
+func RobotsAllowed(robotsTxt, token, path string) bool {
+	return parseRobots(strings.NewReader(robotsTxt), token).allowed(path)
+}
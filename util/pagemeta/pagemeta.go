@@ -0,0 +1,197 @@
+// Package pagemeta fetches the metadata (title, description and Open Graph image) of the web pages.
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Metadata is the metadata of the web page.
+type Metadata struct {
+	Title       string
+	Description string
+	// ImageURL is the absolute url of the Open Graph image.
+	ImageURL string
+}
+
+// Fetcher fetches the metadata of the web page.
+type Fetcher interface {
+	Fetch(ctx context.Context, pageURL string) (Metadata, error)
+}
+
+var (
+	// ErrDisallowed is returned when robots.txt of the site disallows the page.
+	ErrDisallowed = errors.New("disallowed by robots.txt")
+	// ErrNotHTML is returned when the page is not an html document.
+	ErrNotHTML = errors.New("not an html document")
+	// ErrForbiddenAddress is returned when the host resolves to a private address.
+	ErrForbiddenAddress = errors.New("forbidden address")
+)
+
+const (
+	defaultTimeout   = 5 * time.Second
+	defaultMaxBytes  = 1 << 20
+	defaultUserAgent = "url-shortener/1.0"
+
+	maxRedirects = 5
+)
+
+// HTTPFetcher fetches the pages over http, honoring robots.txt of the sites.
+// The hosts resolving to the loopback, private and link-local addresses are refused by default,
+// not to let the clients reach the internal network through the server.
+type HTTPFetcher struct {
+	client       *http.Client
+	timeout      time.Duration
+	maxBytes     int64
+	userAgent    string
+	allowPrivate bool
+	robots       *robotsCache
+}
+
+type Option func(f *HTTPFetcher)
+
+// WithTimeout sets the deadline of a Fetch including robots.txt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(f *HTTPFetcher) {
+		f.timeout = timeout
+	}
+}
+
+// WithMaxBytes sets the max size of the page to be read.
+// The rest of the page is ignored, since the metadata are usually at the beginning.
+func WithMaxBytes(n int64) Option {
+	return func(f *HTTPFetcher) {
+		f.maxBytes = n
+	}
+}
+
+// WithUserAgent sets the User-Agent header, whose product token (e.g. "url-shortener")
+// selects the group in robots.txt.
+func WithUserAgent(userAgent string) Option {
+	return func(f *HTTPFetcher) {
+		f.userAgent = userAgent
+	}
+}
+
+// WithAllowPrivateNetworks allows the pages on the private networks, which is meant for tests.
+func WithAllowPrivateNetworks(allow bool) Option {
+	return func(f *HTTPFetcher) {
+		f.allowPrivate = allow
+	}
+}
+
+func NewHTTPFetcher(opts ...Option) *HTTPFetcher {
+	f := &HTTPFetcher{
+		timeout:   defaultTimeout,
+		maxBytes:  defaultMaxBytes,
+		userAgent: defaultUserAgent,
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	dialer := &net.Dialer{Control: f.checkAddress}
+
+	//nolint:forcetypeassert
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// プロキシ経由だと接続先のアドレスを検査できないため、使わない。
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	f.client = &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+
+			return f.checkRobots(req.Context(), req.URL)
+		},
+	}
+	f.robots = newRobotsCache(&http.Client{Transport: transport}, f.userAgent)
+
+	return f
+}
+
+// Fetch returns the metadata of the page.
+// The empty fields mean the page does not have them.
+func (f *HTTPFetcher) Fetch(ctx context.Context, pageURL string) (Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Metadata{}, fmt.Errorf("invalid url: %q", pageURL)
+	}
+
+	if err := f.checkRobots(ctx, u); err != nil {
+		return Metadata{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to get page: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || 300 <= res.StatusCode {
+		return Metadata{}, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, fmt.Errorf("%w: %q", ErrNotHTML, mediaType)
+	}
+
+	return parse(io.LimitReader(res.Body, f.maxBytes), res.Request.URL), nil
+}
+
+func (f *HTTPFetcher) checkRobots(ctx context.Context, u *url.URL) error {
+	allowed, err := f.robots.allowed(ctx, u)
+	if err != nil {
+		return fmt.Errorf("failed to check robots.txt: %w", err)
+	}
+
+	if !allowed {
+		return ErrDisallowed
+	}
+
+	return nil
+}
+
+// checkAddress refuses to connect to the non-public addresses, which is checked after DNS resolution.
+func (f *HTTPFetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	if f.allowPrivate {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
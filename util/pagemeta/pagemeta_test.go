@@ -0,0 +1,165 @@
+package pagemeta_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/pagemeta"
+)
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+	<title>
+		Example &amp; Co.
+	</title>
+	<meta name="description" content="An example page.">
+	<meta property="og:title" content="OG title">
+	<meta property="og:image" content="/images/og.png">
+</head>
+<body><title>not a title</title></body>
+</html>`
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(page))
+	})
+	mux.HandleFunc("/og-only", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><meta property="og:title" content="OG title"><meta property="og:description" content="OG description">`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<head><!--" + strings.Repeat("x", 1024) + "--><title>too late</title></head>"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/private/page", http.StatusFound)
+	})
+	mux.HandleFunc("/private/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(page))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func Test_HTTPFetcher_Fetch(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer(t)
+
+	testCases := map[string]struct {
+		path    string
+		opts    []pagemeta.Option
+		want    pagemeta.Metadata
+		wantErr error
+		wantMsg string
+	}{
+		"success": {
+			path: "/page",
+			want: pagemeta.Metadata{
+				Title:       "Example & Co.",
+				Description: "An example page.",
+				ImageURL:    srv.URL + "/images/og.png",
+			},
+		},
+		"success: open graph fallback": {
+			path: "/og-only",
+			want: pagemeta.Metadata{Title: "OG title", Description: "OG description"},
+		},
+		"success: size limit": {
+			path: "/large",
+			opts: []pagemeta.Option{pagemeta.WithMaxBytes(512)},
+			want: pagemeta.Metadata{},
+		},
+		"failure: disallowed by robots.txt": {
+			path:    "/private/page",
+			wantErr: pagemeta.ErrDisallowed,
+		},
+		"failure: redirected to the disallowed page": {
+			path:    "/redirect",
+			wantErr: pagemeta.ErrDisallowed,
+		},
+		"failure: not html": {
+			path:    "/json",
+			wantErr: pagemeta.ErrNotHTML,
+		},
+		"failure: not found": {
+			path:    "/not-found",
+			wantMsg: "unexpected status: 404 Not Found",
+		},
+		"failure: timeout": {
+			path:    "/slow",
+			opts:    []pagemeta.Option{pagemeta.WithTimeout(50 * time.Millisecond)},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			f := pagemeta.NewHTTPFetcher(append([]pagemeta.Option{pagemeta.WithAllowPrivateNetworks(true)}, tc.opts...)...)
+
+			// Act
+			got, err := f.Fetch(context.Background(), srv.URL+tc.path)
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+
+			switch {
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr, "error does not match")
+			case tc.wantMsg != "":
+				assert.ErrorContains(t, err, tc.wantMsg, "error does not match")
+			default:
+				require.NoError(t, err, "error should be nil")
+			}
+		})
+	}
+}
+
+func Test_HTTPFetcher_Fetch_PrivateNetwork(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	srv := newServer(t)
+	f := pagemeta.NewHTTPFetcher()
+
+	// Act
+	_, err := f.Fetch(context.Background(), srv.URL+"/page")
+
+	// Assert
+	assert.ErrorIs(t, err, pagemeta.ErrForbiddenAddress, "loopback address should be refused")
+}
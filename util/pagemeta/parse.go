@@ -0,0 +1,137 @@
+package pagemeta
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Limits of the metadata, not to store huge texts from the pages.
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+	maxImageURLLength    = 2048
+)
+
+// parse reads the metadata in <head>, where base is the url of the page to resolve the relative image url.
+// The broken or truncated html is parsed as far as possible.
+func parse(r io.Reader, base *url.URL) Metadata {
+	var (
+		title, description, ogTitle, ogDescription, ogImage string
+		inTitle, titleDone                                  bool
+	)
+
+	z := html.NewTokenizer(r)
+
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF or the size limit.
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = !titleDone
+			case atom.Meta:
+				if !hasAttr {
+					continue
+				}
+
+				attrs := attrsOf(z)
+				content := attrs["content"]
+
+				switch {
+				case strings.EqualFold(attrs["name"], "description"):
+					description = firstNonEmpty(description, content)
+				case attrs["property"] == "og:title":
+					ogTitle = firstNonEmpty(ogTitle, content)
+				case attrs["property"] == "og:description":
+					ogDescription = firstNonEmpty(ogDescription, content)
+				case attrs["property"] == "og:image" || attrs["property"] == "og:image:url":
+					ogImage = firstNonEmpty(ogImage, content)
+				}
+			case atom.Body:
+				// メタデータは <head> にのみあるため、本文は読まない。
+				break loop
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle, titleDone = false, true
+			case atom.Head:
+				break loop
+			}
+		}
+	}
+
+	return Metadata{
+		Title:       truncate(firstNonEmpty(clean(title), clean(ogTitle)), maxTitleLength),
+		Description: truncate(firstNonEmpty(clean(description), clean(ogDescription)), maxDescriptionLength),
+		ImageURL:    resolveImage(base, strings.TrimSpace(ogImage)),
+	}
+}
+
+func attrsOf(z *html.Tokenizer) map[string]string {
+	attrs := map[string]string{}
+
+	for {
+		key, val, more := z.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(val)
+
+		if !more {
+			return attrs
+		}
+	}
+}
+
+// resolveImage returns the absolute http(s) url of the image, or empty if invalid.
+func resolveImage(base *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+
+	u, err := base.Parse(image)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	if s := u.String(); len(s) <= maxImageURLLength {
+		return s
+	}
+
+	return ""
+}
+
+// clean collapses the whitespaces including the newlines.
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return string([]rune(s)[:n])
+}
+
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+
+	return b
+}
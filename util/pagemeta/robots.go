@@ -0,0 +1,211 @@
+package pagemeta
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	robotsCacheTTL  = time.Hour
+	maxRobotsBytes  = 512 << 10
+	maxRobotsCached = 10000
+)
+
+// rule is an Allow or Disallow line of robots.txt.
+type rule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// rules are the rules of robots.txt applied to this fetcher.
+type rules []rule
+
+// allowed reports whether the path is allowed by the longest matching rule (RFC 9309).
+// Allow wins when Allow and Disallow are the same length.
+func (rs rules) allowed(path string) bool {
+	allowed, longest := true, -1
+
+	for _, r := range rs {
+		if !r.re.MatchString(path) {
+			continue
+		}
+
+		if n := len(r.pattern); n > longest || (n == longest && r.allow) {
+			allowed, longest = r.allow, n
+		}
+	}
+
+	return allowed
+}
+
+// parseRobots returns the rules of the groups for the product token,
+// or the groups for "*" if there is no such group.
+func parseRobots(r io.Reader, token string) rules {
+	var (
+		own, wildcard rules
+		hasOwn        bool
+		// the user agents of the current group, which ends at the next user-agent line after the rules.
+		agents  []string
+		inRules bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+
+			agents = append(agents, strings.ToLower(value))
+
+			if strings.EqualFold(value, token) {
+				hasOwn = true
+			}
+		case "allow", "disallow":
+			inRules = true
+
+			// 空の Disallow は全て許可する意味のため、ルールとしては扱わない。
+			if value == "" {
+				continue
+			}
+
+			r := rule{allow: key == "allow", pattern: value, re: compilePattern(value)}
+
+			for _, agent := range agents {
+				switch agent {
+				case strings.ToLower(token):
+					own = append(own, r)
+				case "*":
+					wildcard = append(wildcard, r)
+				}
+			}
+		}
+	}
+
+	if hasOwn {
+		return own
+	}
+
+	return wildcard
+}
+
+// compilePattern converts the path pattern, where "*" matches any characters and "$" matches the end.
+func compilePattern(pattern string) *regexp.Regexp {
+	end := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	re := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if end {
+		re += "$"
+	}
+
+	return regexp.MustCompile(re)
+}
+
+type robotsEntry struct {
+	rules   rules
+	expires time.Time
+}
+
+// robotsCache fetches and caches robots.txt per origin.
+type robotsCache struct {
+	client    *http.Client
+	userAgent string
+	token     string
+
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+}
+
+func newRobotsCache(client *http.Client, userAgent string) *robotsCache {
+	// "url-shortener/1.0" の場合は "url-shortener" が robots.txt での名前になる。
+	token, _, _ := strings.Cut(userAgent, "/")
+
+	return &robotsCache{
+		client:    client,
+		userAgent: userAgent,
+		token:     token,
+		entries:   map[string]robotsEntry{},
+	}
+}
+
+// allowed reports whether robots.txt of the site allows the url.
+// Missing robots.txt (4xx) allows everything, and the unreachable one (e.g. 5xx) is an error.
+func (c *robotsCache) allowed(ctx context.Context, u *url.URL) (bool, error) {
+	origin := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[origin]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		rs, err := c.fetch(ctx, origin)
+		if err != nil {
+			return false, err
+		}
+
+		entry = robotsEntry{rules: rs, expires: time.Now().Add(robotsCacheTTL)}
+
+		c.mu.Lock()
+		// 多数のサイトを扱っても無制限に増えないよう、上限に達したら作り直す。
+		if len(c.entries) >= maxRobotsCached {
+			c.entries = map[string]robotsEntry{}
+		}
+		c.entries[origin] = entry
+		c.mu.Unlock()
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return entry.rules.allowed(path), nil
+}
+
+func (c *robotsCache) fetch(ctx context.Context, origin string) (rules, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get robots.txt: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case 200 <= res.StatusCode && res.StatusCode < 300:
+		return parseRobots(io.LimitReader(res.Body, maxRobotsBytes), c.token), nil
+	case 400 <= res.StatusCode && res.StatusCode < 500:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status of robots.txt: %s", res.Status)
+	}
+}
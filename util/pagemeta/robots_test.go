@@ -0,0 +1,103 @@
+package pagemeta_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/pagemeta"
+)
+
+func Test_RobotsAllowed(t *testing.T) {
+	t.Parallel()
+
+	const robotsTxt = `
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: other-bot
+User-agent: url-shortener
+Disallow: /only-for-us
+Disallow:
+`
+
+	testCases := map[string]struct {
+		robotsTxt string
+		token     string
+		path      string
+		want      bool
+	}{
+		"success: no rule matches": {
+			robotsTxt: robotsTxt,
+			token:     "another",
+			path:      "/index.html",
+			want:      true,
+		},
+		"success: disallowed by prefix": {
+			robotsTxt: robotsTxt,
+			token:     "another",
+			path:      "/private/doc",
+			want:      false,
+		},
+		"success: longest allow wins": {
+			robotsTxt: robotsTxt,
+			token:     "another",
+			path:      "/private/public/doc",
+			want:      true,
+		},
+		"success: wildcard and end anchor": {
+			robotsTxt: robotsTxt,
+			token:     "another",
+			path:      "/docs/a.pdf",
+			want:      false,
+		},
+		"success: end anchor does not match the longer path": {
+			robotsTxt: robotsTxt,
+			token:     "another",
+			path:      "/docs/a.pdf?download=1",
+			want:      true,
+		},
+		"success: own group replaces the wildcard group": {
+			robotsTxt: robotsTxt,
+			token:     "URL-Shortener",
+			path:      "/private/doc",
+			want:      true,
+		},
+		"success: own group disallows": {
+			robotsTxt: robotsTxt,
+			token:     "url-shortener",
+			path:      "/only-for-us",
+			want:      false,
+		},
+		"success: empty robots.txt": {
+			robotsTxt: "",
+			token:     "url-shortener",
+			path:      "/private",
+			want:      true,
+		},
+		"success: allow wins the tie": {
+			robotsTxt: "User-agent: *\nDisallow: /page\nAllow: /page\n",
+			token:     "url-shortener",
+			path:      "/page",
+			want:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got := pagemeta.RobotsAllowed(tc.robotsTxt, tc.token, tc.path)
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package reporter forwards the internal errors to the error tracking services.
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Reporter reports the internal errors (e.g. panics and 5xx) of the requests.
+// Report must not block for a long time, since it is called in the request path.
+type Reporter interface {
+	Report(ctx context.Context, err error)
+}
+
+// PanicError is the error reported when the request panics.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+type nop struct{}
+
+// Nop returns the reporter which discards every error.
+func Nop() Reporter {
+	return nop{}
+}
+
+func (nop) Report(context.Context, error) {}
+
+// Memory keeps the reported errors in memory, which is used for tests.
+type Memory struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Report(_ context.Context, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.errs = append(m.errs, err)
+}
+
+// Errors returns the reported errors in order.
+func (m *Memory) Errors() []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]error{}, m.errs...)
+}
@@ -0,0 +1,7 @@
+package throttle
+
+import "time"
+
+func (l *Limiter) SetNow(now func() time.Time) {
+	l.now = now
+}
@@ -0,0 +1,91 @@
+// Package throttle limits the attempts per key, e.g. the password attempts per short url and client.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// maxEntries bounds the memory, where the expired entries are swept when it is reached.
+const maxEntries = 100000
+
+type entry struct {
+	attempts int
+	// expires is the end of the window, which starts at the first attempt.
+	expires time.Time
+}
+
+// Limiter allows up to max attempts per key in the window, unless a success resets them.
+// The key is blocked after that until the window ends.
+type Limiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// New returns the limiter, which never blocks if max is 0.
+func New(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:     max,
+		window:  window,
+		now:     time.Now,
+		entries: map[string]entry{},
+	}
+}
+
+// Allow counts an attempt of the key, and reports whether it can try, and how long to wait if not.
+// The attempt is counted before it is checked (e.g. by bcrypt), so that the concurrent attempts
+// never exceed max, and Reset must be called when it succeeds.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.max <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.expires) {
+		if len(l.entries) >= maxEntries {
+			l.sweep(now)
+		}
+
+		e = entry{expires: now.Add(l.window)}
+	}
+
+	if e.attempts >= l.max {
+		return false, e.expires.Sub(now)
+	}
+
+	e.attempts++
+	l.entries[key] = e
+
+	return true, 0
+}
+
+// Reset forgets the attempts of the key, e.g. after a success.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+// sweep removes the expired entries, or all of them if none is expired.
+func (l *Limiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if !now.Before(e.expires) {
+			delete(l.entries, key)
+		}
+	}
+
+	// 期限切れが無い場合も無制限に増えないよう、作り直す。
+	if len(l.entries) >= maxEntries {
+		l.entries = map[string]entry{}
+	}
+}
@@ -0,0 +1,121 @@
+package throttle_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/throttle"
+)
+
+func Test_Limiter(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		max            int
+		attempts       int
+		elapsed        time.Duration
+		reset          bool
+		want           bool
+		wantRetryAfter time.Duration
+	}{
+		"success: under the max": {
+			max:      3,
+			attempts: 2,
+			want:     true,
+		},
+		"success: window ended": {
+			max:      3,
+			attempts: 3,
+			elapsed:  time.Minute,
+			want:     true,
+		},
+		"success: reset": {
+			max:      3,
+			attempts: 3,
+			reset:    true,
+			want:     true,
+		},
+		"success: disabled": {
+			max:      0,
+			attempts: 10,
+			want:     true,
+		},
+		"failure: blocked": {
+			max:            3,
+			attempts:       3,
+			elapsed:        20 * time.Second,
+			want:           false,
+			wantRetryAfter: 40 * time.Second,
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			l := throttle.New(tc.max, time.Minute)
+			l.SetNow(func() time.Time { return now })
+
+			for i := 0; i < tc.attempts; i++ {
+				l.Allow("key")
+			}
+
+			if tc.reset {
+				l.Reset("key")
+			}
+
+			now = now.Add(tc.elapsed)
+
+			// Act
+			got, retryAfter := l.Allow("key")
+
+			// Assert
+			assert.Equal(t, tc.want, got, "result does not match")
+			assert.Equal(t, tc.wantRetryAfter, retryAfter, "retry after does not match")
+
+			other, _ := l.Allow("other")
+			assert.True(t, other, "other keys should not be blocked")
+		})
+	}
+}
+
+func Test_Limiter_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	l := throttle.New(5, time.Minute)
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+
+	// Act
+	// 確認前に数えるため、同時の試行でも max を超えて許可されないこと。
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if ok, _ := l.Allow("key"); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, int32(5), allowed.Load(), "allowed attempts does not match")
+}
@@ -0,0 +1,118 @@
+// Package tlsutil provides the helpers to serve https in-process.
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+)
+
+// defaultCheckInterval is the minimum interval to check whether the files are rotated.
+const defaultCheckInterval = 10 * time.Second
+
+// CertReloader serves the certificate, which is reloaded when the files are rotated
+// (e.g. renewed by cert-manager or certbot) without restarting the server.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   logger.Logger
+
+	checkInterval time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTimes  [2]time.Time
+	checkedAt time.Time
+}
+
+// NewCertReloader loads the certificate, and returns an error if it is invalid.
+func NewCertReloader(certFile, keyFile string, logger logger.Logger) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		logger:        logger,
+		checkInterval: defaultCheckInterval,
+		now:           time.Now,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.
+// The files are checked at most once per interval, and the old certificate is kept if the new one is invalid.
+func (r *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.checkedAt) < r.checkInterval {
+		return r.cert, nil
+	}
+
+	r.checkedAt = now
+
+	modTimes, err := r.stat()
+	if err != nil || modTimes == r.modTimes {
+		return r.cert, nil
+	}
+
+	// cert と key が別々に書き換えられる途中で読むと失敗するが、次回の確認で再度読み込む。
+	if err := r.loadLocked(); err != nil {
+		r.logger.WithFields(logger.Err(err)).Error(context.Background(), "failed to reload certificate, keep using the old one")
+
+		return r.cert, nil
+	}
+
+	r.logger.Info(context.Background(), "certificate is reloaded")
+
+	return r.cert, nil
+}
+
+func (r *CertReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.loadLocked()
+}
+
+func (r *CertReloader) loadLocked() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.modTimes = modTimes
+
+	return nil
+}
+
+func (r *CertReloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+
+	for i, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("failed to stat: %w", err)
+		}
+
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
@@ -0,0 +1,8 @@
+package tlsutil
+
+import "time"
+
+func (r *CertReloader) SetClock(now func() time.Time, checkInterval time.Duration) {
+	r.now = now
+	r.checkInterval = checkInterval
+}
@@ -0,0 +1,59 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Versions which can be passed to ParseVersion.
+const (
+	Version12 = "1.2"
+	Version13 = "1.3"
+)
+
+// ParseVersion returns the TLS version of the name like "1.2".
+func ParseVersion(name string) (uint16, error) {
+	switch name {
+	case Version12:
+		return tls.VersionTLS12, nil
+	case Version13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version: %s", name)
+	}
+}
+
+// NewServerConfig returns the TLS config serving the certificate of the reloader.
+func NewServerConfig(reloader *CertReloader, minVersion uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+}
+
+// RedirectHandler redirects every request to https with the same host, path and query.
+// httpsPort is omitted from the location if it is the default port (443).
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hostname は port と IPv6 アドレスの括弧を取り除く。
+		host := (&url.URL{Host: r.Host}).Hostname()
+
+		switch {
+		case httpsPort != "" && httpsPort != "443":
+			host = net.JoinHostPort(host, httpsPort)
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]"
+		}
+
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+
+		// 308 はメソッドとボディを保ったままリダイレクトさせる。
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,160 @@
+package tlsutil_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kokoichi206-sandbox/url-shortener/util/logger"
+	"github.com/kokoichi206-sandbox/url-shortener/util/tlsutil"
+)
+
+// writeCert writes a self-signed certificate of the common name with the modified time.
+func writeCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "failed to generate key")
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err, "failed to create certificate")
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err, "failed to marshal key")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	// ファイルシステムの時刻の粒度に依存しないよう、明示的に更新時刻を設定する。
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func commonNameOf(t *testing.T, r *tlsutil.CertReloader) string {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err, "failed to get certificate")
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err, "failed to parse certificate")
+
+	return leaf.Subject.CommonName
+}
+
+func Test_CertReloader(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	base := time.Now().Add(-time.Hour)
+	writeCert(t, certFile, keyFile, "old", base)
+
+	b := bytes.NewBuffer([]byte{})
+
+	r, err := tlsutil.NewCertReloader(certFile, keyFile, logger.NewBasicLogger(b, "test", "tlsutil"))
+	require.NoError(t, err, "failed to create reloader")
+
+	now := time.Now()
+	r.SetClock(func() time.Time { return now }, time.Minute)
+
+	require.Equal(t, "old", commonNameOf(t, r), "initial certificate should be served")
+
+	// Act & Assert
+	writeCert(t, certFile, keyFile, "new", base.Add(time.Second))
+	assert.Equal(t, "old", commonNameOf(t, r), "files should not be checked within the interval")
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, "new", commonNameOf(t, r), "rotated certificate should be served")
+
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	now = now.Add(time.Minute)
+	assert.Equal(t, "new", commonNameOf(t, r), "old certificate should be kept if the new one is invalid")
+	assert.Contains(t, b.String(), "failed to reload certificate", "reload failure should be logged")
+}
+
+func Test_NewCertReloader_Invalid(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	dir := t.TempDir()
+
+	// Act
+	_, err := tlsutil.NewCertReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), nil)
+
+	// Assert
+	require.Error(t, err, "error should be returned if the files do not exist")
+}
+
+func Test_RedirectHandler(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		httpsPort string
+		target    string
+		want      string
+	}{
+		"success: default port": {
+			httpsPort: "443",
+			target:    "http://example.com/abc?x=1",
+			want:      "https://example.com/abc?x=1",
+		},
+		"success: custom port": {
+			httpsPort: "8443",
+			target:    "http://example.com:8080/api/v1/urls",
+			want:      "https://example.com:8443/api/v1/urls",
+		},
+		"success: ipv6 without port": {
+			httpsPort: "8443",
+			target:    "http://[::1]/abc",
+			want:      "https://[::1]:8443/abc",
+		},
+		"success: ipv6 with default port": {
+			httpsPort: "443",
+			target:    "http://[::1]:8080/abc",
+			want:      "https://[::1]/abc",
+		},
+	}
+
+	for name, tc := range testCases {
+		name := name
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tc.target, nil)
+
+			// Act
+			tlsutil.RedirectHandler(tc.httpsPort).ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, http.StatusPermanentRedirect, recorder.Code, "status code should be equal")
+			assert.Equal(t, tc.want, recorder.Header().Get("Location"), "location should be equal")
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// RatioSampler samples the given ratio of the traces respecting the decision of the parent span,
+// and the ratio can be changed at runtime (e.g. on config reload).
+type RatioSampler struct {
+	sampler atomic.Pointer[sdktrace.Sampler]
+}
+
+func NewRatioSampler(ratio float64) *RatioSampler {
+	s := &RatioSampler{}
+	s.SetRatio(ratio)
+
+	return s
+}
+
+// SetRatio changes the ratio of the traces to be sampled, from 0 to 1.
+func (s *RatioSampler) SetRatio(ratio float64) {
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	s.sampler.Store(&sampler)
+}
+
+func (s *RatioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*s.sampler.Load()).ShouldSample(p)
+}
+
+func (s *RatioSampler) Description() string {
+	return (*s.sampler.Load()).Description()
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/kokoichi206-sandbox/url-shortener"
+
+// Exporters which can be set to Config.Exporter.
+const (
+	// ExporterNone does not export the spans, which still propagate the trace context.
+	ExporterNone     = "none"
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterStdout   = "stdout"
+)
+
+type Config struct {
+	ServiceName string
+	Exporter    string
+	// Host and Port of the collector (like jaeger).
+	// By default, jaeger is listening OTLP at 4317 (gRPC) and 4318 (HTTP).
+	Host string
+	Port string
+	// SamplingRatio is the ratio of the traces to be sampled, from 0 to 1.
+	// The decision of the parent span is respected.
+	SamplingRatio float64
+	// Sampler is used instead of SamplingRatio if not nil, to change the ratio at runtime.
+	Sampler *RatioSampler
+}
+
+// propagator handles W3C traceparent and baggage headers.
+//
+//nolint:gochecknoglobals
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// NewTracerProvider creates a tracer provider which exports spans to the given exporter.
+// The caller must call Shutdown of the provider to flush the remaining spans.
+func NewTracerProvider(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	res := resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))
+
+	sampler := cfg.Sampler
+	if sampler == nil {
+		sampler = NewRatioSampler(cfg.SamplingRatio)
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	}
+
+	if cfg.Exporter != ExporterNone {
+		exporter, err := newExporter(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	if cfg.Exporter == ExporterStdout {
+		//nolint:wrapcheck
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	}
+
+	if cfg.Host == "" || cfg.Port == "" {
+		return nil, fmt.Errorf("host or port is empty")
+	}
+
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+
+	switch cfg.Exporter {
+	case ExporterOTLPGRPC:
+		//nolint:wrapcheck
+		return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(addr), otlptracegrpc.WithInsecure())
+	case ExporterOTLPHTTP:
+		//nolint:wrapcheck
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(addr), otlptracehttp.WithInsecure())
+	default:
+		return nil, fmt.Errorf("unknown exporter: %s", cfg.Exporter)
+	}
+}
+
+// NewInMemoryTracerProvider creates a tracer provider which keeps every span in memory.
+// This is intended to be used in tests.
+func NewInMemoryTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+
+	return tp, exporter
+}
+
+// SetGlobal sets the tracer provider and the W3C propagator globally.
+func SetGlobal(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+}
+
+// Start starts a span with the global tracer provider.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	//nolint:spancheck
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// Extract returns the context which has the remote span context in the given headers.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject writes the span context of ctx to the given headers.
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}